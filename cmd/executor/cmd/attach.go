@@ -0,0 +1,101 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/osscontainertools/kaniko/pkg/executor"
+	"github.com/osscontainertools/kaniko/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	attachOpts         = &config.KanikoOptions{}
+	attachSubject      string
+	attachArtifactType string
+	attachList         bool
+)
+
+func init() {
+	AddRegistryOptionsFlags(attachCmd, &attachOpts.RegistryOptions)
+	attachCmd.Flags().StringVar(&attachSubject, "subject", "", "Image or index the artifact is attached to.")
+	attachCmd.Flags().StringVar(&attachArtifactType, "artifact-type", "", "Media type of the artifact, such as application/spdx+json. Filters the attachments with --list.")
+	attachCmd.Flags().VarP(&attachOpts.Annotations, "annotation", "", "Set annotations of the artifact manifest in key=value format. Set it repeatedly for multiple annotations.")
+	attachCmd.Flags().BoolVar(&attachList, "list", false, "List the artifacts attached to the subject instead of attaching one.")
+	RootCmd.AddCommand(attachCmd)
+}
+
+var attachCmd = &cobra.Command{
+	Use:   "attach --subject <ref> --artifact-type <media type> <file>",
+	Short: "Attach an artifact to an image in a registry",
+	Long: `Push a file as an OCI artifact whose subject is an image or index, so that
+registries list it among the subject's referrers. Registries without the
+referrers API get the referrers tag updated instead. The file - is read from
+standard input. With --list, print the artifacts attached to the subject.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := logging.Configure(logLevel, logFormat, logTimestamp)
+		if err != nil {
+			return err
+		}
+		if attachSubject == "" {
+			return errors.New("--subject is required")
+		}
+
+		if attachList {
+			if len(args) > 0 {
+				return errors.New("--list takes no file")
+			}
+			referrers, err := executor.ListReferrers(cmd.Context(), attachSubject, attachArtifactType, attachOpts)
+			if err != nil {
+				return err
+			}
+			for _, desc := range referrers {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", desc.Digest, desc.ArtifactType)
+			}
+			return nil
+		}
+
+		if len(args) == 0 {
+			return errors.New("a file to attach is required")
+		}
+		if attachArtifactType == "" {
+			return errors.New("--artifact-type is required")
+		}
+		var content []byte
+		if args[0] == "-" {
+			content, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			content, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", args[0], err)
+		}
+
+		ref, err := executor.Attach(cmd.Context(), attachSubject, attachArtifactType, content, attachOpts.Annotations, attachOpts)
+		if err != nil {
+			return fmt.Errorf("attaching %s: %w", args[0], err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), ref)
+		return nil
+	},
+}
@@ -34,6 +34,8 @@ func init() {
 	AddRegistryOptionsFlags(pushCmd, &pushOpts.RegistryOptions)
 	pushCmd.Flags().VarP(&pushOpts.Destinations, "destination", "d", "Registry the image should be pushed to. Set repeatedly for multiple destinations.")
 	pushCmd.Flags().BoolVar(&pushOpts.SkipPushPermissionCheck, "skip-push-permission-check", false, "Skip check of the push permission")
+	pushCmd.Flags().StringVar(&pushOpts.MetadataFile, "metadata-file", "", "Specify a file to save the metadata of the pushed image to, in the JSON format of docker buildx build --metadata-file.")
+	pushCmd.Flags().StringVar(&pushOpts.SignKey, "sign-key", "", "Path to a PEM or cosign private key to sign the pushed image with, a cosign key is decrypted with $COSIGN_PASSWORD.")
 	RootCmd.AddCommand(pushCmd)
 }
 
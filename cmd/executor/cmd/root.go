@@ -17,9 +17,11 @@ limitations under the License.
 package cmd
 
 import (
-	"context"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -34,8 +36,10 @@ import (
 	"github.com/osscontainertools/kaniko/pkg/buildcontext"
 	"github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/pkg/constants"
+	"github.com/osscontainertools/kaniko/pkg/dockerfile"
 	"github.com/osscontainertools/kaniko/pkg/executor"
 	"github.com/osscontainertools/kaniko/pkg/logging"
+	"github.com/osscontainertools/kaniko/pkg/signing"
 	"github.com/osscontainertools/kaniko/pkg/tracing"
 	"github.com/osscontainertools/kaniko/pkg/util"
 	"github.com/osscontainertools/kaniko/pkg/util/proc"
@@ -55,7 +59,7 @@ var (
 
 func init() {
 	RootCmd.Flags().StringVarP(&logLevel, "verbosity", "v", logging.DefaultLevel, "Log level (trace, debug, info, warn, error, fatal, panic)")
-	RootCmd.Flags().StringVar(&logFormat, "log-format", logging.FormatColor, "Log format (text, color, json)")
+	RootCmd.Flags().StringVar(&logFormat, "log-format", logging.FormatColor, "Log format (text, color, json, gitlab, github)")
 	RootCmd.Flags().BoolVar(&logTimestamp, "log-timestamp", logging.DefaultLogTimestamp, "Timestamp in log output")
 	RootCmd.Flags().BoolVarP(&force, "force", "", false, "Force building outside of a container")
 
@@ -161,12 +165,36 @@ var RootCmd = &cobra.Command{
 			if opts.TarPath != "" && opts.Compression == config.ZStd {
 				return errors.New("--compression=zstd cannot be used with --tar-path, the Docker schema2 tarball has no zstd layer media type, use --oci-layout-path for zstd layers")
 			}
+			if opts.DockerfileInline != "" && cmd.Flags().Changed("dockerfile") {
+				return errors.New("--dockerfile-inline cannot be used with --dockerfile")
+			}
+			if opts.DockerfilePath == "-" && opts.SrcContext == buildcontext.TarBuildContextPrefix+"stdin" {
+				return errors.New("--dockerfile=- cannot be used with --context=tar://stdin, both are read from stdin")
+			}
+			if opts.SignKey != "" {
+				// fail before the build rather than after it
+				if _, err := signing.LoadSigner(opts.SignKey, os.Getenv(signing.PasswordEnv)); err != nil {
+					return fmt.Errorf("loading --sign-key: %w", err)
+				}
+				if opts.NoPush {
+					logrus.Warn("--sign-key has no effect with --no-push, signatures are only pushed")
+				}
+			}
+			if _, err := signing.LoadPolicy(opts.VerifyBasePolicy, opts.VerifyBaseKey); err != nil {
+				return fmt.Errorf("loading base image verification keys: %w", err)
+			}
+			if _, err := dockerfile.LoadBaseImagePolicy(opts.BaseImagePolicy); err != nil {
+				return fmt.Errorf("loading --base-image-policy: %w", err)
+			}
 			if err := cacheFlagsValid(); err != nil {
 				return fmt.Errorf("cache flags invalid: %w", err)
 			}
 			if err := resolveSourceContext(); err != nil {
 				return fmt.Errorf("error resolving source context: %w", err)
 			}
+			if err := resolveBuildContexts(); err != nil {
+				return fmt.Errorf("error resolving build contexts: %w", err)
+			}
 			if err := resolveDockerfilePath(); err != nil {
 				return fmt.Errorf("error resolving dockerfile path: %w", err)
 			}
@@ -197,7 +225,7 @@ var RootCmd = &cobra.Command{
 		}
 		return nil
 	},
-	Run: func(_ *cobra.Command, _ []string) {
+	Run: func(cmd *cobra.Command, _ []string) {
 		if !checkContained() {
 			if !force {
 				exit(errors.New("kaniko should only be run inside of a container, run with the --force flag if you are sure you want to continue"))
@@ -231,14 +259,15 @@ var RootCmd = &cobra.Command{
 				}
 			}()
 		}
-		tracing.Init(context.Background(), opts)
-		image, err := executor.DoBuild(opts)
+		ctx := cmd.Context()
+		tracing.Init(ctx, opts)
+		image, err := executor.Build(ctx, opts)
 		if err != nil {
 			exit(fmt.Errorf("error building image: %w", err))
 		}
 		// mz992: a dryrun renders the plan and returns no image, there is nothing to push.
 		if !opts.Dryrun {
-			if err := executor.DoPush(image, opts); err != nil {
+			if err := executor.Push(ctx, image, opts); err != nil {
 				exit(fmt.Errorf("error pushing image: %w", err))
 			}
 		}
@@ -249,7 +278,8 @@ var RootCmd = &cobra.Command{
 
 // addKanikoOptionsFlags configures opts
 func AddKanikoOptionsFlags(cmd *cobra.Command, opts *config.KanikoOptions) {
-	cmd.Flags().StringVarP(&opts.DockerfilePath, "dockerfile", "f", "Dockerfile", "Path to the dockerfile to be built.")
+	cmd.Flags().StringVarP(&opts.DockerfilePath, "dockerfile", "f", "Dockerfile", "Path to the dockerfile to be built, - to read it from stdin.")
+	cmd.Flags().StringVarP(&opts.DockerfileInline, "dockerfile-inline", "", "", "Contents of the dockerfile to be built, instead of a --dockerfile path.")
 	cmd.Flags().StringVarP(&opts.SrcContext, "context", "c", "/workspace/", "Path to the dockerfile build context.")
 	cmd.Flags().StringVarP(&ctxSubPath, "context-sub-path", "", "", "Sub path within the given context.")
 	cmd.Flags().StringVarP(&opts.Bucket, "bucket", "b", "", "Name of the GCS bucket from which to access build context as tarball.")
@@ -270,9 +300,20 @@ func AddKanikoOptionsFlags(cmd *cobra.Command, opts *config.KanikoOptions) {
 	cmd.Flags().StringVarP(&opts.DigestFile, "digest-file", "", "", "Specify a file to save the digest of the built image to.")
 	cmd.Flags().StringVarP(&opts.ImageNameDigestFile, "image-name-with-digest-file", "", "", "Specify a file to save the image name w/ digest of the built image to.")
 	cmd.Flags().StringVarP(&opts.ImageNameTagDigestFile, "image-name-tag-with-digest-file", "", "", "Specify a file to save the image name w/ image tag w/ digest of the built image to.")
+	cmd.Flags().StringVarP(&opts.MetadataFile, "metadata-file", "", "", "Specify a file to save the metadata of the built image to, in the JSON format of docker buildx build --metadata-file.")
 	cmd.Flags().StringVarP(&opts.OCILayoutPath, "oci-layout-path", "", "", "Path to save the OCI image layout of the built image.")
 	cmd.Flags().VarP(&opts.Compression, "compression", "", "Compression algorithm (gzip, zstd)")
 	cmd.Flags().VarP(&opts.ImageFormat, "image-format", "", "Output image media type (docker, oci). Defaults to inheriting the format of the base image.")
+	cmd.Flags().VarP(&opts.SBOM, "sbom", "", "Generate an SBOM of the packages installed in the image (spdx, cyclonedx) and push it alongside the image as an OCI referrer.")
+	cmd.Flags().StringVarP(&opts.SignKey, "sign-key", "", "", "Path to a PEM or cosign private key to sign the pushed image and its attachments with, a cosign key is decrypted with $COSIGN_PASSWORD.")
+	cmd.Flags().StringVarP(&opts.VerifyBaseKey, "verify-base-key", "", "", "Path to a PEM public key every base image and COPY --from image must carry a cosign signature by.")
+	cmd.Flags().StringVarP(&opts.VerifyBasePolicy, "verify-base-policy", "", "", "Path to a JSON file mapping registries and repositories to the public keys their base images must be signed with.")
+	cmd.Flags().StringVarP(&opts.BaseImagePolicy, "base-image-policy", "", "", "Path to a policy file restricting base images and COPY --from images to allowed repositories, requiring digest pinning or limiting their age.")
+	cmd.Flags().StringVarP(&opts.Lockfile, "lockfile", "", "", "Path to a lockfile pinning every base image and COPY --from image to a digest per platform.")
+	opts.LockfileMode = config.LockfileModeEnforce
+	cmd.Flags().VarP(&opts.LockfileMode, "lockfile-mode", "", "Whether to resolve images only through the --lockfile (enforce) or to record the digests they resolve to in it (write).")
+	cmd.Flags().VarP(&opts.AutoMetadata, "auto-metadata", "", "Record the git source, revision and version of the build context and the base image of the pushed image as OCI labels, annotations or both (labels, annotations, all).")
+	cmd.Flags().VarP(&opts.Provenance, "provenance", "", "Generate a SLSA provenance statement of the build (min, max) and push it alongside the image as an OCI referrer. max adds build arg values and the stages.")
 	cmd.Flags().IntVarP(&opts.CompressionLevel, "compression-level", "", -1, "Compression level")
 	cmd.Flags().BoolVarP(&opts.Cache, "cache", "", false, "Use cache when building image")
 	cmd.Flags().BoolVarP(&opts.CompressedCaching, "compressed-caching", "", true, "Compress the cached layers. Decreases build time, but increases memory usage.")
@@ -294,6 +335,10 @@ func AddKanikoOptionsFlags(cmd *cobra.Command, opts *config.KanikoOptions) {
 	opts.Secrets = make(config.SecretOptions)
 	cmd.Flags().VarP(&opts.Secrets, "secret", "", "Set build secrets in key=value format. Set it repeatedly for multiple secrets.")
 	cmd.Flags().BoolVarP(&opts.Dryrun, "dryrun", "", false, "Whether to only run a plan")
+	cmd.Flags().StringVarP(&opts.RunLogDir, "run-log-dir", "", "", "Directory to write the output of each RUN instruction to, one file per instruction.")
+	opts.BuildContexts = make(map[string]string)
+	cmd.Flags().VarP(&opts.BuildContexts, "build-context", "", "Add a named build context in name=source format, usable with COPY --from=name and FROM name. The source is a local directory, a git:// or https:// context, docker-image://ref or oci-layout://path[:tag]. Set it repeatedly for multiple contexts.")
+	cmd.Flags().StringVarP(&opts.URLCacheDir, "url-cache-dir", "", "", "Directory to cache the content of ADD URLs in, they are only downloaded again if the server reports a change.")
 
 	AddRegistryOptionsFlags(cmd, &opts.RegistryOptions)
 
@@ -414,7 +459,14 @@ func cacheFlagsValid() error {
 
 // resolveDockerfilePath resolves the Dockerfile path to an absolute path
 func resolveDockerfilePath() error {
+	if opts.DockerfileInline != "" {
+		return writeDockerfile(strings.NewReader(opts.DockerfileInline))
+	}
+	if opts.DockerfilePath == "-" {
+		return writeDockerfile(os.Stdin)
+	}
 	if isURL(opts.DockerfilePath) {
+		opts.DockerfileName = opts.DockerfilePath
 		return nil
 	}
 	if util.FilepathExists(opts.DockerfilePath) {
@@ -423,6 +475,7 @@ func resolveDockerfilePath() error {
 			return fmt.Errorf("getting absolute path for dockerfile: %w", err)
 		}
 		opts.DockerfilePath = abs
+		opts.DockerfileName = dockerfileName(abs, opts.SrcContext)
 		return copyDockerfile()
 	}
 	// Otherwise, check if the path relative to the build context exists
@@ -432,11 +485,23 @@ func resolveDockerfilePath() error {
 			return fmt.Errorf("getting absolute path for src context/dockerfile path: %w", err)
 		}
 		opts.DockerfilePath = abs
+		opts.DockerfileName = dockerfileName(abs, opts.SrcContext)
 		return copyDockerfile()
 	}
 	return errors.New("please provide a valid path to a Dockerfile within the build context with --dockerfile")
 }
 
+// dockerfileName is the Dockerfile's path within the build context, or its
+// base name when it came from elsewhere.
+func dockerfileName(path, srcContext string) string {
+	if ctx, err := filepath.Abs(srcContext); err == nil {
+		if rel, err := filepath.Rel(ctx, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return filepath.Base(path)
+}
+
 func resolveSecrets() error {
 	for k, s := range opts.Secrets {
 		if s.Type == "env" {
@@ -495,6 +560,27 @@ func copyDockerfile() error {
 	return nil
 }
 
+// writeDockerfile stores a Dockerfile read from r where a copied Dockerfile
+// goes. Not being a file, it has no Dockerfile specific .dockerignore and the
+// one of the build context applies.
+func writeDockerfile(r io.Reader) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading dockerfile: %w", err)
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return errors.New("the dockerfile is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(config.DockerfilePath), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(config.DockerfilePath, content, 0o644); err != nil {
+		return fmt.Errorf("writing dockerfile: %w", err)
+	}
+	opts.DockerfilePath = config.DockerfilePath
+	return nil
+}
+
 // resolveSourceContext unpacks the source context if it is a tar in a bucket or in kaniko container
 // it resets srcContext to be the path to the unpacked build context within the image
 func resolveSourceContext() error {
@@ -502,7 +588,7 @@ func resolveSourceContext() error {
 		return errors.New("please specify a path to the build context with the --context flag or a bucket with the --bucket flag")
 	}
 	if opts.SrcContext != "" && !strings.Contains(opts.SrcContext, "://") {
-		return nil
+		return resolveContextRevision(nil)
 	}
 	if opts.Bucket != "" {
 		if !strings.Contains(opts.Bucket, "://") {
@@ -523,10 +609,15 @@ func resolveSourceContext() error {
 		return err
 	}
 	logrus.Debugf("Getting source context from %s", opts.SrcContext)
+	source := opts.SrcContext
 	opts.SrcContext, err = contextExecutor.UnpackTarFromBuildContext()
 	if err != nil {
 		return err
 	}
+	opts.ContextSource = config.ContextSource{URI: buildcontext.RedactURL(source)}
+	if s, ok := contextExecutor.(buildcontext.Sourced); ok {
+		opts.ContextSource = s.Source()
+	}
 	if ctxSubPath != "" {
 		opts.SrcContext = filepath.Join(opts.SrcContext, ctxSubPath)
 		if _, err := os.Stat(opts.SrcContext); os.IsNotExist(err) {
@@ -534,6 +625,77 @@ func resolveSourceContext() error {
 		}
 	}
 	logrus.Debugf("Build context located at %s", opts.SrcContext)
+	return resolveContextRevision(contextExecutor)
+}
+
+// resolveContextRevision finds the git commit the build context was checked
+// out at for --auto-metadata, from a git context or else the checkout the
+// context directory is in.
+func resolveContextRevision(contextExecutor buildcontext.BuildContext) error {
+	if opts.AutoMetadata == config.AutoMetadataNone {
+		return nil
+	}
+	if r, ok := contextExecutor.(buildcontext.Revisioned); ok {
+		opts.ContextRevision = r.Revision()
+		return nil
+	}
+	rev, err := buildcontext.LocalGitRevision(opts.SrcContext)
+	if err != nil {
+		return err
+	}
+	if rev == nil {
+		logrus.Warnf("Build context %s is not a git checkout, --auto-metadata records only the base image", opts.SrcContext)
+	}
+	opts.ContextRevision = rev
+	return nil
+}
+
+// resolveBuildContexts unpacks the named build contexts that are not images,
+// each into its own directory. A local directory is only made absolute.
+func resolveBuildContexts() error {
+	for name, source := range opts.BuildContexts {
+		if name == "" {
+			return fmt.Errorf("build context %q has no name", source)
+		}
+		if _, err := strconv.Atoi(name); err == nil {
+			return fmt.Errorf("build context name %s is reserved for stage indices", name)
+		}
+		if buildcontext.IsImage(source) {
+			continue
+		}
+		if !strings.Contains(source, "://") {
+			dir, err := filepath.Abs(source)
+			if err != nil {
+				return err
+			}
+			fi, err := os.Stat(dir)
+			if err != nil {
+				return fmt.Errorf("build context %s: %w", name, err)
+			}
+			if !fi.IsDir() {
+				return fmt.Errorf("build context %s: %s is not a directory", name, dir)
+			}
+			opts.BuildContexts[name] = dir
+			continue
+		}
+		contextExecutor, err := buildcontext.GetBuildContext(source, buildcontext.BuildOptions{
+			GitBranch:            opts.Git.Branch,
+			GitSingleBranch:      opts.Git.SingleBranch,
+			GitDepth:             opts.Git.Depth,
+			GitRecurseSubmodules: opts.Git.RecurseSubmodules,
+			InsecureSkipTLS:      opts.Git.InsecureSkipTLS,
+			Directory:            filepath.Join(config.KanikoContextsDir, url.PathEscape(name)),
+		})
+		if err != nil {
+			return fmt.Errorf("build context %s: %w", name, err)
+		}
+		logrus.Debugf("Getting build context %s from %s", name, source)
+		dir, err := contextExecutor.UnpackTarFromBuildContext()
+		if err != nil {
+			return fmt.Errorf("build context %s: %w", name, err)
+		}
+		opts.BuildContexts[name] = dir
+	}
 	return nil
 }
 
@@ -546,7 +708,13 @@ func resolveRelativePaths() error {
 		&opts.DigestFile,
 		&opts.ImageNameDigestFile,
 		&opts.ImageNameTagDigestFile,
+		&opts.MetadataFile,
 		&opts.OCILayoutPath,
+		&opts.SignKey,
+		&opts.VerifyBaseKey,
+		&opts.VerifyBasePolicy,
+		&opts.BaseImagePolicy,
+		&opts.Lockfile,
 	}
 
 	for _, p := range optsPaths {
@@ -17,8 +17,11 @@ limitations under the License.
 package cmd
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/testutil"
 )
 
@@ -149,3 +152,49 @@ func TestResolveEnvironmentBuildArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveDockerfilePath_Inline(t *testing.T) {
+	original, originalOpts := config.DockerfilePath, *opts
+	defer func() {
+		config.DockerfilePath, *opts = original, originalOpts
+	}()
+	config.DockerfilePath = filepath.Join(t.TempDir(), "Dockerfile")
+
+	opts.DockerfileInline = "FROM scratch\nCOPY . /\n"
+	testutil.CheckNoError(t, resolveDockerfilePath())
+	testutil.CheckDeepEqual(t, config.DockerfilePath, opts.DockerfilePath)
+	content, err := os.ReadFile(opts.DockerfilePath)
+	testutil.CheckErrorAndDeepEqual(t, false, err, opts.DockerfileInline, string(content))
+
+	opts.DockerfileInline = "\n"
+	testutil.CheckError(t, true, resolveDockerfilePath())
+}
+
+func TestResolveDockerfilePath_Name(t *testing.T) {
+	original, originalOpts := config.DockerfilePath, *opts
+	defer func() {
+		config.DockerfilePath, *opts = original, originalOpts
+	}()
+	config.DockerfilePath = filepath.Join(t.TempDir(), "Dockerfile")
+	srcContext := t.TempDir()
+	outside := t.TempDir()
+	testutil.CheckNoError(t, testutil.SetupFiles(srcContext, map[string]string{"docker/Dockerfile": "FROM scratch\n"}))
+	testutil.CheckNoError(t, testutil.SetupFiles(outside, map[string]string{"build.Dockerfile": "FROM scratch\n"}))
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "relative to the context", path: "docker/Dockerfile", expected: "docker/Dockerfile"},
+		{name: "outside the context", path: filepath.Join(outside, "build.Dockerfile"), expected: "build.Dockerfile"},
+		{name: "url", path: "https://example.com/Dockerfile", expected: "https://example.com/Dockerfile"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts.SrcContext, opts.DockerfilePath, opts.DockerfileName = srcContext, tt.path, ""
+			testutil.CheckNoError(t, resolveDockerfilePath())
+			testutil.CheckDeepEqual(t, tt.expected, opts.DockerfileName)
+		})
+	}
+}
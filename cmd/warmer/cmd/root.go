@@ -45,7 +45,7 @@ var (
 
 func init() {
 	RootCmd.Flags().StringVarP(&logLevel, "verbosity", "v", logging.DefaultLevel, "Log level (trace, debug, info, warn, error, fatal, panic)")
-	RootCmd.Flags().StringVar(&logFormat, "log-format", logging.FormatColor, "Log format (text, color, json)")
+	RootCmd.Flags().StringVar(&logFormat, "log-format", logging.FormatColor, "Log format (text, color, json, gitlab, github)")
 	RootCmd.Flags().BoolVar(&logTimestamp, "log-timestamp", logging.DefaultLogTimestamp, "Timestamp in log output")
 
 	addKanikoOptionsFlags()
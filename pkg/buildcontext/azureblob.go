@@ -25,7 +25,6 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
-	kConfig "github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/pkg/constants"
 	"github.com/osscontainertools/kaniko/pkg/util"
 )
@@ -33,6 +32,7 @@ import (
 // AzureBlob struct for Azure Blob Storage processing
 type AzureBlob struct {
 	context string
+	opts    BuildOptions
 }
 
 // Download context file from given azure blob storage url and unpack it to BuildContextDir
@@ -59,7 +59,7 @@ func (b *AzureBlob) UnpackTarFromBuildContext() (string, error) {
 	}
 
 	// Create directory and target file for downloading the context file
-	directory := kConfig.BuildContextDir
+	directory := b.opts.directory()
 	tarPath := filepath.Join(directory, constants.ContextTar)
 	file, err := util.CreateTargetTarfile(tarPath)
 	if err != nil {
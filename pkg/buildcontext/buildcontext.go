@@ -17,9 +17,15 @@ limitations under the License.
 package buildcontext
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"io"
+	"net/url"
+	"os"
 	"strings"
 
+	kConfig "github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/pkg/constants"
 	"github.com/osscontainertools/kaniko/pkg/util"
 )
@@ -34,6 +40,22 @@ type BuildOptions struct {
 	GitDepth             int
 	GitRecurseSubmodules bool
 	InsecureSkipTLS      bool
+	// Directory is where the context is unpacked into, the BuildContextDir
+	// if empty. Contexts that are already a directory ignore it.
+	Directory string
+}
+
+func (o BuildOptions) directory() string {
+	if o.Directory != "" {
+		return o.Directory
+	}
+	return kConfig.BuildContextDir
+}
+
+// IsImage reports whether the source of a named build context is an image
+// rather than files.
+func IsImage(source string) bool {
+	return strings.HasPrefix(source, constants.DockerImageContextPrefix) || strings.HasPrefix(source, constants.OCILayoutContextPrefix)
 }
 
 // BuildContext unifies calls to download and unpack the build context.
@@ -42,6 +64,46 @@ type BuildContext interface {
 	UnpackTarFromBuildContext() (string, error)
 }
 
+// Sourced is implemented by build contexts that know which revision of their
+// source they unpacked, once UnpackTarFromBuildContext has returned.
+type Sourced interface {
+	Source() kConfig.ContextSource
+}
+
+// Revisioned is implemented by build contexts checked out of a git
+// repository, once UnpackTarFromBuildContext has returned.
+type Revisioned interface {
+	Revision() *kConfig.ContextRevision
+}
+
+// fileSource describes a context downloaded as the archive at path by its
+// sha256, which unlike the URI stays valid if the object is overwritten.
+func fileSource(uri, path string) (kConfig.ContextSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return kConfig.ContextSource{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return kConfig.ContextSource{}, err
+	}
+	return kConfig.ContextSource{
+		URI:    uri,
+		Digest: map[string]string{"sha256": hex.EncodeToString(h.Sum(nil))},
+	}, nil
+}
+
+// RedactURL drops the credentials from a context URL before it is recorded.
+func RedactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	u.User = nil
+	return u.String()
+}
+
 // GetBuildContext parses srcContext for the prefix and returns related buildcontext
 // parser
 func GetBuildContext(srcContext string, opts BuildOptions) (BuildContext, error) {
@@ -52,20 +114,20 @@ func GetBuildContext(srcContext string, opts BuildOptions) (BuildContext, error)
 
 		switch prefix {
 		case constants.GCSBuildContextPrefix:
-			return &GCS{context: srcContext}, nil
+			return &GCS{context: srcContext, opts: opts}, nil
 		case constants.S3BuildContextPrefix:
-			return &S3{context: srcContext}, nil
+			return &S3{context: srcContext, opts: opts}, nil
 		case constants.LocalDirBuildContextPrefix:
 			return &Dir{context: context}, nil
 		case constants.GitBuildContextPrefix:
 			return &Git{context: context, opts: opts}, nil
 		case constants.HTTPSBuildContextPrefix:
 			if util.ValidAzureBlobStorageHost(srcContext) {
-				return &AzureBlob{context: srcContext}, nil
+				return &AzureBlob{context: srcContext, opts: opts}, nil
 			}
-			return &HTTPSTar{context: srcContext}, nil
+			return &HTTPSTar{context: srcContext, opts: opts}, nil
 		case TarBuildContextPrefix:
-			return &Tar{context: context}, nil
+			return &Tar{context: context, opts: opts}, nil
 		}
 	}
 	return nil, errors.New("unknown build context prefix provided, please use one of the following: gs://, dir://, tar://, s3://, git://, https://")
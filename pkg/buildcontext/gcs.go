@@ -22,6 +22,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"cloud.google.com/go/storage"
 	kConfig "github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/pkg/constants"
 	"github.com/osscontainertools/kaniko/pkg/util"
@@ -32,6 +33,8 @@ import (
 // GCS struct for Google Cloud Storage processing
 type GCS struct {
 	context string
+	opts    BuildOptions
+	source  kConfig.ContextSource
 }
 
 func (g *GCS) UnpackTarFromBuildContext() (string, error) {
@@ -39,43 +42,58 @@ func (g *GCS) UnpackTarFromBuildContext() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("getting bucketname and filepath from context: %w", err)
 	}
-	return kConfig.BuildContextDir, unpackTarFromGCSBucket(bucketName, filepath, kConfig.BuildContextDir)
+	directory := g.opts.directory()
+	g.source, err = unpackTarFromGCSBucket(bucketName, filepath, directory)
+	return directory, err
+}
+
+// Source returns the object with its generation, and the digest of its content.
+func (g *GCS) Source() kConfig.ContextSource {
+	return g.source
 }
 
 // unpackTarFromGCSBucket unpacks the context.tar.gz file in the given bucket to the given directory
-func unpackTarFromGCSBucket(bucketName, item, directory string) error {
+func unpackTarFromGCSBucket(bucketName, item, directory string) (kConfig.ContextSource, error) {
 	// Get the tar from the bucket
-	tarPath, err := getTarFromBucket(bucketName, item, directory)
+	tarPath, generation, err := getTarFromBucket(bucketName, item, directory)
 	if err != nil {
-		return err
+		return kConfig.ContextSource{}, err
+	}
+	source, err := fileSource(fmt.Sprintf("gs://%s/%s#%d", bucketName, item, generation), tarPath)
+	if err != nil {
+		return kConfig.ContextSource{}, err
 	}
 	logrus.Debug("Unpacking source context tar...")
 	if err := util.UnpackCompressedTar(tarPath, directory); err != nil {
-		return err
+		return kConfig.ContextSource{}, err
 	}
 	// Remove the tar so it doesn't interfere with subsequent commands
 	logrus.Debugf("Deleting %s", tarPath)
-	return os.Remove(tarPath)
+	return source, os.Remove(tarPath)
 }
 
 // getTarFromBucket gets context.tar.gz from the GCS bucket and saves it to the filesystem
-// It returns the path to the tar file
-func getTarFromBucket(bucketName, filepathInBucket, directory string) (string, error) {
+// It returns the path to the tar file and the generation of the object read
+func getTarFromBucket(bucketName, filepathInBucket, directory string) (string, int64, error) {
 	ctx := context.Background()
 	client, err := bucket.NewClient(ctx)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 	// Get the tarfile context.tar.gz from the GCS bucket, and save it to a tar object
 	reader, err := bucket.ReadCloser(ctx, bucketName, filepathInBucket, client)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 	defer reader.Close()
 	tarPath := filepath.Join(directory, constants.ContextTar)
 	if err := util.CreateFile(tarPath, reader, 0o600, 0o755, 0, 0); err != nil {
-		return "", err
+		return "", 0, err
 	}
 	logrus.Debugf("Copied tarball %s from GCS bucket %s to %s", constants.ContextTar, bucketName, tarPath)
-	return tarPath, nil
+	var generation int64
+	if r, ok := reader.(*storage.Reader); ok {
+		generation = r.Attrs.Generation
+	}
+	return tarPath, generation, nil
 }
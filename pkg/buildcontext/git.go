@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/go-git/go-billy/v5/osfs"
@@ -30,7 +31,9 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
 	kConfig "github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/osscontainertools/kaniko/pkg/util"
 	"github.com/sirupsen/logrus"
 )
 
@@ -48,53 +51,244 @@ var supportedGitPullMethods = map[string]bool{gitPullMethodHTTPS: true, gitPullM
 
 // Git unifies calls to download and unpack the build context.
 type Git struct {
-	context string
-	opts    BuildOptions
+	context  string
+	opts     BuildOptions
+	source   kConfig.ContextSource
+	revision *kConfig.ContextRevision
 }
 
 // UnpackTarFromBuildContext will provide the directory where Git Repository is Cloned
 func (g *Git) UnpackTarFromBuildContext() (string, error) {
-	directory := kConfig.BuildContextDir
+	directory := g.opts.directory()
 	parts := strings.Split(g.context, "#")
 	url := getGitPullMethod() + "://" + parts[0]
+	var ref, commit string
+	if len(parts) > 1 {
+		ref = parts[1]
+	}
+	if len(parts) > 2 {
+		commit = parts[2]
+	}
+	r, err := cloneGitRepo(directory, url, ref, commit, g.opts)
+	if err != nil {
+		return directory, err
+	}
+	head, err := r.Head()
+	if err != nil {
+		return directory, err
+	}
+	g.source = kConfig.ContextSource{
+		URI:    "git+" + RedactURL(url),
+		Digest: map[string]string{"gitCommit": head.Hash().String()},
+	}
+	if ref != "" {
+		g.source.URI += "@" + ref
+	}
+	g.revision, err = gitRevision(r, RedactURL(url), ref)
+	if err != nil {
+		return directory, err
+	}
+	return directory, nil
+}
+
+// Source returns the repository and the commit that was checked out.
+func (g *Git) Source() kConfig.ContextSource {
+	return g.source
+}
+
+// Revision returns the repository, the commit that was checked out and the
+// tag pointing at it.
+func (g *Git) Revision() *kConfig.ContextRevision {
+	return g.revision
+}
+
+// LocalGitRevision returns the revision of the git checkout dir is in, nil
+// if it is not in a checkout or the checkout has no commit yet. The source
+// is the URL of the origin remote.
+func LocalGitRevision(dir string) (*kConfig.ContextRevision, error) {
+	r, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening git checkout of %s: %w", dir, err)
+	}
+	source := ""
+	if origin, err := r.Remote("origin"); err == nil && len(origin.Config().URLs) > 0 {
+		source = RedactURL(origin.Config().URLs[0])
+	}
+	rev, err := gitRevision(r, source, "")
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	return rev, err
+}
+
+// gitRevision returns the revision r has checked out. The version is ref
+// when it names a tag pointing at the commit, otherwise the highest of the
+// tags pointing at it, compared as versions.
+func gitRevision(r *git.Repository, source, ref string) (*kConfig.ContextRevision, error) {
+	head, err := r.Head()
+	if err != nil {
+		return nil, err
+	}
+	rev := &kConfig.ContextRevision{Source: source, Revision: head.Hash().String()}
+	tags, err := r.Tags()
+	if err != nil {
+		return nil, err
+	}
+	checkedOut := strings.TrimPrefix(ref, "refs/tags/")
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		target := ref.Hash()
+		// annotated tags point at a tag object rather than the commit
+		if tag, err := r.TagObject(target); err == nil {
+			target = tag.Target
+		}
+		if target != head.Hash() || checkedOut != "" && rev.Version == checkedOut {
+			return nil
+		}
+		if name := ref.Name().Short(); name == checkedOut || compareVersions(name, rev.Version) > 0 {
+			rev.Version = name
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// compareVersions orders tags as semantic versions, with or without a v
+// prefix, so v1.10.0 comes after v1.9.0 and v1.0.0-rc.1 before v1.0.0. Tags
+// that are not versions come before those that are, in lexical order.
+func compareVersions(a, b string) int {
+	va, aok := parseVersion(a)
+	vb, bok := parseVersion(b)
+	switch {
+	case aok && !bok:
+		return 1
+	case !aok && bok:
+		return -1
+	case !aok && !bok:
+		return strings.Compare(a, b)
+	}
+	for i := range max(len(va.release), len(vb.release)) {
+		var x, y int
+		if i < len(va.release) {
+			x = va.release[i]
+		}
+		if i < len(vb.release) {
+			y = vb.release[i]
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	// a pre-release comes before its release
+	switch {
+	case va.pre == "" && vb.pre != "":
+		return 1
+	case va.pre != "" && vb.pre == "":
+		return -1
+	}
+	return comparePrerelease(va.pre, vb.pre)
+}
+
+type version struct {
+	release []int
+	pre     string
+}
+
+func parseVersion(s string) (version, bool) {
+	s = strings.TrimPrefix(s, "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, pre, _ := strings.Cut(s, "-")
+	var v version
+	for f := range strings.SplitSeq(s, ".") {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		v.release = append(v.release, n)
+	}
+	v.pre = pre
+	return v, true
+}
+
+// comparePrerelease compares dot separated pre-release identifiers, numeric
+// ones numerically and before alphanumeric ones.
+func comparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	fa, fb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(fa), len(fb)) {
+		na, aerr := strconv.Atoi(fa[i])
+		nb, berr := strconv.Atoi(fb[i])
+		switch {
+		case aerr == nil && berr == nil:
+			if na != nb {
+				return na - nb
+			}
+		case aerr == nil:
+			return -1
+		case berr == nil:
+			return 1
+		default:
+			if c := strings.Compare(fa[i], fb[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return len(fa) - len(fb)
+}
+
+// cloneGitRepo clones url into directory and checks out ref, which is either
+// a commit SHA, a full reference or a branch name. A non-empty commit is
+// checked out last.
+func cloneGitRepo(directory, url, ref, commit string, opts BuildOptions) (*git.Repository, error) {
 	options := git.CloneOptions{
 		URL:               url,
-		Auth:              getGitAuth(),
+		Auth:              getGitAuthFor(url),
 		Progress:          os.Stdout,
-		SingleBranch:      g.opts.GitSingleBranch,
-		Depth:             g.opts.GitDepth,
-		RecurseSubmodules: getRecurseSubmodules(g.opts.GitRecurseSubmodules),
-		InsecureSkipTLS:   g.opts.InsecureSkipTLS,
+		SingleBranch:      opts.GitSingleBranch,
+		Depth:             opts.GitDepth,
+		RecurseSubmodules: getRecurseSubmodules(opts.GitRecurseSubmodules),
+		InsecureSkipTLS:   opts.InsecureSkipTLS,
+	}
+	repo := url
+	if _, after, ok := strings.Cut(url, "://"); ok {
+		repo = after
 	}
 	var fetchRef string
 	var checkoutRef string
-	if len(parts) > 1 {
-		if plumbing.IsHash(parts[1]) {
+	if ref != "" {
+		if plumbing.IsHash(ref) {
 			// Commit SHA
 			// Fetch the sha in case it is an ephemeral commit - ie. merged results pipeline
-			fetchRef = parts[1]
+			fetchRef = ref
 			checkoutRef = fetchRef
-		} else if strings.HasPrefix(parts[1], "refs/heads/") ||
-			strings.HasPrefix(parts[0], "github.com/") && strings.HasPrefix(parts[1], "refs/pull/") ||
-			strings.HasPrefix(parts[0], "gitlab.com/") && strings.HasPrefix(parts[1], "refs/merge-requests/") {
+		} else if strings.HasPrefix(ref, "refs/heads/") ||
+			strings.HasPrefix(repo, "github.com/") && strings.HasPrefix(ref, "refs/pull/") ||
+			strings.HasPrefix(repo, "gitlab.com/") && strings.HasPrefix(ref, "refs/merge-requests/") {
 			// Full branch ref will be cloned directly
 			// For github, pull-request refs can be cloned like branches directly
 			// For gitlab, merge-request refs can be cloned like branches directly
-			options.ReferenceName = plumbing.ReferenceName(parts[1])
-		} else if strings.HasPrefix(parts[1], "refs/") {
+			options.ReferenceName = plumbing.ReferenceName(ref)
+		} else if strings.HasPrefix(ref, "refs/") {
 			// Handle any non-branch refs separately. First, clone the repo HEAD, and
 			// then fetch and check out the fetchRef.
-			fetchRef = parts[1]
+			fetchRef = ref
 		} else {
 			// Plain branch name like "main", will be cloned directly
-			options.ReferenceName = plumbing.ReferenceName(parts[1])
+			options.ReferenceName = plumbing.ReferenceName(ref)
 		}
 	}
 
-	if branch := g.opts.GitBranch; branch != "" {
+	if branch := opts.GitBranch; branch != "" {
 		ref, err := getGitReferenceName(directory, url, branch)
 		if err != nil {
-			return directory, err
+			return nil, err
 		}
 		options.ReferenceName = ref
 	}
@@ -102,40 +296,40 @@ func (g *Git) UnpackTarFromBuildContext() (string, error) {
 	logrus.Debugf("Getting source from reference %s", options.ReferenceName)
 	r, err := git.PlainClone(directory, false, &options)
 	if err != nil {
-		return directory, err
+		return nil, err
 	}
 
 	if fetchRef != "" {
 		err = r.Fetch(&git.FetchOptions{
 			RemoteName: "origin",
-			Auth:       getGitAuth(),
+			Auth:       getGitAuthFor(url),
 			RefSpecs:   []config.RefSpec{config.RefSpec(fetchRef + ":" + fetchRef)},
 		})
 		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
-			return directory, err
+			return nil, err
 		}
 		if checkoutRef == "" {
 			ref, err := r.Reference(plumbing.ReferenceName(fetchRef), true)
 			if err != nil {
-				return directory, err
+				return nil, err
 			}
 			checkoutRef = ref.Hash().String()
 		}
 	}
 
-	if len(parts) > 2 {
-		checkoutRef = parts[2]
+	if commit != "" {
+		checkoutRef = commit
 	}
 	if checkoutRef != "" {
 		// ... retrieving the commit being pointed by HEAD
 		_, err := r.Head()
 		if err != nil {
-			return directory, err
+			return nil, err
 		}
 
 		w, err := r.Worktree()
 		if err != nil {
-			return directory, err
+			return nil, err
 		}
 
 		// ... checking out to desired commit
@@ -143,10 +337,10 @@ func (g *Git) UnpackTarFromBuildContext() (string, error) {
 			Hash: plumbing.NewHash(checkoutRef),
 		})
 		if err != nil {
-			return directory, err
+			return nil, err
 		}
 	}
-	return directory, nil
+	return r, nil
 }
 
 func getGitReferenceName(directory string, url string, branch string) (plumbing.ReferenceName, error) {
@@ -161,7 +355,7 @@ func getGitReferenceName(directory string, url string, branch string) (plumbing.
 	)
 
 	refs, err := remote.List(&git.ListOptions{
-		Auth: getGitAuth(),
+		Auth: getGitAuthFor(url),
 	})
 	if err != nil {
 		return plumbing.HEAD, err
@@ -211,6 +405,15 @@ func getGitAuth() transport.AuthMethod {
 	return nil
 }
 
+// getGitAuthFor returns the credentials for url, ssh remotes authenticate
+// through the ssh agent instead.
+func getGitAuthFor(url string) transport.AuthMethod {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil
+	}
+	return getGitAuth()
+}
+
 func getGitPullMethod() string {
 	gitPullMethod := os.Getenv(gitPullMethodEnvKey)
 	if ok := supportedGitPullMethods[gitPullMethod]; !ok {
@@ -218,3 +421,111 @@ func getGitPullMethod() string {
 	}
 	return gitPullMethod
 }
+
+// GitSource is a git repository used as the source of an ADD instruction,
+// ie. ADD https://github.com/org/repo.git#ref:subdir /dest
+type GitSource struct {
+	URL string
+	// Ref is a branch, tag, full reference or commit SHA, the remote HEAD if empty
+	Ref string
+	// Subdir is the directory within the repository to add
+	Subdir string
+}
+
+// ParseGitSource splits src into the repository URL and its #ref:subdir
+// fragment, it returns false if src is not a git URL.
+func ParseGitSource(src string) (GitSource, bool) {
+	if !util.IsSrcGitURL(src) {
+		return GitSource{}, false
+	}
+	url, fragment, _ := strings.Cut(src, "#")
+	ref, subdir, _ := strings.Cut(fragment, ":")
+	return GitSource{URL: url, Ref: ref, Subdir: subdir}, true
+}
+
+// String returns the source in the form it was given.
+func (s GitSource) String() string {
+	if s.Subdir != "" {
+		return s.URL + "#" + s.Ref + ":" + s.Subdir
+	}
+	if s.Ref != "" {
+		return s.URL + "#" + s.Ref
+	}
+	return s.URL
+}
+
+// ResolveCommit returns the SHA of the commit Ref points to, listing the
+// remote references rather than cloning the repository.
+func (s GitSource) ResolveCommit() (string, error) {
+	if plumbing.IsHash(s.Ref) {
+		return s.Ref, nil
+	}
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{s.URL},
+	})
+	refs, err := remote.List(&git.ListOptions{
+		Auth:          getGitAuthFor(s.URL),
+		PeelingOption: git.AppendPeeled,
+	})
+	if err != nil {
+		return "", fmt.Errorf("listing references of %s: %w", s.URL, err)
+	}
+	byName := map[plumbing.ReferenceName]*plumbing.Reference{}
+	for _, ref := range refs {
+		byName[ref.Name()] = ref
+	}
+
+	var candidates []plumbing.ReferenceName
+	switch {
+	case s.Ref == "":
+		candidates = []plumbing.ReferenceName{plumbing.HEAD}
+	case strings.HasPrefix(s.Ref, "refs/"):
+		candidates = []plumbing.ReferenceName{plumbing.ReferenceName(s.Ref)}
+	default:
+		candidates = []plumbing.ReferenceName{plumbing.NewBranchReferenceName(s.Ref), plumbing.NewTagReferenceName(s.Ref)}
+	}
+	for _, name := range candidates {
+		ref, ok := byName[name]
+		// follow HEAD to the branch it points to
+		for ok && ref.Type() == plumbing.SymbolicReference {
+			name = ref.Target()
+			ref, ok = byName[name]
+		}
+		if !ok {
+			continue
+		}
+		// annotated tags are peeled to the commit they point to
+		if peeled, ok := byName[name+"^{}"]; ok {
+			ref = peeled
+		}
+		return ref.Hash().String(), nil
+	}
+	return "", fmt.Errorf("reference %q not found in %s", s.Ref, s.URL)
+}
+
+// Clone clones the repository including its submodules into directory, and
+// returns the SHA of the checked out commit.
+func (s GitSource) Clone(directory string) (string, error) {
+	opts := BuildOptions{GitRecurseSubmodules: true}
+	var ref, commit string
+	switch {
+	case plumbing.IsHash(s.Ref):
+		// not every server allows fetching a SHA, check it out of the full clone instead
+		commit = s.Ref
+	case s.Ref == "" || strings.HasPrefix(s.Ref, "refs/"):
+		ref = s.Ref
+	default:
+		// plain names may be branches or tags
+		opts.GitBranch = s.Ref
+	}
+	r, err := cloneGitRepo(directory, s.URL, ref, commit, opts)
+	if err != nil {
+		return "", fmt.Errorf("cloning %s: %w", s, err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
+}
@@ -23,7 +23,6 @@ import (
 	"os"
 	"path/filepath"
 
-	kConfig "github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/pkg/constants"
 	"github.com/osscontainertools/kaniko/pkg/util"
 	"github.com/sirupsen/logrus"
@@ -32,6 +31,7 @@ import (
 // HTTPSTar struct for https tar.gz files processing
 type HTTPSTar struct {
 	context string
+	opts    BuildOptions
 }
 
 // UnpackTarFromBuildContext downloads context file from https server
@@ -39,7 +39,7 @@ func (h *HTTPSTar) UnpackTarFromBuildContext() (directory string, retErr error)
 	logrus.Info("Retrieving https tar file")
 
 	// Create directory and target file for downloading the context file
-	directory = kConfig.BuildContextDir
+	directory = h.opts.directory()
 	tarPath := filepath.Join(directory, constants.ContextTar)
 	file, err := util.CreateTargetTarfile(tarPath)
 	if err != nil {
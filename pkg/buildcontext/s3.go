@@ -36,6 +36,8 @@ import (
 // S3 unifies calls to download and unpack the build context.
 type S3 struct {
 	context string
+	opts    BuildOptions
+	source  kConfig.ContextSource
 }
 
 // UnpackTarFromBuildContext download and untar a file from s3
@@ -59,7 +61,7 @@ func (s *S3) UnpackTarFromBuildContext() (string, error) {
 		}
 	})
 	downloader := transfermanager.New(client)
-	directory := kConfig.BuildContextDir
+	directory := s.opts.directory()
 	tarPath := filepath.Join(directory, constants.ContextTar)
 	if err := os.MkdirAll(directory, 0o750); err != nil {
 		return directory, err
@@ -68,7 +70,7 @@ func (s *S3) UnpackTarFromBuildContext() (string, error) {
 	if err != nil {
 		return directory, err
 	}
-	_, err = downloader.DownloadObject(context.TODO(), &transfermanager.DownloadObjectInput{
+	out, err := downloader.DownloadObject(context.TODO(), &transfermanager.DownloadObjectInput{
 		Bucket:   aws.String(bucket),
 		Key:      aws.String(item),
 		WriterAt: file,
@@ -76,6 +78,19 @@ func (s *S3) UnpackTarFromBuildContext() (string, error) {
 	if err != nil {
 		return directory, err
 	}
+	uri := fmt.Sprintf("s3://%s/%s", bucket, item)
+	if v := aws.ToString(out.VersionID); v != "" {
+		uri += "?versionId=" + v
+	}
+	s.source, err = fileSource(uri, tarPath)
+	if err != nil {
+		return directory, err
+	}
 
 	return directory, util.UnpackCompressedTar(tarPath, directory)
 }
+
+// Source returns the object with its version, and the digest of its content.
+func (s *S3) Source() kConfig.ContextSource {
+	return s.source
+}
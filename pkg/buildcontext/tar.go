@@ -22,7 +22,6 @@ import (
 	"fmt"
 	"os"
 
-	kConfig "github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/pkg/util"
 	"github.com/sirupsen/logrus"
 )
@@ -30,11 +29,12 @@ import (
 // Tar unifies calls to download and unpack the build context.
 type Tar struct {
 	context string
+	opts    BuildOptions
 }
 
 // UnpackTarFromBuildContext unpack the compressed tar file
 func (t *Tar) UnpackTarFromBuildContext() (string, error) {
-	directory := kConfig.BuildContextDir
+	directory := t.opts.directory()
 	if err := os.MkdirAll(directory, 0o750); err != nil {
 		return "", fmt.Errorf("unpacking tar from build context: %w", err)
 	}
@@ -17,24 +17,35 @@ limitations under the License.
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"time"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	digest "github.com/opencontainers/go-digest"
 	"github.com/osscontainertools/kaniko/pkg/assert"
+	"github.com/osscontainertools/kaniko/pkg/buildcontext"
 	kConfig "github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/pkg/dockerfile"
 	"github.com/osscontainertools/kaniko/pkg/util"
 	"github.com/sirupsen/logrus"
+	mode "github.com/tonistiigi/dchapes-mode"
 )
 
 type AddCommand struct {
 	BaseCommand
 	cmd           *instructions.AddCommand
 	fileContext   util.FileContext
+	urlCache      *util.URLCache
 	snapshotFiles []string
 	shdCache      bool
+	linkTarPath   string
+	epoch         *time.Time
+	ctx           context.Context
 }
 
 // ExecuteCommand executes the ADD command
@@ -43,12 +54,19 @@ type AddCommand struct {
 //     - destination will have permissions of 0600
 //     - If remote file has HTTP Last-Modified header, we set the mtime of the file to that timestamp
 //     - If dest doesn't end with a slash, the filepath is inferred to be <dest>/<filename>
+//     - With a URL cache, it is only downloaded again if the server reports a change
 //  2. If <src> is a local tar archive:
 //     - it is unpacked at the dest, as 'tar -x' would
+//     - --unpack=false copies it instead, --unpack=true unpacks remote archives too
+//  3. If <src> is a git repository:
+//     - it is cloned, and the checked out subdir is copied to dest
+//     - the .git directory is only copied with --keep-git-dir
+//  4. With --link all of the above is written to an empty staging root,
+//     which becomes the layer
 func (a *AddCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.BuildArgs) error {
 	replacementEnvs := buildArgs.ReplacementEnvs(config.Env)
 
-	chmod, _, err := util.GetChmod(a.cmd.Chmod, replacementEnvs)
+	chmod, useDefaultChmod, err := util.GetChmod(a.cmd.Chmod, replacementEnvs)
 	if err != nil {
 		return fmt.Errorf("getting permissions from chmod: %w", err)
 	}
@@ -58,30 +76,81 @@ func (a *AddCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.Bui
 		return fmt.Errorf("getting user group from chown: %w", err)
 	}
 
-	srcs, dest, err := util.ResolveEnvAndWildcards(a.cmd.SourcesAndDest, a.fileContext, replacementEnvs)
+	fileContext, err := withExcludes(a.fileContext, a.cmd.ExcludePatterns, replacementEnvs)
 	if err != nil {
 		return err
 	}
 
+	srcs, dest, err := util.ResolveEnvAndWildcards(a.cmd.SourcesAndDest, fileContext, replacementEnvs, false)
+	if err != nil {
+		return err
+	}
+
+	checksum, err := addChecksum(a.cmd, srcs, replacementEnvs)
+	if err != nil {
+		return err
+	}
+
+	var stage *linkStage
+	if a.cmd.Link {
+		stage, err = newLinkStage(a.epoch)
+		if err != nil {
+			return err
+		}
+		defer stage.cleanup()
+		cwd := config.WorkingDir
+		if cwd == "" {
+			cwd = kConfig.RootDir
+		}
+		dest = stage.dest(dest, cwd)
+	}
+
 	var unresolvedSrcs []string
 	// If any of the sources are local tar archives:
 	// 	1. Unpack them to the specified destination
+	// If any of the sources is a git repository:
+	//	1. Clone it and copy its contents to the specified dest
 	// If any of the sources is a remote file URL:
 	//	1. Download and copy it to the specified dest
 	// Else, add to the list of unresolved sources
 	for _, src := range srcs {
-		fullPath := filepath.Join(a.fileContext.Root, src)
-		if util.IsSrcRemoteFileURL(src) {
+		fullPath := filepath.Join(fileContext.Root, src)
+		if gitSrc, ok := buildcontext.ParseGitSource(src); ok {
+			gitDest, err := util.DestinationFilepath("", dest, config.WorkingDir)
+			if err != nil {
+				return fmt.Errorf("determining dest for git repository: %w", err)
+			}
+			copiedFiles, err := addGitSource(gitSrc, gitDest, a.keepGitDir(), uid, gid, chmod, useDefaultChmod)
+			if err != nil {
+				return err
+			}
+			a.snapshotFiles = append(a.snapshotFiles, copiedFiles...)
+		} else if util.IsSrcRemoteFileURL(src) {
 			urlDest, err := util.URLDestinationFilepath(src, dest, config.WorkingDir, replacementEnvs)
 			if err != nil {
 				return err
 			}
 			logrus.Infof("Adding remote URL %s to %s", src, urlDest)
-			if err := util.DownloadFileToDest(src, urlDest, uid, gid, chmod.Apply(0o600)); err != nil {
+			if a.urlCache != nil {
+				err = a.urlCache.CopyToDest(a.ctx, src, urlDest, uid, gid, chmod.Apply(0o600), checksum)
+			} else {
+				err = util.DownloadFileToDest(a.ctx, src, urlDest, uid, gid, chmod.Apply(0o600), checksum)
+			}
+			if err != nil {
 				return fmt.Errorf("downloading remote source file: %w", err)
 			}
+			if a.unpack(true) && util.IsFileLocalTarArchive(urlDest) {
+				extractedFiles, err := unpackRemoteArchive(src, urlDest, dest, config.WorkingDir)
+				if err != nil {
+					return err
+				}
+				a.snapshotFiles = append(a.snapshotFiles, extractedFiles...)
+				continue
+			}
 			a.snapshotFiles = append(a.snapshotFiles, urlDest)
-		} else if util.IsFileLocalTarArchive(fullPath) {
+		} else if fileContext.ExcludesFile(fullPath) {
+			logrus.Debugf("%s is excluded, ignoring", src)
+		} else if a.unpack(false) && util.IsFileLocalTarArchive(fullPath) {
 			tarDest, err := util.DestinationFilepath("", dest, config.WorkingDir)
 			if err != nil {
 				return fmt.Errorf("determining dest for tar: %w", err)
@@ -99,26 +168,97 @@ func (a *AddCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.Bui
 	}
 	// With the remaining "normal" sources, create and execute a standard copy command
 	heredocs := a.cmd.SourceContents
-	if len(unresolvedSrcs) == 0 && len(heredocs) == 0 {
-		return nil
-	}
+	if len(unresolvedSrcs) > 0 || len(heredocs) > 0 {
+		copyCmd := CopyCommand{
+			cmd: &instructions.CopyCommand{
+				SourcesAndDest:  instructions.SourcesAndDest{SourcePaths: unresolvedSrcs, DestPath: dest, SourceContents: heredocs},
+				Chown:           a.cmd.Chown,
+				Chmod:           a.cmd.Chmod,
+				ExcludePatterns: a.cmd.ExcludePatterns,
+			},
+			fileContext: a.fileContext,
+		}
 
-	copyCmd := CopyCommand{
-		cmd: &instructions.CopyCommand{
-			SourcesAndDest: instructions.SourcesAndDest{SourcePaths: unresolvedSrcs, DestPath: dest, SourceContents: heredocs},
-			Chown:          a.cmd.Chown,
-			Chmod:          a.cmd.Chmod,
-		},
-		fileContext: a.fileContext,
+		if err := copyCmd.ExecuteCommand(config, buildArgs); err != nil {
+			return fmt.Errorf("executing copy command: %w", err)
+		}
+		a.snapshotFiles = append(a.snapshotFiles, copyCmd.snapshotFiles...)
 	}
 
-	if err := copyCmd.ExecuteCommand(config, buildArgs); err != nil {
-		return fmt.Errorf("executing copy command: %w", err)
+	if stage != nil {
+		a.linkTarPath, a.snapshotFiles, err = stage.commit()
+		if err != nil {
+			return err
+		}
 	}
-	a.snapshotFiles = append(a.snapshotFiles, copyCmd.snapshotFiles...)
 	return nil
 }
 
+func (a *AddCommand) keepGitDir() bool {
+	return a.cmd.KeepGitDir != nil && *a.cmd.KeepGitDir
+}
+
+// unpack reports whether archives are extracted, without --unpack only
+// local archives are.
+func (a *AddCommand) unpack(remote bool) bool {
+	if a.cmd.Unpack != nil {
+		return *a.cmd.Unpack
+	}
+	return !remote
+}
+
+// unpackRemoteArchive extracts the archive downloaded from src to path into
+// dest. It is moved aside first, so it can't collide with its own content.
+func unpackRemoteArchive(src, path, dest, cwd string) ([]string, error) {
+	tarDest, err := util.DestinationFilepath("", dest, cwd)
+	if err != nil {
+		return nil, fmt.Errorf("determining dest for tar: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".kaniko-unpack-")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	if err := os.Rename(path, tmp.Name()); err != nil {
+		return nil, err
+	}
+	logrus.Infof("Unpacking remote tar archive %s to %s", src, tarDest)
+	extractedFiles, err := util.UnpackLocalTarArchive(tmp.Name(), tarDest)
+	if err != nil {
+		return nil, fmt.Errorf("unpacking remote tar: %w", err)
+	}
+	return extractedFiles, nil
+}
+
+// addGitSource clones src into a temporary directory and copies its subdir to dest.
+func addGitSource(src buildcontext.GitSource, dest string, keepGitDir bool, uid, gid int64, chmod mode.Set, useDefaultChmod bool) ([]string, error) {
+	tmp, err := os.MkdirTemp(kConfig.KanikoDir, "git-")
+	if err != nil {
+		return nil, fmt.Errorf("creating temp dir for git repository: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	logrus.Infof("Cloning git repository %s", src)
+	commit, err := src.Clone(tmp)
+	if err != nil {
+		return nil, err
+	}
+	if !keepGitDir {
+		if err := os.RemoveAll(filepath.Join(tmp, ".git")); err != nil {
+			return nil, fmt.Errorf("removing .git dir: %w", err)
+		}
+	}
+	// the subdir can't point outside of the repository
+	root := filepath.Join(tmp, filepath.Clean("/"+src.Subdir))
+	logrus.Infof("Adding git repository %s at %s to %s", src, commit, dest)
+	copiedFiles, err := util.CopyDir(root, dest, util.FileContext{Root: root}, uid, gid, chmod, useDefaultChmod)
+	if err != nil {
+		return nil, fmt.Errorf("copying git repository: %w", err)
+	}
+	return copiedFiles, nil
+}
+
 // FilesToSnapshot should return an empty array if still nil; no files were changed
 func (a *AddCommand) FilesToSnapshot() []string {
 	return a.snapshotFiles
@@ -130,7 +270,78 @@ func (a *AddCommand) String() string {
 }
 
 func (a *AddCommand) CacheKey(replacementEnvs []string) (string, error) {
-	return resolvedCacheKey(a.cmd.String(), a.cmd.SourceContents, replacementEnvs)
+	return addCacheKey(a.cmd, replacementEnvs)
+}
+
+// addCacheKey pins the key to the declared checksum, so a cached ADD of a
+// remote URL is only reused for the exact content it was built from.
+func addCacheKey(cmd *instructions.AddCommand, replacementEnvs []string) (string, error) {
+	key, err := resolvedCacheKey(cmd.String(), cmd.SourceContents, replacementEnvs)
+	if err != nil {
+		return "", err
+	}
+	if cmd.Checksum == "" {
+		return key, nil
+	}
+	checksum, err := util.ResolveEnvironmentReplacement(cmd.Checksum, replacementEnvs, false)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%schecksum:%s", key, checksum), nil
+}
+
+// addChecksum resolves the --checksum flag, which like in buildkit is only
+// valid for a single remote source.
+func addChecksum(cmd *instructions.AddCommand, srcs []string, replacementEnvs []string) (digest.Digest, error) {
+	if cmd.Checksum == "" {
+		return "", nil
+	}
+	resolved, err := util.ResolveEnvironmentReplacement(cmd.Checksum, replacementEnvs, false)
+	if err != nil {
+		return "", err
+	}
+	checksum, err := digest.Parse(resolved)
+	if err != nil {
+		return "", fmt.Errorf("invalid checksum %q: %w", resolved, err)
+	}
+	if len(srcs) != 1 || !util.IsSrcRemoteFileURL(srcs[0]) || util.IsSrcGitURL(srcs[0]) {
+		return "", errors.New("checksum can only be specified for a single HTTP(S) source")
+	}
+	return checksum, nil
+}
+
+// RemoteSourceKeys returns the commit each git source resolves to, and the
+// content digest of remote files if they are cached.
+func (a *AddCommand) RemoteSourceKeys(replacementEnvs []string) ([]string, error) {
+	return addRemoteSourceKeys(a.ctx, a.cmd, a.urlCache, replacementEnvs)
+}
+
+// addRemoteSourceKeys pins the cache key to the commits of git sources, so a
+// cached ADD of a branch is rebuilt once the branch moves. Remote files are
+// only pinned to their content with a URL cache, which avoids downloading
+// them twice, and unless --checksum, part of the cache key, pins it already.
+func addRemoteSourceKeys(ctx context.Context, cmd *instructions.AddCommand, urlCache *util.URLCache, replacementEnvs []string) ([]string, error) {
+	var keys []string
+	for _, src := range cmd.SourcePaths {
+		resolved, err := util.ResolveEnvironmentReplacement(src, replacementEnvs, true)
+		if err != nil {
+			return nil, err
+		}
+		if gitSrc, ok := buildcontext.ParseGitSource(resolved); ok {
+			commit, err := gitSrc.ResolveCommit()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, fmt.Sprintf("git:%s@%s", gitSrc.URL, commit))
+		} else if urlCache != nil && cmd.Checksum == "" && util.IsSrcRemoteFileURL(resolved) {
+			cached, err := urlCache.Fetch(ctx, resolved)
+			if err != nil {
+				return nil, fmt.Errorf("fetching %s: %w", resolved, err)
+			}
+			keys = append(keys, fmt.Sprintf("url:%s@%s", resolved, cached.Digest))
+		}
+	}
+	return keys, nil
 }
 
 func (a *AddCommand) FilesUsedFromContext(config *v1.Config, buildArgs *dockerfile.BuildArgs) ([]string, error) {
@@ -145,6 +356,18 @@ func (a *AddCommand) RequiresUnpackedFS() bool {
 	return true
 }
 
+func (a *AddCommand) Link() bool {
+	return a.cmd.Link
+}
+
+func (a *AddCommand) LinkKey(config *v1.Config, buildArgs *dockerfile.BuildArgs) ([]string, error) {
+	return linkKey(a.cmd.SourcesAndDest, a.cmd.Chown, a.cmd.Chmod, config.User, config, buildArgs.ReplacementEnvs(config.Env))
+}
+
+func (a *AddCommand) LinkTarPath() string {
+	return a.linkTarPath
+}
+
 func (a *AddCommand) ShouldCacheOutput() bool {
 	return a.shdCache
 }
@@ -155,7 +378,9 @@ func (a *AddCommand) CacheCommand(img v1.Image) DockerCommand {
 		img:         img,
 		cmd:         a.cmd,
 		fileContext: a.fileContext,
+		urlCache:    a.urlCache,
 		extractFn:   util.ExtractFile,
+		ctx:         a.ctx,
 	}
 }
 
@@ -166,7 +391,9 @@ type CachingAddCommand struct {
 	extractedFiles []string
 	cmd            *instructions.AddCommand
 	fileContext    util.FileContext
+	urlCache       *util.URLCache
 	extractFn      util.ExtractFunction
+	ctx            context.Context
 }
 
 func (ca *CachingAddCommand) ExecuteCommand(_ *v1.Config, _ *dockerfile.BuildArgs) error {
@@ -221,7 +448,19 @@ func (ca *CachingAddCommand) String() string {
 }
 
 func (ca *CachingAddCommand) CacheKey(replacementEnvs []string) (string, error) {
-	return resolvedCacheKey(ca.cmd.String(), ca.cmd.SourceContents, replacementEnvs)
+	return addCacheKey(ca.cmd, replacementEnvs)
+}
+
+func (ca *CachingAddCommand) RemoteSourceKeys(replacementEnvs []string) ([]string, error) {
+	return addRemoteSourceKeys(ca.ctx, ca.cmd, ca.urlCache, replacementEnvs)
+}
+
+func (ca *CachingAddCommand) Link() bool {
+	return ca.cmd.Link
+}
+
+func (ca *CachingAddCommand) LinkKey(config *v1.Config, buildArgs *dockerfile.BuildArgs) ([]string, error) {
+	return linkKey(ca.cmd.SourcesAndDest, ca.cmd.Chown, ca.cmd.Chmod, config.User, config, buildArgs.ReplacementEnvs(config.Env))
 }
 
 func addCmdFilesUsedFromContext(config *v1.Config, buildArgs *dockerfile.BuildArgs, cmd *instructions.AddCommand,
@@ -229,14 +468,19 @@ func addCmdFilesUsedFromContext(config *v1.Config, buildArgs *dockerfile.BuildAr
 ) ([]string, error) {
 	replacementEnvs := buildArgs.ReplacementEnvs(config.Env)
 
-	srcs, _, err := util.ResolveEnvAndWildcards(cmd.SourcesAndDest, fileContext, replacementEnvs)
+	fileContext, err := withExcludes(fileContext, cmd.ExcludePatterns, replacementEnvs)
+	if err != nil {
+		return nil, err
+	}
+
+	srcs, _, err := util.ResolveEnvAndWildcards(cmd.SourcesAndDest, fileContext, replacementEnvs, false)
 	if err != nil {
 		return nil, err
 	}
 
 	files := []string{}
 	for _, src := range srcs {
-		if util.IsSrcRemoteFileURL(src) {
+		if util.IsSrcRemoteFileURL(src) || util.IsSrcGitURL(src) {
 			continue
 		}
 		if util.IsFileLocalTarArchive(src) {
@@ -246,8 +490,14 @@ func addCmdFilesUsedFromContext(config *v1.Config, buildArgs *dockerfile.BuildAr
 		files = append(files, fullPath)
 	}
 
-	// Remote URLs and tar archives are filtered out, so the result cannot exceed the source count.
+	// Remote URLs, git repositories and tar archives are filtered out, so the result cannot exceed the source count.
 	assert.Assert("add.files-count", len(files) <= len(srcs), "addCmdFilesUsedFromContext: result exceeds source count (srcs=%d, files=%d)", len(srcs), len(files))
+	if len(cmd.ExcludePatterns) > 0 {
+		files, err = filesNotExcluded(files, fileContext)
+		if err != nil {
+			return nil, err
+		}
+	}
 	logrus.Infof("Using files from context: %v", files)
 	return files, nil
 }
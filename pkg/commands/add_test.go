@@ -0,0 +1,224 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/osscontainertools/kaniko/pkg/buildcontext"
+	kConfig "github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/osscontainertools/kaniko/pkg/dockerfile"
+	"github.com/osscontainertools/kaniko/pkg/util"
+	"github.com/osscontainertools/kaniko/testutil"
+	mode "github.com/tonistiigi/dchapes-mode"
+)
+
+const testChecksum = "sha256:24454f830cdb571e2c4ad15481119c43b3cafd48dd869a9b2945d1036d1dc68d"
+
+func Test_addChecksum(t *testing.T) {
+	tests := []struct {
+		name     string
+		checksum string
+		srcs     []string
+		envs     []string
+		want     string
+		wantErr  string
+	}{
+		{name: "no checksum", srcs: []string{"file"}},
+		{name: "remote source", checksum: testChecksum, srcs: []string{"https://example.com/file"}, want: testChecksum},
+		{name: "from arg", checksum: "$SUM", srcs: []string{"https://example.com/file"}, envs: []string{"SUM=" + testChecksum}, want: testChecksum},
+		{name: "invalid", checksum: "sha256:nothex", srcs: []string{"https://example.com/file"}, wantErr: "invalid checksum"},
+		{name: "local source", checksum: testChecksum, srcs: []string{"file"}, wantErr: "single HTTP(S) source"},
+		{name: "multiple sources", checksum: testChecksum, srcs: []string{"https://example.com/a", "https://example.com/b"}, wantErr: "single HTTP(S) source"},
+		{name: "git source", checksum: testChecksum, srcs: []string{"https://github.com/org/repo.git"}, wantErr: "single HTTP(S) source"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &instructions.AddCommand{Checksum: tt.checksum}
+			got, err := addChecksum(cmd, tt.srcs, tt.envs)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			testutil.CheckNoError(t, err)
+			testutil.CheckDeepEqual(t, tt.want, string(got))
+		})
+	}
+}
+
+func Test_addCacheKey_Checksum(t *testing.T) {
+	cmd := &instructions.AddCommand{
+		SourcesAndDest: instructions.SourcesAndDest{SourcePaths: []string{"https://example.com/file"}, DestPath: "/file"},
+	}
+	unpinned, err := addCacheKey(cmd, nil)
+	testutil.CheckNoError(t, err)
+
+	cmd.Checksum = "$SUM"
+	pinned, err := addCacheKey(cmd, []string{"SUM=" + testChecksum})
+	testutil.CheckNoError(t, err)
+	if pinned == unpinned || !strings.HasSuffix(pinned, "checksum:"+testChecksum) {
+		t.Errorf("expected the resolved checksum in the cache key, got %q", pinned)
+	}
+}
+
+func Test_addGitSource(t *testing.T) {
+	repo := t.TempDir()
+	r, err := git.PlainInit(repo, false)
+	testutil.CheckNoError(t, err)
+	testutil.CheckNoError(t, testutil.SetupFiles(repo, map[string]string{"sub/file": "content", "other": "other"}))
+	w, err := r.Worktree()
+	testutil.CheckNoError(t, err)
+	testutil.CheckNoError(t, w.AddGlob("."))
+	_, err = w.Commit("initial", &git.CommitOptions{Author: &object.Signature{Name: "kaniko", Email: "kaniko@example.com"}})
+	testutil.CheckNoError(t, err)
+
+	original := kConfig.KanikoDir
+	defer func() { kConfig.KanikoDir = original }()
+	kConfig.KanikoDir = t.TempDir()
+	uid, gid := int64(os.Getuid()), int64(os.Getgid())
+
+	t.Run("subdir", func(t *testing.T) {
+		dest := t.TempDir()
+		src := buildcontext.GitSource{URL: "file://" + repo, Subdir: "sub"}
+		files, err := addGitSource(src, dest, false, uid, gid, mode.Set{}, true)
+		testutil.CheckNoError(t, err)
+		testutil.CheckDeepEqual(t, []string{dest, filepath.Join(dest, "file")}, files)
+	})
+
+	for _, keepGitDir := range []bool{false, true} {
+		dest := t.TempDir()
+		src := buildcontext.GitSource{URL: "file://" + repo}
+		_, err := addGitSource(src, dest, keepGitDir, uid, gid, mode.Set{}, true)
+		testutil.CheckNoError(t, err)
+		_, err = os.Stat(filepath.Join(dest, ".git"))
+		testutil.CheckDeepEqual(t, keepGitDir, err == nil)
+	}
+}
+
+func Test_addRemoteSourceKeys_URLCache(t *testing.T) {
+	content := "v1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+	cmd := &instructions.AddCommand{
+		SourcesAndDest: instructions.SourcesAndDest{SourcePaths: []string{server.URL + "/file", "local"}, DestPath: "/dest/"},
+	}
+
+	keys, err := addRemoteSourceKeys(t.Context(), cmd, nil, nil)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, 0, len(keys))
+
+	cache := &util.URLCache{Dir: t.TempDir()}
+	keys, err = addRemoteSourceKeys(t.Context(), cmd, cache, nil)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, []string{"url:" + server.URL + "/file@" + digest.FromString("v1").String()}, keys)
+
+	content = "v2"
+	changed, err := addRemoteSourceKeys(t.Context(), cmd, cache, nil)
+	testutil.CheckNoError(t, err)
+	if changed[0] == keys[0] {
+		t.Errorf("expected the key to change with the content, got %q", changed[0])
+	}
+
+	// a checksum pins the content without downloading it
+	requests := 0
+	pinned := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(content))
+	}))
+	defer pinned.Close()
+	cmd = &instructions.AddCommand{
+		SourcesAndDest: instructions.SourcesAndDest{SourcePaths: []string{pinned.URL + "/file"}, DestPath: "/dest/"},
+		Checksum:       digest.FromString("v2").String(),
+	}
+	keys, err = addRemoteSourceKeys(t.Context(), cmd, cache, nil)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, 0, len(keys))
+	testutil.CheckDeepEqual(t, 0, requests)
+}
+
+func TestAddCommand_Unpack(t *testing.T) {
+	var archive bytes.Buffer
+	gzw := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gzw)
+	testutil.CheckNoError(t, tw.WriteHeader(&tar.Header{Name: "hello.txt", Mode: 0o644, Size: 6, Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte("hello\n"))
+	testutil.CheckNoError(t, err)
+	testutil.CheckNoError(t, tw.Close())
+	testutil.CheckNoError(t, gzw.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive.Bytes())
+	}))
+	defer server.Close()
+
+	unpack, keep := true, false
+	tests := []struct {
+		name     string
+		src      string
+		unpack   *bool
+		expected string
+	}{
+		{name: "local archive", src: "archive.tar.gz", expected: "hello.txt"},
+		{name: "local archive without unpack", src: "archive.tar.gz", unpack: &keep, expected: "archive.tar.gz"},
+		{name: "remote archive", src: server.URL + "/archive.tar.gz", expected: "archive.tar.gz"},
+		{name: "remote archive with unpack", src: server.URL + "/archive.tar.gz", unpack: &unpack, expected: "hello.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDir := t.TempDir()
+			context := filepath.Join(testDir, "context")
+			testutil.CheckNoError(t, os.MkdirAll(context, 0o755))
+			testutil.CheckNoError(t, os.WriteFile(filepath.Join(context, "archive.tar.gz"), archive.Bytes(), 0o644))
+			dest := filepath.Join(testDir, "dest") + "/"
+
+			cmd := AddCommand{
+				cmd: &instructions.AddCommand{
+					SourcesAndDest: instructions.SourcesAndDest{SourcePaths: []string{tt.src}, DestPath: dest},
+					Unpack:         tt.unpack,
+				},
+				fileContext: util.FileContext{Root: context},
+				ctx:         t.Context(),
+			}
+			testutil.CheckNoError(t, cmd.ExecuteCommand(&v1.Config{}, dockerfile.NewBuildArgs([]string{})))
+
+			entries, err := os.ReadDir(dest)
+			testutil.CheckNoError(t, err)
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			testutil.CheckDeepEqual(t, []string{tt.expected}, names)
+		})
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package commands
 
 import (
+	"context"
 	"fmt"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -71,15 +72,25 @@ type CacheKeyResolver interface {
 	CacheKey(replacementEnvs []string) (string, error)
 }
 
-func GetCommand(cmd instructions.Command, fileContext util.FileContext, secrets config.SecretOptions, useNewRun bool, cacheCopy bool, cacheRun bool) (DockerCommand, error) {
+// Implement to add the state of remote sources to the cache key, ie. the commit a git
+// ref points to. Unlike files from the context they can't be hashed without fetching.
+type RemoteSourceKeyer interface {
+	RemoteSourceKeys(replacementEnvs []string) ([]string, error)
+}
+
+// GetCommand returns the kaniko implementation of cmd. Processes started by RUN
+// are killed and ADD downloads aborted when ctx is cancelled, --link layers are dated no later than its
+// SOURCE_DATE_EPOCH. Remote files added by ADD are cached in urlCacheDir unless
+// it is empty.
+func GetCommand(ctx context.Context, cmd instructions.Command, fileContext util.FileContext, secrets config.SecretOptions, urlCacheDir string, useNewRun bool, cacheCopy bool, cacheRun bool) (DockerCommand, error) {
 	switch c := cmd.(type) {
 	case *instructions.RunCommand:
 		if useNewRun {
-			return &RunMarkerCommand{cmd: c, fileContext: fileContext, secrets: secrets, shdCache: cacheRun}, nil
+			return &RunMarkerCommand{ctx: ctx, cmd: c, fileContext: fileContext, secrets: secrets, shdCache: cacheRun}, nil
 		}
-		return &RunCommand{cmd: c, fileContext: fileContext, secrets: secrets, shdCache: cacheRun}, nil
+		return &RunCommand{ctx: ctx, cmd: c, fileContext: fileContext, secrets: secrets, shdCache: cacheRun}, nil
 	case *instructions.CopyCommand:
-		return &CopyCommand{cmd: c, fileContext: fileContext, shdCache: cacheCopy}, nil
+		return &CopyCommand{cmd: c, fileContext: fileContext, shdCache: cacheCopy, epoch: config.SourceDateEpoch(ctx)}, nil
 	case *instructions.ExposeCommand:
 		return &ExposeCommand{cmd: c}, nil
 	case *instructions.EnvCommand:
@@ -87,7 +98,11 @@ func GetCommand(cmd instructions.Command, fileContext util.FileContext, secrets
 	case *instructions.WorkdirCommand:
 		return &WorkdirCommand{cmd: c, shdCache: cacheRun}, nil
 	case *instructions.AddCommand:
-		return &AddCommand{cmd: c, fileContext: fileContext, shdCache: cacheCopy}, nil
+		var urlCache *util.URLCache
+		if urlCacheDir != "" {
+			urlCache = &util.URLCache{Dir: urlCacheDir}
+		}
+		return &AddCommand{cmd: c, fileContext: fileContext, urlCache: urlCache, shdCache: cacheCopy, epoch: config.SourceDateEpoch(ctx), ctx: ctx}, nil
 	case *instructions.CmdCommand:
 		return &CmdCommand{cmd: c}, nil
 	case *instructions.EntrypointCommand:
@@ -19,9 +19,11 @@ package commands
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
@@ -44,6 +46,8 @@ type CopyCommand struct {
 	fileContext   util.FileContext
 	snapshotFiles []string
 	shdCache      bool
+	linkTarPath   string
+	epoch         *time.Time
 }
 
 func (c *CopyCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.BuildArgs) error {
@@ -52,7 +56,7 @@ func (c *CopyCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.Bu
 	var err error
 	replacementEnvs := buildArgs.ReplacementEnvs(config.Env)
 	if c.cmd.From != "" {
-		c.fileContext = util.FileContext{Root: filepath.Join(kConfig.KanikoInterStageDepsDir, c.cmd.From)}
+		c.fileContext = c.fileContext.From(c.cmd.From)
 		uid, gid, err = getUserGroup(c.cmd.Chown, replacementEnvs)
 		if err != nil {
 			return fmt.Errorf("getting user group from chown: %w", err)
@@ -72,8 +76,13 @@ func (c *CopyCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.Bu
 		}
 	}
 
+	fileContext, err := withExcludes(c.fileContext, c.cmd.ExcludePatterns, replacementEnvs)
+	if err != nil {
+		return err
+	}
+
 	// sources from the Copy command are resolved with wildcards {*?[}
-	srcs, dest, err := util.ResolveEnvAndWildcards(c.cmd.SourcesAndDest, c.fileContext, replacementEnvs)
+	srcs, dest, err := util.ResolveEnvAndWildcards(c.cmd.SourcesAndDest, fileContext, replacementEnvs, c.cmd.Parents)
 	if err != nil {
 		return fmt.Errorf("resolving src: %w", err)
 	}
@@ -83,9 +92,23 @@ func (c *CopyCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.Bu
 		return fmt.Errorf("getting permissions from chmod: %w", err)
 	}
 
+	cwd := config.WorkingDir
+	if cwd == "" {
+		cwd = kConfig.RootDir
+	}
+	var stage *linkStage
+	if c.cmd.Link {
+		stage, err = newLinkStage(c.epoch)
+		if err != nil {
+			return err
+		}
+		defer stage.cleanup()
+		dest = stage.dest(dest, cwd)
+	}
+
 	// For each source, iterate through and copy it over
 	for _, src := range srcs {
-		fullPath := filepath.Join(c.fileContext.Root, src)
+		fullPath := filepath.Join(fileContext.Root, src)
 
 		fi, err := os.Lstat(fullPath)
 		if err != nil {
@@ -94,14 +117,15 @@ func (c *CopyCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.Bu
 		if fi.IsDir() && !strings.HasSuffix(fullPath, string(os.PathSeparator)) {
 			fullPath += "/"
 		}
-		cwd := config.WorkingDir
-		if cwd == "" {
-			cwd = kConfig.RootDir
-		}
 
-		destPath, err := util.DestinationFilepath(fullPath, dest, cwd)
-		if err != nil {
-			return fmt.Errorf("find destination path: %w", err)
+		var destPath string
+		if c.cmd.Parents {
+			destPath = util.ParentsDestinationFilepath(src, dest, cwd)
+		} else {
+			destPath, err = util.DestinationFilepath(fullPath, dest, cwd)
+			if err != nil {
+				return fmt.Errorf("find destination path: %w", err)
+			}
 		}
 
 		// If the destination dir is a symlink we need to resolve the path and use
@@ -112,14 +136,14 @@ func (c *CopyCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.Bu
 		}
 
 		if fi.IsDir() {
-			copiedFiles, err := util.CopyDir(fullPath, destPath, c.fileContext, uid, gid, chmod, useDefaultChmod)
+			copiedFiles, err := util.CopyDir(fullPath, destPath, fileContext, uid, gid, chmod, useDefaultChmod)
 			if err != nil {
 				return fmt.Errorf("copying dir: %w", err)
 			}
 			c.snapshotFiles = append(c.snapshotFiles, copiedFiles...)
 		} else if util.IsSymlink(fi) {
 			// If file is a symlink, we want to copy the target file to destPath
-			exclude, err := util.CopySymlink(fullPath, destPath, c.fileContext)
+			exclude, err := util.CopySymlink(fullPath, destPath, fileContext)
 			if err != nil {
 				return fmt.Errorf("copying symlink: %w", err)
 			}
@@ -129,7 +153,7 @@ func (c *CopyCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.Bu
 			c.snapshotFiles = append(c.snapshotFiles, destPath)
 		} else {
 			// ... Else, we want to copy over a file
-			exclude, err := util.CopyFile(fullPath, destPath, c.fileContext, uid, gid, chmod, useDefaultChmod)
+			exclude, err := util.CopyFile(fullPath, destPath, fileContext, uid, gid, chmod, useDefaultChmod)
 			if err != nil {
 				return fmt.Errorf("copying file: %w", err)
 			}
@@ -142,20 +166,16 @@ func (c *CopyCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.Bu
 
 	// Heredocs
 	for _, src := range c.cmd.SourceContents {
-		fullPath := filepath.Join(c.fileContext.Root, src.Path)
-		cwd := config.WorkingDir
-		if cwd == "" {
-			cwd = kConfig.RootDir
-		}
+		fullPath := filepath.Join(fileContext.Root, src.Path)
 		destPath, err := util.DestinationFilepath(fullPath, dest, cwd)
 		if err != nil {
 			return fmt.Errorf("find destination path: %w", err)
 		}
-		if util.HasFilepathPrefix(destPath, kConfig.KanikoDir, false) {
+		if util.IsKanikoDest(destPath) {
 			logrus.Warnf("Skipping copy targeting kaniko directory: %s", destPath)
 			logrus.Info("Writes to the kaniko directory are blocked to prevent overwriting the executor.")
 			logrus.Info("To copy files there, relocate kaniko with KANIKO_DIR: https://github.com/osscontainertools/kaniko#bootstrapping-kaniko")
-			return nil
+			break
 		}
 
 		data := src.Data
@@ -175,6 +195,12 @@ func (c *CopyCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.Bu
 		c.snapshotFiles = append(c.snapshotFiles, destPath)
 	}
 
+	if stage != nil {
+		c.linkTarPath, c.snapshotFiles, err = stage.commit()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -232,6 +258,32 @@ func (c *CopyCommand) From() string {
 	return c.cmd.From
 }
 
+func (c *CopyCommand) Parents() bool {
+	return c.cmd.Parents
+}
+
+func (c *CopyCommand) Link() bool {
+	return c.cmd.Link
+}
+
+func (c *CopyCommand) LinkKey(config *v1.Config, buildArgs *dockerfile.BuildArgs) ([]string, error) {
+	return copyLinkKey(c.cmd, config, buildArgs)
+}
+
+// copyLinkKey is the LinkKey of cmd, files copied from another stage or with
+// FF_KANIKO_COPY_AS_ROOT belong to root unless --chown says otherwise.
+func copyLinkKey(cmd *instructions.CopyCommand, config *v1.Config, buildArgs *dockerfile.BuildArgs) ([]string, error) {
+	user := config.User
+	if cmd.From != "" || kConfig.FF.CopyAsRoot {
+		user = ""
+	}
+	return linkKey(cmd.SourcesAndDest, cmd.Chown, cmd.Chmod, user, config, buildArgs.ReplacementEnvs(config.Env))
+}
+
+func (c *CopyCommand) LinkTarPath() string {
+	return c.linkTarPath
+}
+
 func (c *CopyCommand) ShouldCacheOutput() bool {
 	return c.shdCache
 }
@@ -315,6 +367,18 @@ func (cr *CachingCopyCommand) From() string {
 	return cr.cmd.From
 }
 
+func (cr *CachingCopyCommand) Parents() bool {
+	return cr.cmd.Parents
+}
+
+func (cr *CachingCopyCommand) Link() bool {
+	return cr.cmd.Link
+}
+
+func (cr *CachingCopyCommand) LinkKey(config *v1.Config, buildArgs *dockerfile.BuildArgs) ([]string, error) {
+	return copyLinkKey(cr.cmd, config, buildArgs)
+}
+
 func resolveIfSymlink(destPath string) (string, error) {
 	if !filepath.IsAbs(destPath) {
 		return "", errors.New("dest path must be abs")
@@ -366,13 +430,18 @@ func copyCmdFilesUsedFromContext(
 	fileContext util.FileContext,
 ) ([]string, error) {
 	if cmd.From != "" {
-		fileContext = util.FileContext{Root: filepath.Join(kConfig.KanikoInterStageDepsDir, cmd.From)}
+		fileContext = fileContext.From(cmd.From)
 	}
 
 	replacementEnvs := buildArgs.ReplacementEnvs(config.Env)
 
+	fileContext, err := withExcludes(fileContext, cmd.ExcludePatterns, replacementEnvs)
+	if err != nil {
+		return nil, err
+	}
+
 	srcs, _, err := util.ResolveEnvAndWildcards(
-		cmd.SourcesAndDest, fileContext, replacementEnvs,
+		cmd.SourcesAndDest, fileContext, replacementEnvs, cmd.Parents,
 	)
 	if err != nil {
 		return nil, err
@@ -385,14 +454,54 @@ func copyCmdFilesUsedFromContext(
 	}
 
 	assert.Assert("copy.files-count", len(files) <= len(srcs), "copyCmdFilesUsedFromContext: result cannot exceed source count (srcs=%d, files=%d)", len(srcs), len(files))
+	if len(cmd.ExcludePatterns) > 0 {
+		files, err = filesNotExcluded(files, fileContext)
+		if err != nil {
+			return nil, err
+		}
+	}
 	logrus.Debugf("Using files from context: %v", files)
 
 	return files, nil
 }
 
+// withExcludes adds the --exclude patterns of a COPY or ADD to fileContext.
+func withExcludes(fileContext util.FileContext, patterns []string, replacementEnvs []string) (util.FileContext, error) {
+	resolved, err := util.ResolveEnvironmentReplacementList(patterns, replacementEnvs, false)
+	if err != nil {
+		return fileContext, err
+	}
+	return fileContext.WithExcludes(resolved)
+}
+
+// filesNotExcluded lists the files below paths that fileContext doesn't exclude.
+// Directories are hashed as a whole for the cache key, so they are walked here
+// to keep excluded files out of it.
+func filesNotExcluded(paths []string, fileContext util.FileContext) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || fileContext.ExcludesFile(p) {
+				return nil
+			}
+			files = append(files, p)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
 // AbstractCopyCommand can either be a CopyCommand or a CachingCopyCommand.
 type AbstractCopyCommand interface {
 	From() string
+	// Parents is true for COPY --parents
+	Parents() bool
 }
 
 // CastAbstractCopyCommand tries to convert a command to an AbstractCopyCommand.
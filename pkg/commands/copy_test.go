@@ -28,9 +28,12 @@ import (
 	"strings"
 	"syscall"
 	"testing"
+	"time"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	kConfig "github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/pkg/dockerfile"
 	"github.com/osscontainertools/kaniko/pkg/util"
 	"github.com/osscontainertools/kaniko/testutil"
@@ -1149,3 +1152,257 @@ func TestCopyCommand_ExecuteCommand_Extended(t *testing.T) {
 		testutil.CheckDeepEqual(t, "../bam.txt", linkName)
 	})
 }
+
+func TestCopyCommand_ExecuteCommand_Parents(t *testing.T) {
+	tests := []struct {
+		name     string
+		srcs     []string
+		expected []string
+	}{
+		{
+			name:     "wildcard",
+			srcs:     []string{"*/package.json"},
+			expected: []string{"a/package.json", "b/package.json"},
+		},
+		{
+			name:     "pivot",
+			srcs:     []string{"x/./*/package.json"},
+			expected: []string{"c/package.json"},
+		},
+		{
+			name:     "directory",
+			srcs:     []string{"x/c"},
+			expected: []string{"x/c/index.js", "x/c/package.json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDir := t.TempDir()
+			testutil.CheckNoError(t, testutil.SetupFiles(filepath.Join(testDir, "context"), map[string]string{
+				"a/package.json":   "a",
+				"a/index.js":       "a",
+				"b/package.json":   "b",
+				"x/c/package.json": "c",
+				"x/c/index.js":     "c",
+			}))
+			cmd := CopyCommand{
+				cmd: &instructions.CopyCommand{
+					SourcesAndDest: instructions.SourcesAndDest{SourcePaths: tt.srcs, DestPath: "dest/"},
+					Parents:        true,
+				},
+				fileContext: util.FileContext{Root: filepath.Join(testDir, "context")},
+			}
+			cfg := &v1.Config{WorkingDir: testDir}
+			testutil.CheckNoError(t, cmd.ExecuteCommand(cfg, dockerfile.NewBuildArgs([]string{})))
+
+			var actual []string
+			dest := filepath.Join(testDir, "dest")
+			err := filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				rel, err := filepath.Rel(dest, path)
+				actual = append(actual, rel)
+				return err
+			})
+			testutil.CheckNoError(t, err)
+			testutil.CheckDeepEqual(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestCopyCommand_Exclude(t *testing.T) {
+	setup := func(t *testing.T) string {
+		testDir := t.TempDir()
+		testutil.CheckNoError(t, testutil.SetupFiles(filepath.Join(testDir, "context"), map[string]string{
+			"app/main.go":        "main",
+			"app/README.md":      "readme",
+			"app/docs/guide.md":  "guide",
+			"app/testdata/a.txt": "a",
+		}))
+		return testDir
+	}
+	listDest := func(t *testing.T, dest string) []string {
+		var actual []string
+		err := filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dest, path)
+			actual = append(actual, rel)
+			return err
+		})
+		testutil.CheckNoError(t, err)
+		return actual
+	}
+
+	t.Run("context", func(t *testing.T) {
+		testDir := setup(t)
+		fileContext := util.FileContext{Root: filepath.Join(testDir, "context")}
+		cmd := CopyCommand{
+			cmd: &instructions.CopyCommand{
+				SourcesAndDest:  instructions.SourcesAndDest{SourcePaths: []string{"app"}, DestPath: "dest/"},
+				ExcludePatterns: []string{"**/*.md", "$TESTDATA"},
+			},
+			fileContext: fileContext,
+		}
+		cfg := &v1.Config{WorkingDir: testDir, Env: []string{"TESTDATA=app/testdata"}}
+		buildArgs := dockerfile.NewBuildArgs([]string{})
+
+		files, err := cmd.FilesUsedFromContext(cfg, buildArgs)
+		testutil.CheckNoError(t, err)
+		testutil.CheckDeepEqual(t, []string{filepath.Join(fileContext.Root, "app/main.go")}, files)
+
+		testutil.CheckNoError(t, cmd.ExecuteCommand(cfg, buildArgs))
+		testutil.CheckDeepEqual(t, []string{"main.go"}, listDest(t, filepath.Join(testDir, "dest")))
+	})
+
+	t.Run("from stage", func(t *testing.T) {
+		testDir := setup(t)
+		original := kConfig.KanikoInterStageDepsDir
+		defer func() { kConfig.KanikoInterStageDepsDir = original }()
+		kConfig.KanikoInterStageDepsDir = testDir
+		cmd := CopyCommand{
+			cmd: &instructions.CopyCommand{
+				SourcesAndDest:  instructions.SourcesAndDest{SourcePaths: []string{"app/*"}, DestPath: "dest/"},
+				From:            "context",
+				ExcludePatterns: []string{"app/*.md"},
+			},
+		}
+		cfg := &v1.Config{WorkingDir: testDir}
+		testutil.CheckNoError(t, cmd.ExecuteCommand(cfg, dockerfile.NewBuildArgs([]string{})))
+		testutil.CheckDeepEqual(t, []string{"a.txt", "guide.md", "main.go"}, listDest(t, filepath.Join(testDir, "dest")))
+	})
+}
+
+func TestCopyCommand_FromBuildContext(t *testing.T) {
+	testDir := t.TempDir()
+	testutil.CheckNoError(t, testutil.SetupFiles(filepath.Join(testDir, "shared"), map[string]string{
+		"main.go":        "main",
+		"testdata/a.txt": "a",
+	}))
+	fileContext := util.FileContext{
+		Root:     filepath.Join(testDir, "context"),
+		Contexts: map[string]string{"shared": filepath.Join(testDir, "shared")},
+	}
+	cmd := CopyCommand{
+		cmd: &instructions.CopyCommand{
+			SourcesAndDest: instructions.SourcesAndDest{SourcePaths: []string{"main.go", "testdata"}, DestPath: "dest/"},
+			From:           "shared",
+		},
+		fileContext: fileContext,
+	}
+	cfg := &v1.Config{WorkingDir: testDir}
+	buildArgs := dockerfile.NewBuildArgs([]string{})
+
+	files, err := cmd.FilesUsedFromContext(cfg, buildArgs)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, []string{
+		filepath.Join(testDir, "shared", "main.go"),
+		filepath.Join(testDir, "shared", "testdata"),
+	}, files)
+
+	testutil.CheckNoError(t, cmd.ExecuteCommand(cfg, buildArgs))
+	for _, p := range []string{"dest/main.go", "dest/a.txt"} {
+		if _, err := os.Stat(filepath.Join(testDir, p)); err != nil {
+			t.Errorf("expected %s to be copied: %v", p, err)
+		}
+	}
+}
+
+func TestCopyCommand_ExecuteCommand_Link(t *testing.T) {
+	testDir := t.TempDir()
+	root := filepath.Join(testDir, "root")
+	testutil.CheckNoError(t, testutil.SetupFiles(filepath.Join(testDir, "context"), map[string]string{
+		"app/main.go": "main",
+	}))
+	testutil.CheckNoError(t, testutil.SetupFiles(root, map[string]string{
+		"app/existing": "base",
+	}))
+
+	originalRoot, originalLink, originalLayers := kConfig.RootDir, kConfig.KanikoLinkDir, kConfig.KanikoLayersDir
+	defer func() {
+		kConfig.RootDir, kConfig.KanikoLinkDir, kConfig.KanikoLayersDir = originalRoot, originalLink, originalLayers
+	}()
+	kConfig.RootDir = root
+	kConfig.KanikoLinkDir = filepath.Join(testDir, "link") + "/"
+	kConfig.KanikoLayersDir = filepath.Join(testDir, "layers") + "/"
+
+	cmd := CopyCommand{
+		cmd: &instructions.CopyCommand{
+			SourcesAndDest: instructions.SourcesAndDest{SourcePaths: []string{"app"}, DestPath: "/app/"},
+			Link:           true,
+		},
+		fileContext: util.FileContext{Root: filepath.Join(testDir, "context")},
+	}
+	testutil.CheckNoError(t, cmd.ExecuteCommand(&v1.Config{}, dockerfile.NewBuildArgs([]string{})))
+
+	// the layer only holds the copied files
+	f, err := os.Open(cmd.LinkTarPath())
+	testutil.CheckNoError(t, err)
+	defer f.Close()
+	var names []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		testutil.CheckNoError(t, err)
+		names = append(names, hdr.Name)
+	}
+	testutil.CheckDeepEqual(t, []string{"app/", "app/main.go"}, names)
+
+	// and is extracted on top of the rootfs
+	for _, p := range []string{"app/main.go", "app/existing"} {
+		_, err := os.Stat(filepath.Join(root, p))
+		testutil.CheckNoError(t, err)
+	}
+	entries, err := os.ReadDir(kConfig.KanikoLinkDir)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, 0, len(entries))
+}
+
+func TestCopyCommand_ExecuteCommand_LinkEpoch(t *testing.T) {
+	testDir := t.TempDir()
+	contextDir := filepath.Join(testDir, "context")
+	testutil.CheckNoError(t, testutil.SetupFiles(contextDir, map[string]string{
+		"app/main.go": "main",
+	}))
+
+	originalRoot, originalLink, originalLayers := kConfig.RootDir, kConfig.KanikoLinkDir, kConfig.KanikoLayersDir
+	defer func() {
+		kConfig.RootDir, kConfig.KanikoLinkDir, kConfig.KanikoLayersDir = originalRoot, originalLink, originalLayers
+	}()
+	kConfig.KanikoLinkDir = filepath.Join(testDir, "link") + "/"
+	kConfig.KanikoLayersDir = filepath.Join(testDir, "layers") + "/"
+
+	epoch := time.Unix(0, 0).UTC()
+	build := func(mtime time.Time) v1.Hash {
+		t.Helper()
+		for _, p := range []string{"app", "app/main.go"} {
+			testutil.CheckNoError(t, os.Chtimes(filepath.Join(contextDir, p), mtime, mtime))
+		}
+		kConfig.RootDir = t.TempDir()
+		cmd := CopyCommand{
+			cmd: &instructions.CopyCommand{
+				SourcesAndDest: instructions.SourcesAndDest{SourcePaths: []string{"app"}, DestPath: "/app/"},
+				Link:           true,
+			},
+			fileContext: util.FileContext{Root: contextDir},
+			epoch:       &epoch,
+		}
+		testutil.CheckNoError(t, cmd.ExecuteCommand(&v1.Config{}, dockerfile.NewBuildArgs([]string{})))
+		layer, err := tarball.LayerFromFile(cmd.LinkTarPath())
+		testutil.CheckNoError(t, err)
+		digest, err := layer.Digest()
+		testutil.CheckNoError(t, err)
+		return digest
+	}
+
+	// the layer is pushed as built under --reproducible, so it must not
+	// depend on when its sources were touched
+	first := build(time.Now().Add(-time.Hour))
+	second := build(time.Now())
+	testutil.CheckDeepEqual(t, first, second)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	kConfig "github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/osscontainertools/kaniko/pkg/dockerfile"
+	"github.com/osscontainertools/kaniko/pkg/util"
+)
+
+// Linker is implemented by COPY and ADD, Link is true for --link.
+// Their layer only holds the copied files and is cached by its sources and
+// LinkKey alone, so it can be reused on top of any base.
+type Linker interface {
+	Link() bool
+	// LinkKey returns what decides where the copied files land and whom
+	// they belong to, which their sources do not tell.
+	LinkKey(config *v1.Config, buildArgs *dockerfile.BuildArgs) ([]string, error)
+}
+
+// LinkLayer is implemented by commands that built their layer in isolation,
+// LinkTarPath is the layer tarball or empty if none was built.
+type LinkLayer interface {
+	LinkTarPath() string
+}
+
+// linkStage is the empty root a COPY --link or ADD --link copies into,
+// the rootfs is neither consulted nor touched until the layer is complete.
+type linkStage struct {
+	root  string
+	epoch *time.Time
+}
+
+func newLinkStage(epoch *time.Time) (*linkStage, error) {
+	if err := os.MkdirAll(kConfig.KanikoLinkDir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating link staging dir: %w", err)
+	}
+	root, err := os.MkdirTemp(kConfig.KanikoLinkDir, "")
+	if err != nil {
+		return nil, fmt.Errorf("creating link staging dir: %w", err)
+	}
+	return &linkStage{root: root, epoch: epoch}, nil
+}
+
+// linkKey resolves the destination against the working directory, and
+// --chown and --chmod against the environment. user is who owns the files
+// without --chown, empty if that is root regardless of USER.
+func linkKey(sd instructions.SourcesAndDest, chown, chmod, user string, config *v1.Config, replacementEnvs []string) ([]string, error) {
+	dest, err := util.ResolveEnvironmentReplacement(sd.DestPath, replacementEnvs, true)
+	if err != nil {
+		return nil, err
+	}
+	cwd := config.WorkingDir
+	if cwd == "" {
+		cwd = kConfig.RootDir
+	}
+	dest = (&linkStage{root: "/"}).dest(dest, cwd)
+	chown, err = util.ResolveEnvironmentReplacement(chown, replacementEnvs, false)
+	if err != nil {
+		return nil, err
+	}
+	chmod, err = util.ResolveEnvironmentReplacement(chmod, replacementEnvs, false)
+	if err != nil {
+		return nil, err
+	}
+	if chown != "" {
+		user = ""
+	}
+	return []string{"dest=" + dest, "chown=" + chown, "chmod=" + chmod, "user=" + user}, nil
+}
+
+func (l *linkStage) cleanup() {
+	os.RemoveAll(l.root)
+}
+
+// dest moves dest below the staging root, relative destinations are resolved
+// against cwd first. A trailing slash is kept as it marks a directory.
+func (l *linkStage) dest(dest, cwd string) string {
+	isDir := dest == "." || strings.HasSuffix(dest, "/") || strings.HasSuffix(dest, "/.")
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(cwd, dest)
+	}
+	staged := filepath.Join(l.root, dest)
+	if isDir {
+		staged += "/"
+	}
+	return staged
+}
+
+// commit writes the staged files to a layer tarball and extracts it into the
+// rootfs, it returns the tarball and the files extracted.
+func (l *linkStage) commit() (string, []string, error) {
+	if err := os.MkdirAll(kConfig.KanikoLayersDir, 0o755); err != nil {
+		return "", nil, fmt.Errorf("creating link layer: %w", err)
+	}
+	f, err := os.CreateTemp(kConfig.KanikoLayersDir, "link-*.tar")
+	if err != nil {
+		return "", nil, fmt.Errorf("creating link layer: %w", err)
+	}
+	t := util.NewTarWithRoot(f, l.root)
+	t.SetSourceDateEpoch(l.epoch)
+	err = filepath.WalkDir(l.root, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil || path == l.root {
+			return err
+		}
+		return t.AddFileToTar(path)
+	})
+	t.Close()
+	if err := errors.Join(err, f.Close()); err != nil {
+		return "", nil, fmt.Errorf("writing link layer: %w", err)
+	}
+
+	layer, err := tarball.LayerFromFile(f.Name())
+	if err != nil {
+		return "", nil, err
+	}
+	files, err := util.GetFSFromLayers(kConfig.RootDir, []v1.Layer{layer}, util.ExtractFunc(util.ExtractFile), util.IncludeWhiteout())
+	if err != nil {
+		return "", nil, fmt.Errorf("extracting link layer: %w", err)
+	}
+	return f.Name(), files, nil
+}
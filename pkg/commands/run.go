@@ -17,10 +17,12 @@ limitations under the License.
 package commands
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -33,6 +35,7 @@ import (
 	kConfig "github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/pkg/constants"
 	"github.com/osscontainertools/kaniko/pkg/dockerfile"
+	"github.com/osscontainertools/kaniko/pkg/logging"
 	"github.com/osscontainertools/kaniko/pkg/util"
 	otiai10Cpy "github.com/otiai10/copy"
 	"github.com/sirupsen/logrus"
@@ -40,10 +43,27 @@ import (
 
 type RunCommand struct {
 	BaseCommand
+	ctx         context.Context
 	cmd         *instructions.RunCommand
 	fileContext util.FileContext
 	secrets     kConfig.SecretOptions
 	shdCache    bool
+	proc        *Process
+}
+
+// Process is how the executor hooks into the process a RUN instruction starts.
+type Process struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	// Env is added to the environment of the process, it is not part of the cache key
+	Env []string
+	// State is set once the process exited
+	State *os.ProcessState
+}
+
+// ProcessRunner is implemented by commands that run a process.
+type ProcessRunner interface {
+	SetProcess(proc *Process)
 }
 
 // for testing
@@ -55,11 +75,15 @@ func (r *RunCommand) IsArgsEnvsRequiredInCache() bool {
 	return true
 }
 
+func (r *RunCommand) SetProcess(proc *Process) {
+	r.proc = proc
+}
+
 func (r *RunCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.BuildArgs) error {
-	return runCommandWithFlags(config, buildArgs, r.cmd, r.fileContext, r.secrets)
+	return runCommandWithFlags(r.ctx, config, buildArgs, r.cmd, r.fileContext, r.secrets, r.proc)
 }
 
-func runCommandWithFlags(config *v1.Config, buildArgs *dockerfile.BuildArgs, cmdRun *instructions.RunCommand, fileContext util.FileContext, secrets kConfig.SecretOptions) (reterr error) {
+func runCommandWithFlags(ctx context.Context, config *v1.Config, buildArgs *dockerfile.BuildArgs, cmdRun *instructions.RunCommand, fileContext util.FileContext, secrets kConfig.SecretOptions, proc *Process) (reterr error) {
 	ff_bind := kConfig.FF.RunMountBind
 	for _, f := range cmdRun.FlagsUsed {
 		if f != "mount" {
@@ -164,6 +188,7 @@ func runCommandWithFlags(config *v1.Config, buildArgs *dockerfile.BuildArgs, cmd
 						return fmt.Errorf("failed to read secret file %q for %q: %w", s.Src, secretId, err)
 					}
 				}
+				logging.RegisterSecret(string(secretData))
 				defer func() {
 					// null the memory section that contained the secret
 					for i := range secretData {
@@ -277,10 +302,10 @@ func runCommandWithFlags(config *v1.Config, buildArgs *dockerfile.BuildArgs, cmd
 			}
 		}
 	}
-	return runCommandInExec(config, buildArgs, cmdRun, secretEnvs)
+	return runCommandInExec(ctx, config, buildArgs, cmdRun, secretEnvs, proc)
 }
 
-func runCommandInExec(config *v1.Config, buildArgs *dockerfile.BuildArgs, cmdRun *instructions.RunCommand, secretEnvs []string) error {
+func runCommandInExec(ctx context.Context, config *v1.Config, buildArgs *dockerfile.BuildArgs, cmdRun *instructions.RunCommand, secretEnvs []string, proc *Process) error {
 	var newCommand []string
 	if cmdRun.PrependShell {
 		// This is the default shell on Linux
@@ -342,11 +367,27 @@ func runCommandInExec(config *v1.Config, buildArgs *dockerfile.BuildArgs, cmdRun
 		newCommand = append([]string{kConfig.TiniExec, "-s", "--"}, newCommand...)
 	}
 
-	cmd := exec.Command(newCommand[0], newCommand[1:]...)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd := exec.CommandContext(ctx, newCommand[0], newCommand[1:]...)
+	// the child leads its own process group, take its grandchildren down with it
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
 
 	cmd.Dir = setWorkDirIfExists(config.WorkingDir)
+	if proc == nil {
+		proc = &Process{}
+	}
 	cmd.Stdout = os.Stdout
+	if proc.Stdout != nil {
+		cmd.Stdout = proc.Stdout
+	}
 	cmd.Stderr = os.Stderr
+	if proc.Stderr != nil {
+		cmd.Stderr = proc.Stderr
+	}
 	replacementEnvs := buildArgs.ReplacementEnvs(config.Env)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
@@ -378,7 +419,7 @@ func runCommandInExec(config *v1.Config, buildArgs *dockerfile.BuildArgs, cmdRun
 		return fmt.Errorf("adding default HOME variable: %w", err)
 	}
 
-	cmd.Env = append(env, secretEnvs...)
+	cmd.Env = append(append(env, proc.Env...), secretEnvs...)
 
 	logrus.Infof("Running: %s", cmd.Args)
 	if err := cmd.Start(); err != nil {
@@ -390,7 +431,12 @@ func runCommandInExec(config *v1.Config, buildArgs *dockerfile.BuildArgs, cmdRun
 	if err != nil {
 		return fmt.Errorf("getting group id for process: %w", err)
 	}
-	if err := cmd.Wait(); err != nil {
+	err = cmd.Wait()
+	proc.State = cmd.ProcessState
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("command cancelled: %w", ctxErr)
+		}
 		return fmt.Errorf("waiting for process to exit: %w", err)
 	}
 
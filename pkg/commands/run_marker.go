@@ -17,6 +17,7 @@ limitations under the License.
 package commands
 
 import (
+	"context"
 	"os"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -29,11 +30,17 @@ import (
 
 type RunMarkerCommand struct {
 	BaseCommand
+	ctx         context.Context
 	cmd         *instructions.RunCommand
 	Files       []string
 	fileContext util.FileContext
 	secrets     config.SecretOptions
 	shdCache    bool
+	proc        *Process
+}
+
+func (r *RunMarkerCommand) SetProcess(proc *Process) {
+	r.proc = proc
 }
 
 func (r *RunMarkerCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfile.BuildArgs) error {
@@ -43,7 +50,7 @@ func (r *RunMarkerCommand) ExecuteCommand(config *v1.Config, buildArgs *dockerfi
 	if err != nil {
 		return err
 	}
-	if err := runCommandWithFlags(config, buildArgs, r.cmd, r.fileContext, r.secrets); err != nil {
+	if err := runCommandWithFlags(r.ctx, config, buildArgs, r.cmd, r.fileContext, r.secrets, r.proc); err != nil {
 		return err
 	}
 	_, r.Files, err = util.GetFSInfoMap("/", prevFilesMap)
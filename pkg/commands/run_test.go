@@ -19,6 +19,8 @@ package commands
 import (
 	"archive/tar"
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"io/fs"
 	"log"
@@ -27,6 +29,7 @@ import (
 	"path/filepath"
 	"slices"
 	"testing"
+	"time"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
@@ -320,3 +323,45 @@ func TestSetWorkDirIfExists(t *testing.T) {
 	testutil.CheckDeepEqual(t, testDir, setWorkDirIfExists(testDir))
 	testutil.CheckDeepEqual(t, "", setWorkDirIfExists("doesnot-exists"))
 }
+
+func Test_runCommandInExec_cancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	cmdRun := &instructions.RunCommand{
+		ShellDependantCmdLine: instructions.ShellDependantCmdLine{
+			CmdLine:      []string{"sleep 10"},
+			PrependShell: true,
+		},
+	}
+	start := time.Now()
+	err := runCommandInExec(ctx, &v1.Config{}, dockerfile.NewBuildArgs(nil), cmdRun, nil, nil)
+	if err == nil {
+		t.Fatal("expected an error from a cancelled command")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command was not killed on cancellation, took %v", elapsed)
+	}
+}
+
+func Test_runCommandInExec_process(t *testing.T) {
+	var out bytes.Buffer
+	proc := &Process{
+		Stdout: &out,
+		Env:    []string{"TRACEPARENT=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+	}
+	cmdRun := &instructions.RunCommand{
+		ShellDependantCmdLine: instructions.ShellDependantCmdLine{
+			CmdLine:      []string{"echo $TRACEPARENT"},
+			PrependShell: true,
+		},
+	}
+	err := runCommandInExec(context.Background(), &v1.Config{}, dockerfile.NewBuildArgs(nil), cmdRun, nil, proc)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01\n", out.String())
+	if proc.State == nil || !proc.State.Success() {
+		t.Errorf("expected the exit state of the process to be recorded, got %v", proc.State)
+	}
+}
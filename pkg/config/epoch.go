@@ -0,0 +1,65 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceDateEpochArg names the build arg and environment variable holding
+// the reproducible-builds.org SOURCE_DATE_EPOCH.
+const SourceDateEpochArg = "SOURCE_DATE_EPOCH"
+
+type sourceDateEpochKey struct{}
+
+// WithSourceDateEpoch returns a copy of ctx that dates the build it belongs to
+// no later than epoch, nil leaves timestamps as they are.
+func WithSourceDateEpoch(ctx context.Context, epoch *time.Time) context.Context {
+	return context.WithValue(ctx, sourceDateEpochKey{}, epoch)
+}
+
+// SourceDateEpoch is the time snapshotted files and the image of the build
+// ctx belongs to are dated no later than, nil unless it sets SOURCE_DATE_EPOCH.
+func SourceDateEpoch(ctx context.Context) *time.Time {
+	epoch, _ := ctx.Value(sourceDateEpochKey{}).(*time.Time)
+	return epoch
+}
+
+// ParseSourceDateEpoch returns the time SOURCE_DATE_EPOCH is set to, by
+// buildArgs or else by the environment getenv reads. It is nil if neither
+// sets it to a non-empty value.
+func ParseSourceDateEpoch(buildArgs []string, getenv func(string) string) (*time.Time, error) {
+	value := getenv(SourceDateEpochArg)
+	for _, arg := range buildArgs {
+		if v, ok := strings.CutPrefix(arg, SourceDateEpochArg+"="); ok {
+			value = v
+		}
+	}
+	if value == "" {
+		return nil, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return nil, fmt.Errorf("invalid %s %q: must be a number of seconds since the Unix epoch", SourceDateEpochArg, value)
+	}
+	epoch := time.Unix(seconds, 0).UTC()
+	return &epoch, nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/osscontainertools/kaniko/testutil"
+)
+
+func TestParseSourceDateEpoch(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+	epoch := func(seconds int64) *time.Time {
+		t := time.Unix(seconds, 0).UTC()
+		return &t
+	}
+
+	got, err := ParseSourceDateEpoch([]string{"FOO=bar"}, getenv)
+	testutil.CheckErrorAndDeepEqual(t, false, err, (*time.Time)(nil), got)
+
+	env[SourceDateEpochArg] = "1700000000"
+	got, err = ParseSourceDateEpoch(nil, getenv)
+	testutil.CheckErrorAndDeepEqual(t, false, err, epoch(1700000000), got)
+
+	// the build arg takes precedence, the last one wins
+	got, err = ParseSourceDateEpoch([]string{"SOURCE_DATE_EPOCH=1", "SOURCE_DATE_EPOCH=42"}, getenv)
+	testutil.CheckErrorAndDeepEqual(t, false, err, epoch(42), got)
+
+	got, err = ParseSourceDateEpoch([]string{"SOURCE_DATE_EPOCH="}, getenv)
+	testutil.CheckErrorAndDeepEqual(t, false, err, (*time.Time)(nil), got)
+
+	for _, invalid := range []string{"yesterday", "-1", "1.5"} {
+		_, err = ParseSourceDateEpoch([]string{"SOURCE_DATE_EPOCH=" + invalid}, getenv)
+		testutil.CheckError(t, true, err)
+	}
+}
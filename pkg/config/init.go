@@ -71,6 +71,13 @@ var KanikoLayersDir = func() string {
 	return KanikoDir + "/layers/"
 }()
 
+// KanikoLinkDir holds the staging roots COPY --link and ADD --link copy into
+var KanikoLinkDir string
+
+// KanikoContextsDir is where named build contexts from --build-context are
+// unpacked into, one directory per name
+var KanikoContextsDir = KanikoDir + "/contexts/"
+
 // KanikoCacheDir is where we will store cache mount directories, ie.
 // RUN --mount=type=cache,target=/var/lib/apt/lists/
 // Contents are stored as-is.
@@ -95,6 +102,7 @@ var MountInfoPath string
 func init() {
 	RootDir = constants.RootDir
 	MountInfoPath = constants.MountInfoPath
+	KanikoLinkDir = KanikoDir + "/link/"
 }
 
 // Same as os.RemoveAll, but asserts that we don't delete / or /kaniko.
@@ -151,6 +159,14 @@ func Cleanup() error {
 	if err != nil {
 		return err
 	}
+	err = safeRemove(KanikoLinkDir)
+	if err != nil {
+		return err
+	}
+	err = safeRemove(KanikoContextsDir)
+	if err != nil {
+		return err
+	}
 	err = safeRemove(KanikoSwapDir)
 	if err != nil {
 		return err
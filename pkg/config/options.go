@@ -58,9 +58,11 @@ type KanikoOptions struct {
 	BuildArgs                    multiArg
 	Labels                       multiArg
 	Annotations                  keyValueArg
+	BuildContexts                keyValueArg
 	Git                          KanikoGitOptions
 	IgnorePaths                  multiArg
 	DockerfilePath               string
+	DockerfileInline             string
 	SrcContext                   string
 	SnapshotMode                 string
 	SnapshotModeDeprecated       string
@@ -75,9 +77,21 @@ type KanikoOptions struct {
 	DigestFile                   string
 	ImageNameDigestFile          string
 	ImageNameTagDigestFile       string
+	MetadataFile                 string
 	OCILayoutPath                string
+	SignKey                      string
+	VerifyBaseKey                string
+	VerifyBasePolicy             string
+	BaseImagePolicy              string
+	Lockfile                     string
+	RunLogDir                    string
+	URLCacheDir                  string
 	Compression                  Compression
 	ImageFormat                  ImageFormat
+	SBOM                         SBOMFormat
+	Provenance                   ProvenanceMode
+	LockfileMode                 LockfileMode
+	AutoMetadata                 AutoMetadataMode
 	CompressionLevel             int
 	ImageFSExtractRetry          int
 	SingleSnapshot               bool
@@ -100,6 +114,18 @@ type KanikoOptions struct {
 	Materialize                  bool
 	Secrets                      SecretOptions
 	Dryrun                       bool
+
+	// ContextSource is where the build context was fetched from, resolved
+	// along with --context for the provenance statement.
+	ContextSource ContextSource
+	// DockerfileName is the Dockerfile as the provenance statement names it,
+	// resolved along with --dockerfile before it is copied into the kaniko
+	// dir: its path within the build context, its base name when it lies
+	// elsewhere or its URL. Empty for --dockerfile-inline and stdin.
+	DockerfileName string
+	// ContextRevision is the git commit the build context was checked out
+	// at, nil unless --auto-metadata is set and the context is a checkout.
+	ContextRevision *ContextRevision
 }
 
 type KanikoGitOptions struct {
@@ -211,6 +237,146 @@ func (f *ImageFormat) Type() string {
 	return "imageformat"
 }
 
+// SBOMFormat is the document format of the SBOM attached to the image,
+// unset means no SBOM is generated
+type SBOMFormat string
+
+const (
+	SBOMFormatNone      SBOMFormat = ""
+	SBOMFormatSPDX      SBOMFormat = "spdx"
+	SBOMFormatCycloneDX SBOMFormat = "cyclonedx"
+)
+
+func (f *SBOMFormat) String() string {
+	return string(*f)
+}
+
+func (f *SBOMFormat) Set(v string) error {
+	switch v {
+	case "spdx", "cyclonedx":
+		*f = SBOMFormat(v)
+		return nil
+	default:
+		return errors.New(`must be either "spdx" or "cyclonedx"`)
+	}
+}
+
+func (f *SBOMFormat) Type() string {
+	return "sbomformat"
+}
+
+// ProvenanceMode is how much of the build the provenance statement records,
+// unset means no statement is generated
+type ProvenanceMode string
+
+const (
+	ProvenanceNone ProvenanceMode = ""
+	ProvenanceMin  ProvenanceMode = "min"
+	ProvenanceMax  ProvenanceMode = "max"
+)
+
+func (m *ProvenanceMode) String() string {
+	return string(*m)
+}
+
+func (m *ProvenanceMode) Set(v string) error {
+	switch v {
+	case "min", "max":
+		*m = ProvenanceMode(v)
+		return nil
+	default:
+		return errors.New(`must be either "min" or "max"`)
+	}
+}
+
+func (m *ProvenanceMode) Type() string {
+	return "provenancemode"
+}
+
+// LockfileMode is whether --lockfile is written from the images a build
+// resolves or enforced on them
+type LockfileMode string
+
+const (
+	LockfileModeEnforce LockfileMode = "enforce"
+	LockfileModeWrite   LockfileMode = "write"
+)
+
+func (m *LockfileMode) String() string {
+	return string(*m)
+}
+
+func (m *LockfileMode) Set(v string) error {
+	switch v {
+	case "enforce", "write":
+		*m = LockfileMode(v)
+		return nil
+	default:
+		return errors.New(`must be either "enforce" or "write"`)
+	}
+}
+
+func (m *LockfileMode) Type() string {
+	return "lockfilemode"
+}
+
+// AutoMetadataMode is where --auto-metadata records the build context and
+// base image of the pushed image, unset means nowhere
+type AutoMetadataMode string
+
+const (
+	AutoMetadataNone        AutoMetadataMode = ""
+	AutoMetadataLabels      AutoMetadataMode = "labels"
+	AutoMetadataAnnotations AutoMetadataMode = "annotations"
+	AutoMetadataAll         AutoMetadataMode = "all"
+)
+
+func (m *AutoMetadataMode) String() string {
+	return string(*m)
+}
+
+func (m *AutoMetadataMode) Set(v string) error {
+	switch v {
+	case "labels", "annotations", "all":
+		*m = AutoMetadataMode(v)
+		return nil
+	default:
+		return errors.New(`must be either "labels", "annotations" or "all"`)
+	}
+}
+
+func (m *AutoMetadataMode) Type() string {
+	return "autometadatamode"
+}
+
+// Labels reports whether the metadata is recorded as image labels.
+func (m AutoMetadataMode) Labels() bool {
+	return m == AutoMetadataLabels || m == AutoMetadataAll
+}
+
+// Annotations reports whether the metadata is recorded as manifest
+// annotations.
+func (m AutoMetadataMode) Annotations() bool {
+	return m == AutoMetadataAnnotations || m == AutoMetadataAll
+}
+
+// ContextSource identifies the revision of a remote build context, in the
+// shape of an in-toto ResourceDescriptor.
+type ContextSource struct {
+	URI    string
+	Digest map[string]string
+}
+
+// ContextRevision is the git checkout a build context was taken from.
+type ContextRevision struct {
+	// Source is the URL of the repository, empty if it has no origin remote
+	Source string
+	// Revision is the SHA of the checked out commit
+	Revision string
+	// Version is the tag pointing at the commit, if any
+	Version string
+}
+
 // WarmerOptions are options that are set by command line arguments to the cache warmer.
 type WarmerOptions struct {
 	CacheOptions
@@ -22,8 +22,10 @@ import (
 
 // KanikoStage wraps a stage of the Dockerfile and provides extra information
 type KanikoStage struct {
-	Name                   string
-	BaseName               string
+	Name     string
+	BaseName string
+	// Platform is the resolved FROM --platform, empty for the --custom-platform
+	Platform               string
 	Commands               []instructions.Command
 	BaseImageIndex         int
 	BaseImageDigest        string
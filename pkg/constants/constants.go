@@ -41,6 +41,10 @@ const (
 	GitBuildContextPrefix      = "git://"
 	HTTPSBuildContextPrefix    = "https://"
 
+	// Named build contexts may also refer to an image
+	DockerImageContextPrefix = "docker-image://"
+	OCILayoutContextPrefix   = "oci-layout://"
+
 	HOME = "HOME"
 	// DefaultHOMEValue is the default value Docker sets for $HOME
 	DefaultHOMEValue = "/root"
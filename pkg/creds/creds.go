@@ -93,5 +93,5 @@ func GetKeychain(opts *config.RegistryOptions) authn.Keychain {
 	}
 
 	logrus.Infof("credential providers by priority: [%s]", strings.Join(prios, ", "))
-	return authn.NewMultiKeychain(keychains...)
+	return redactingKeychain{authn.NewMultiKeychain(keychains...)}
 }
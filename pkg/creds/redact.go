@@ -0,0 +1,75 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creds
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/osscontainertools/kaniko/pkg/logging"
+)
+
+// redactingKeychain registers every credential it resolves as a secret,
+// so registry passwords and tokens are masked in the build output.
+type redactingKeychain struct {
+	authn.Keychain
+}
+
+func (k redactingKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	return k.ResolveContext(context.Background(), target)
+}
+
+func (k redactingKeychain) ResolveContext(ctx context.Context, target authn.Resource) (authn.Authenticator, error) {
+	auth, err := authn.Resolve(ctx, k.Keychain, target)
+	if err != nil {
+		return nil, err
+	}
+	// transports compare against Anonymous to skip authentication
+	if auth == authn.Anonymous {
+		return auth, nil
+	}
+	return redactingAuthenticator{auth}, nil
+}
+
+type redactingAuthenticator struct {
+	authn.Authenticator
+}
+
+func (a redactingAuthenticator) Authorization() (*authn.AuthConfig, error) {
+	return a.AuthorizationContext(context.Background())
+}
+
+func (a redactingAuthenticator) AuthorizationContext(ctx context.Context) (*authn.AuthConfig, error) {
+	cfg, err := authn.Authorization(ctx, a.Authenticator)
+	if err != nil {
+		return nil, err
+	}
+	registerAuthConfig(cfg)
+	return cfg, nil
+}
+
+func registerAuthConfig(cfg *authn.AuthConfig) {
+	for _, v := range []string{cfg.Password, cfg.Auth, cfg.IdentityToken, cfg.RegistryToken} {
+		if v != "" {
+			logging.RegisterSecret(v)
+		}
+	}
+	if cfg.Password != "" {
+		logging.RegisterSecret(base64.StdEncoding.EncodeToString([]byte(cfg.Username + ":" + cfg.Password)))
+	}
+}
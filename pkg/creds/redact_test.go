@@ -0,0 +1,69 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package creds
+
+import (
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/osscontainertools/kaniko/pkg/logging"
+)
+
+type staticKeychain struct {
+	auth authn.Authenticator
+}
+
+func (k staticKeychain) Resolve(authn.Resource) (authn.Authenticator, error) {
+	return k.auth, nil
+}
+
+func TestRedactingKeychain(t *testing.T) {
+	t.Cleanup(logging.ResetSecrets)
+	repo, err := name.NewRepository("registry.example.com/image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kc := redactingKeychain{staticKeychain{authn.FromConfig(authn.AuthConfig{Username: "user", Password: "registry-pw"})}}
+	auth, err := kc.Resolve(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := logging.Redact("registry-pw"); got != "registry-pw" {
+		t.Errorf("password masked before it was resolved: %q", got)
+	}
+	if _, err := auth.Authorization(); err != nil {
+		t.Fatal(err)
+	}
+	if got := logging.Redact("pw is registry-pw"); got != "pw is ***" {
+		t.Errorf("password not masked: %q", got)
+	}
+	// base64 of user:registry-pw as sent in a basic auth header
+	if got := logging.Redact("Basic dXNlcjpyZWdpc3RyeS1wdw=="); got != "Basic ***" {
+		t.Errorf("basic auth not masked: %q", got)
+	}
+
+	kc = redactingKeychain{staticKeychain{authn.Anonymous}}
+	auth, err = kc.Resolve(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth != authn.Anonymous {
+		t.Errorf("anonymous must be passed through, got %T", auth)
+	}
+}
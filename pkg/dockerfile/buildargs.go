@@ -19,6 +19,7 @@ package dockerfile
 import (
 	"fmt"
 	"maps"
+	"slices"
 	"strings"
 
 	"github.com/containerd/platforms"
@@ -242,3 +243,21 @@ func (b *BuildArgs) InitPredefinedArgs(customPlatform string, lastStage string)
 	}
 	return nil
 }
+
+// PlatformArgs returns the predefined platform args as key=value pairs. They
+// are in scope for FROM, before any stage declares them.
+func PlatformArgs(customPlatform string) ([]string, error) {
+	b := NewBuildArgs(nil)
+	if err := b.InitPredefinedArgs(customPlatform, ""); err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, len(b.predefinedArgs))
+	for key, val := range b.predefinedArgs {
+		if key == "TARGETSTAGE" {
+			continue
+		}
+		args = append(args, fmt.Sprintf("%s=%s", key, *val))
+	}
+	slices.Sort(args)
+	return args, nil
+}
@@ -18,6 +18,7 @@ package dockerfile
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -28,6 +29,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/containerd/platforms"
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 	"github.com/moby/buildkit/frontend/dockerfile/linter"
 	"github.com/moby/buildkit/frontend/dockerfile/parser"
@@ -41,27 +43,42 @@ import (
 var GetRemoteOnBuild = getRemoteOnBuild
 
 func ParseStages(opts *config.KanikoOptions) ([]instructions.Stage, []instructions.ArgCommand, error) {
+	d, err := Read(opts.DockerfilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ParseStagesFrom(d, opts)
+}
+
+// Read returns the content of the Dockerfile at path, which may be an http(s)
+// URL.
+func Read(path string) ([]byte, error) {
 	var err error
 	var d []uint8
-	match, _ := regexp.MatchString("^https?://", opts.DockerfilePath)
+	match, _ := regexp.MatchString("^https?://", path)
 	if match {
-		resp, e := http.Get(opts.DockerfilePath) //nolint:noctx
+		resp, e := http.Get(path) //nolint:noctx
 		if e != nil {
-			return nil, nil, e
+			return nil, e
 		}
 		defer resp.Body.Close()
 		if resp.StatusCode >= 400 {
-			return nil, nil, fmt.Errorf("request failed: %s", resp.Status)
+			return nil, fmt.Errorf("request failed: %s", resp.Status)
 		}
 		d, err = io.ReadAll(resp.Body)
 	} else {
-		d, err = os.ReadFile(opts.DockerfilePath)
+		d, err = os.ReadFile(path)
 	}
 
 	if err != nil {
-		return nil, nil, fmt.Errorf("reading dockerfile at path %s: %w", opts.DockerfilePath, err)
+		return nil, fmt.Errorf("reading dockerfile at path %s: %w", path, err)
 	}
+	return d, nil
+}
 
+// ParseStagesFrom parses the Dockerfile content d into its stages and meta
+// ARGs, the latter expanded against opts.BuildArgs.
+func ParseStagesFrom(d []byte, opts *config.KanikoOptions) ([]instructions.Stage, []instructions.ArgCommand, error) {
 	stages, metaArgs, err := Parse(d)
 	if err != nil {
 		return nil, nil, fmt.Errorf("parsing dockerfile: %w", err)
@@ -261,10 +278,14 @@ func ParseCommands(cmdArray []string) ([]instructions.Command, error) {
 // ResolveCrossStageCommands resolves any calls to previous stages with names to indices
 // Ex. --from=secondStage should be --from=1 for easier processing later on
 // As third party library lowers stage name in FROM instruction, this function resolves stage case insensitively.
-func resolveCrossStageCommands(cmds []instructions.Command, stageNameToIdx map[string]int, nStages int) error {
+func resolveCrossStageCommands(cmds []instructions.Command, stageNameToIdx map[string]int, contexts map[string]string, nStages int) error {
 	for _, cmd := range cmds {
 		switch c := cmd.(type) {
 		case *instructions.CopyCommand:
+			// a named build context replaces the stage it is named after
+			if _, ok := contexts[c.From]; ok {
+				continue
+			}
 			if c.From != "" {
 				if val, ok := stageNameToIdx[strings.ToLower(c.From)]; ok {
 					c.From = strconv.Itoa(val)
@@ -280,6 +301,34 @@ func resolveCrossStageCommands(cmds []instructions.Command, stageNameToIdx map[s
 	return nil
 }
 
+// resolvePlatforms resolves the FROM --platform of each stage. A stage without
+// one is built for the platform of the stage it is based on, or is left empty
+// for the --custom-platform.
+func resolvePlatforms(stages []instructions.Stage, args []string, contexts map[string]string) ([]string, error) {
+	resolved := make([]string, len(stages))
+	for i, s := range stages {
+		if s.Platform == "" {
+			if _, ok := contexts[s.BaseName]; ok {
+				continue
+			}
+			if idx := baseImageIndex(i, stages); idx != -1 {
+				resolved[i] = resolved[idx]
+			}
+			continue
+		}
+		platform, err := util.ResolveEnvironmentReplacement(s.Platform, args, false)
+		if err != nil {
+			return nil, fmt.Errorf("resolving platform %s: %w", s.Platform, err)
+		}
+		spec, err := platforms.Parse(platform)
+		if err != nil {
+			return nil, fmt.Errorf("stage %d: invalid platform %q: %w", i, platform, err)
+		}
+		resolved[i] = platforms.Format(platforms.Normalize(spec))
+	}
+	return resolved, nil
+}
+
 // resolveStagesArgs resolves all the args from list of stages
 func resolveStagesArgs(stages []instructions.Stage, args []string) error {
 	for i, s := range stages {
@@ -294,7 +343,7 @@ func resolveStagesArgs(stages []instructions.Stage, args []string) error {
 	return nil
 }
 
-func MakeKanikoStages(opts *config.KanikoOptions, stages []instructions.Stage, metaArgs []instructions.ArgCommand) ([]config.KanikoStage, error) {
+func MakeKanikoStages(ctx context.Context, opts *config.KanikoOptions, stages []instructions.Stage, metaArgs []instructions.ArgCommand) ([]config.KanikoStage, error) {
 	if len(stages) == 0 {
 		return nil, errors.New("dockerfile must contain at least one FROM instruction")
 	}
@@ -307,11 +356,19 @@ func MakeKanikoStages(opts *config.KanikoOptions, stages []instructions.Stage, m
 	targetStages = slices.Compact(targetStages)
 	finalStage := targetStages[len(targetStages)-1]
 
-	args := unifyArgs(metaArgs, opts.BuildArgs)
+	args, err := PlatformArgs(opts.CustomPlatform)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, unifyArgs(metaArgs, opts.BuildArgs)...)
 	if err := resolveStagesArgs(stages, args); err != nil {
 		return nil, fmt.Errorf("resolving args: %w", err)
 	}
 	stages = stages[:finalStage+1]
+	stagePlatforms, err := resolvePlatforms(stages, args, opts.BuildContexts)
+	if err != nil {
+		return nil, err
+	}
 
 	stageByName := make(map[string]int)
 	for idx, s := range stages {
@@ -319,6 +376,10 @@ func MakeKanikoStages(opts *config.KanikoOptions, stages []instructions.Stage, m
 			stageByName[s.Name] = idx
 		}
 	}
+	policy, err := LoadBaseImagePolicy(opts.BaseImagePolicy)
+	if err != nil {
+		return nil, fmt.Errorf("loading --base-image-policy: %w", err)
+	}
 
 	kanikoStages := make([]config.KanikoStage, len(stages))
 	// We now "count" references, it is only safe to squash
@@ -342,8 +403,15 @@ func MakeKanikoStages(opts *config.KanikoOptions, stages []instructions.Stage, m
 			logrus.Infof("Resolved base name of %s to %s", stage.Name, stage.BaseName)
 		}
 		baseImageIndex := baseImageIndex(i, stages)
+		if _, ok := opts.BuildContexts[stage.BaseName]; ok {
+			baseImageIndex = -1
+		}
 		baseImageStoredLocally := baseImageIndex != -1
 
+		if err := checkStagePolicy(policy, stage, baseImageStoredLocally, stageByName, opts.BuildContexts); err != nil {
+			return nil, err
+		}
+
 		var onBuild []string
 		var baseImageDigest string
 		if stage.BaseName == constants.NoBaseImage {
@@ -351,17 +419,26 @@ func MakeKanikoStages(opts *config.KanikoOptions, stages []instructions.Stage, m
 		} else if baseImageStoredLocally {
 			onBuild = getOnBuild(stages[baseImageIndex].Commands)
 		} else {
-			onBuild, baseImageDigest, err = GetRemoteOnBuild(stage.BaseName, metaArgs, opts)
+			onBuild, baseImageDigest, err = GetRemoteOnBuild(ctx, stage.BaseName, stagePlatforms[i], metaArgs, opts)
 			if err != nil {
 				return nil, err
 			}
+			if policy != nil {
+				image, err := image_util.RetrieveSourceImageInternal(ctx, stage.BaseName, stagePlatforms[i], false, -1, metaArgs, opts)
+				if err != nil {
+					return nil, err
+				}
+				if err := policy.CheckCreated("FROM", stage.Location, stage.BaseName, opts.BuildContexts, image); err != nil {
+					return nil, err
+				}
+			}
 		}
 		cmds, err := ParseCommands(onBuild)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse ONBUILD instructions: %w", err)
 		}
 		stage.Commands = append(cmds, stage.Commands...)
-		err = resolveCrossStageCommands(stage.Commands, stageByName, len(stages))
+		err = resolveCrossStageCommands(stage.Commands, stageByName, opts.BuildContexts, len(stages))
 		if err != nil {
 			return nil, fmt.Errorf("stage %d: %w", i, err)
 		}
@@ -379,6 +456,7 @@ func MakeKanikoStages(opts *config.KanikoOptions, stages []instructions.Stage, m
 		kanikoStages[i] = config.KanikoStage{
 			Name:                   stage.Name,
 			BaseName:               stage.BaseName,
+			Platform:               stagePlatforms[i],
 			Commands:               stage.Commands,
 			BaseImageIndex:         baseImageIndex,
 			BaseImageDigest:        baseImageDigest,
@@ -392,7 +470,7 @@ func MakeKanikoStages(opts *config.KanikoOptions, stages []instructions.Stage, m
 	}
 	for i, s := range kanikoStages {
 		if buildTargets[i] || stagesDependencies[i] > 0 || copyDependencies[i] > 0 {
-			if s.BaseImageStoredLocally && stagesDependencies[s.BaseImageIndex] == 1 && copyDependencies[s.BaseImageIndex] == 0 {
+			if s.BaseImageStoredLocally && stagesDependencies[s.BaseImageIndex] == 1 && copyDependencies[s.BaseImageIndex] == 0 && kanikoStages[s.BaseImageIndex].Platform == s.Platform {
 				sb := kanikoStages[s.BaseImageIndex]
 				// squash stages[i] into stages[i].BaseName
 				logrus.Infof("Squashing stages: %s into %s", s.Name, sb.Name)
@@ -414,6 +492,35 @@ func MakeKanikoStages(opts *config.KanikoOptions, stages []instructions.Stage, m
 	return onlyUsedStages, nil
 }
 
+// checkStagePolicy checks the images the FROM and COPY --from instructions of
+// stage name against the --base-image-policy.
+func checkStagePolicy(policy *BaseImagePolicy, stage instructions.Stage, baseImageStoredLocally bool, stageByName map[string]int, contexts map[string]string) error {
+	if policy == nil {
+		return nil
+	}
+	if !baseImageStoredLocally && stage.BaseName != constants.NoBaseImage {
+		if err := policy.CheckSource("FROM", stage.Location, stage.BaseName, contexts); err != nil {
+			return err
+		}
+	}
+	for _, c := range stage.Commands {
+		cmd, ok := c.(*instructions.CopyCommand)
+		if !ok || cmd.From == "" {
+			continue
+		}
+		if _, ok := stageByName[strings.ToLower(cmd.From)]; ok {
+			continue
+		}
+		if _, err := strconv.Atoi(cmd.From); err == nil {
+			continue
+		}
+		if err := policy.CheckSource("COPY --from", cmd.Location(), cmd.From, contexts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // unifyArgs returns the unified args between metaArgs and --build-arg
 // by default --build-arg overrides metaArgs except when --build-arg is empty
 func unifyArgs(metaArgs []instructions.ArgCommand, buildArgs []string) []string {
@@ -450,8 +557,8 @@ func getOnBuild(cmds []instructions.Command) []string {
 	return out
 }
 
-func getRemoteOnBuild(baseName string, metaArgs []instructions.ArgCommand, opts *config.KanikoOptions) ([]string, string, error) {
-	image, err := image_util.RetrieveSourceImageInternal(baseName, false, -1, metaArgs, opts)
+func getRemoteOnBuild(ctx context.Context, baseName, platform string, metaArgs []instructions.ArgCommand, opts *config.KanikoOptions) ([]string, string, error) {
+	image, err := image_util.RetrieveSourceImageInternal(ctx, baseName, platform, false, -1, metaArgs, opts)
 	if err != nil {
 		return nil, "", err
 	}
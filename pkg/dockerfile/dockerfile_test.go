@@ -21,6 +21,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/containerd/platforms"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 	"github.com/osscontainertools/kaniko/pkg/config"
@@ -196,6 +197,7 @@ func Test_GetOnBuildInstructions(t *testing.T) {
 		name        string
 		cfg         *v1.Config
 		stageToIdx  map[string]int
+		contexts    map[string]string
 		expCommands []instructions.Command
 	}
 
@@ -227,6 +229,22 @@ func Test_GetOnBuildInstructions(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:       "onBuild on config, named build context replaces stage",
+			cfg:        &v1.Config{OnBuild: []string{"COPY --from=builder a.txt b.txt", "COPY --from=configs /etc /etc"}},
+			stageToIdx: map[string]int{"builder": 0},
+			contexts:   map[string]string{"builder": "/workspace/builder", "configs": "docker-image://configs:latest"},
+			expCommands: []instructions.Command{
+				&instructions.CopyCommand{
+					SourcesAndDest: instructions.SourcesAndDest{SourcePaths: []string{"a.txt"}, DestPath: "b.txt"},
+					From:           "builder",
+				},
+				&instructions.CopyCommand{
+					SourcesAndDest: instructions.SourcesAndDest{SourcePaths: []string{"/etc"}, DestPath: "/etc"},
+					From:           "configs",
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -238,7 +256,7 @@ func Test_GetOnBuildInstructions(t *testing.T) {
 			if len(cmds) != len(test.expCommands) {
 				t.Fatalf("Expected %d commands, got %d", len(test.expCommands), len(cmds))
 			}
-			err = resolveCrossStageCommands(cmds, test.stageToIdx, len(test.stageToIdx))
+			err = resolveCrossStageCommands(cmds, test.stageToIdx, test.contexts, len(test.stageToIdx))
 			if err != nil {
 				t.Fatalf("resolveCrossStageCommands: %v", err)
 			}
@@ -433,3 +451,34 @@ func Test_ResolveStagesArgs(t *testing.T) {
 		}
 	}
 }
+
+func Test_resolvePlatforms(t *testing.T) {
+	dockerfile := `
+	ARG GOARCH=arm64
+	FROM --platform=$BUILDPLATFORM golang AS build
+	FROM build AS test
+	FROM --platform=linux/${GOARCH} alpine AS cross
+	FROM alpine
+	`
+	stages, metaArgs, err := Parse([]byte(dockerfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := PlatformArgs("linux/s390x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args = append(args, unifyArgs(metaArgs, nil)...)
+
+	actual, err := resolvePlatforms(stages, args, nil)
+	build := platforms.Format(platforms.Normalize(platforms.DefaultSpec()))
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{build, build, "linux/arm64", ""}, actual)
+
+	stages[3].Platform = "$TARGETPLATFORM"
+	actual, err = resolvePlatforms(stages, args, nil)
+	testutil.CheckErrorAndDeepEqual(t, false, err, "linux/s390x", actual[3])
+
+	stages[3].Platform = "not a platform"
+	_, err = resolvePlatforms(stages, args, nil)
+	testutil.CheckError(t, true, err)
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/osscontainertools/kaniko/pkg/constants"
+	"github.com/osscontainertools/kaniko/pkg/util"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+)
+
+// BaseImagePolicy restricts the images FROM and COPY --from may name, read
+// from --base-image-policy. A nil policy allows everything.
+type BaseImagePolicy struct {
+	allow         []string
+	requireDigest bool
+	maxAge        time.Duration
+}
+
+// LoadBaseImagePolicy reads the policy at path, nil if path is empty. The
+// file is a YAML document, or JSON, which is YAML as well:
+//
+//	allow:
+//	  - docker.io/library
+//	  - gcr.io/my-project
+//	requireDigest: true
+//	maxAge: 90d
+//
+// allow lists registries and repository prefixes, all are allowed if it is
+// empty. maxAge is a Go duration or a number of days.
+func LoadBaseImagePolicy(path string) (*BaseImagePolicy, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		Allow         []string `json:"allow"`
+		RequireDigest bool     `json:"requireDigest"`
+		MaxAge        string   `json:"maxAge"`
+	}
+	if err := yaml.UnmarshalStrict(b, &raw); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	p := &BaseImagePolicy{requireDigest: raw.RequireDigest}
+	for _, scope := range raw.Allow {
+		normalized, err := util.NormalizeRepositoryScope(scope)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		p.allow = append(p.allow, normalized)
+	}
+	if raw.MaxAge != "" {
+		p.maxAge, err = parseAge(raw.MaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid maxAge %q: %w", path, raw.MaxAge, err)
+		}
+	}
+	return p, nil
+}
+
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, errors.New("must be a positive number of days")
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("must be positive")
+	}
+	return d, nil
+}
+
+// CheckSource checks the image source, named by the instruction at
+// location, against the allowed repositories and digest pinning. source is
+// an image reference or the name of a build context, which is checked if it
+// is an image. It needs nothing from the registry, so a violation fails the
+// build before anything is pulled.
+func (p *BaseImagePolicy) CheckSource(instruction string, location []parser.Range, source string, contexts map[string]string) error {
+	image, ok := registryImage(source, contexts)
+	if p == nil || !ok {
+		return nil
+	}
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return policyError(instruction, location, image, err)
+	}
+	if len(p.allow) > 0 && !p.allows(ref.Context()) {
+		return policyError(instruction, location, image, fmt.Errorf("%s is not an allowed repository", ref.Context()))
+	}
+	if _, pinned := ref.(name.Digest); p.requireDigest && !pinned {
+		return policyError(instruction, location, image, errors.New("not pinned to a digest"))
+	}
+	return nil
+}
+
+// registryImage returns the registry image source names, following a build
+// context to the image it stands for.
+func registryImage(source string, contexts map[string]string) (string, bool) {
+	target, ok := contexts[source]
+	if !ok {
+		return source, true
+	}
+	return strings.CutPrefix(target, constants.DockerImageContextPrefix)
+}
+
+func (p *BaseImagePolicy) allows(repo name.Repository) bool {
+	for _, scope := range p.allow {
+		if util.InRepositoryScope(repo, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCreated checks the creation time of img, retrieved for source,
+// against the maximum age. An image dated zero or the Unix epoch is let
+// through with a warning as its age is unknown.
+func (p *BaseImagePolicy) CheckCreated(instruction string, location []parser.Range, source string, contexts map[string]string, img v1.Image) error {
+	image, ok := registryImage(source, contexts)
+	if p == nil || p.maxAge == 0 || !ok {
+		return nil
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return err
+	}
+	created := cfg.Created.Time
+	// reproducible builds zero the date, it tells nothing about the image's age
+	if created.IsZero() || created.Unix() == 0 {
+		logrus.Warnf("%s %s has no creation time, not checking its age against --base-image-policy", instruction, image)
+		return nil
+	}
+	if time.Since(created) > p.maxAge {
+		return policyError(instruction, location, image, fmt.Errorf("created %s, more than %s ago", created.Format(time.DateOnly), p.maxAge))
+	}
+	return nil
+}
+
+func policyError(instruction string, location []parser.Range, image string, err error) error {
+	if len(location) == 0 {
+		return fmt.Errorf("%s %s violates --base-image-policy: %w", instruction, image, err)
+	}
+	return fmt.Errorf("line %d: %s %s violates --base-image-policy: %w", location[0].Start.Line, instruction, image, err)
+}
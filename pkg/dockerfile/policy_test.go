@@ -0,0 +1,151 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/osscontainertools/kaniko/testutil"
+)
+
+func writePolicy(t *testing.T, policy string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	testutil.CheckNoError(t, os.WriteFile(path, []byte(policy), 0o644))
+	return path
+}
+
+func Test_LoadBaseImagePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		maxAge  time.Duration
+		wantErr bool
+	}{
+		{name: "days", policy: `{"maxAge": "30d"}`, maxAge: 30 * 24 * time.Hour},
+		{name: "duration", policy: `{"maxAge": "36h"}`, maxAge: 36 * time.Hour},
+		{name: "invalid age", policy: `{"maxAge": "-1d"}`, wantErr: true},
+		{name: "yaml", policy: "allow:\n  - gcr.io/project\nrequireDigest: true\nmaxAge: 90d\n", maxAge: 90 * 24 * time.Hour},
+		{name: "unknown field", policy: `{"allowed": ["gcr.io"]}`, wantErr: true},
+		{name: "unknown yaml field", policy: "allowed:\n  - gcr.io\n", wantErr: true},
+		{name: "invalid yaml", policy: "allow: [gcr.io\n", wantErr: true},
+		{name: "invalid registry", policy: `{"allow": ["in valid/foo"]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := LoadBaseImagePolicy(writePolicy(t, tt.policy))
+			testutil.CheckError(t, tt.wantErr, err)
+			if err == nil {
+				testutil.CheckDeepEqual(t, tt.maxAge, p.maxAge)
+			}
+		})
+	}
+
+	p, err := LoadBaseImagePolicy("")
+	testutil.CheckNoError(t, err)
+	if p != nil {
+		t.Error("expected no policy without a path")
+	}
+}
+
+func Test_BaseImagePolicy_CheckSource(t *testing.T) {
+	p, err := LoadBaseImagePolicy(writePolicy(t, `{"allow": ["docker.io/library", "gcr.io/project"], "requireDigest": true}`))
+	testutil.CheckNoError(t, err)
+	digest := "@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	contexts := map[string]string{
+		"base":  "docker-image://quay.io/foo/bar" + digest,
+		"local": "oci-layout:///layouts/base",
+		"src":   "/src",
+	}
+	tests := []struct {
+		source  string
+		wantErr bool
+	}{
+		{source: "debian" + digest},
+		{source: "debian:12" + digest},
+		{source: "gcr.io/project/app" + digest},
+		{source: "debian:12", wantErr: true},
+		{source: "gcr.io/projectx/app" + digest, wantErr: true},
+		{source: "bitnami/redis" + digest, wantErr: true},
+		{source: "base", wantErr: true},
+		{source: "local"},
+		{source: "src"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			err := p.CheckSource("FROM", nil, tt.source, contexts)
+			testutil.CheckError(t, tt.wantErr, err)
+		})
+	}
+
+	var none *BaseImagePolicy
+	testutil.CheckNoError(t, none.CheckSource("FROM", nil, "debian:12", nil))
+}
+
+func Test_BaseImagePolicy_CheckCreated(t *testing.T) {
+	p, err := LoadBaseImagePolicy(writePolicy(t, `{"maxAge": "30d"}`))
+	testutil.CheckNoError(t, err)
+	img, err := random.Image(64, 1)
+	testutil.CheckNoError(t, err)
+
+	recent, err := mutate.CreatedAt(img, v1.Time{Time: time.Now().Add(-24 * time.Hour)})
+	testutil.CheckNoError(t, err)
+	testutil.CheckNoError(t, p.CheckCreated("FROM", nil, "debian:12", nil, recent))
+
+	old, err := mutate.CreatedAt(img, v1.Time{Time: time.Now().Add(-60 * 24 * time.Hour)})
+	testutil.CheckNoError(t, err)
+	testutil.CheckError(t, true, p.CheckCreated("FROM", nil, "debian:12", nil, old))
+
+	// distroless, ko and --reproducible images have no creation time
+	for _, created := range []time.Time{{}, time.Unix(0, 0)} {
+		undated, err := mutate.CreatedAt(img, v1.Time{Time: created})
+		testutil.CheckNoError(t, err)
+		testutil.CheckNoError(t, p.CheckCreated("FROM", nil, "gcr.io/distroless/static", nil, undated))
+	}
+}
+
+func Test_MakeKanikoStages_BaseImagePolicy(t *testing.T) {
+	original := GetRemoteOnBuild
+	defer func() { GetRemoteOnBuild = original }()
+	GetRemoteOnBuild = func(context.Context, string, string, []instructions.ArgCommand, *config.KanikoOptions) ([]string, string, error) {
+		t.Fatal("base image retrieved despite the policy violation")
+		return nil, "", nil
+	}
+
+	stages, metaArgs, err := Parse([]byte(`FROM scratch AS build
+COPY a /b
+
+FROM debian:12
+COPY --from=build /b /c
+`))
+	testutil.CheckNoError(t, err)
+	opts := &config.KanikoOptions{BaseImagePolicy: writePolicy(t, `{"requireDigest": true}`)}
+	_, err = MakeKanikoStages(context.Background(), opts, stages, metaArgs)
+	want := "line 4: FROM debian:12 violates --base-image-policy: not pinned to a digest"
+	if err == nil || err.Error() != want {
+		t.Errorf("expected error %q, got %v", want, err)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	image_util "github.com/osscontainertools/kaniko/pkg/image"
+)
+
+// Attach pushes content as an OCI artifact of artifactType whose subject is
+// the manifest subject names, image or index, to the repository of subject.
+// It returns the reference of the artifact.
+func Attach(ctx context.Context, subject, artifactType string, content []byte, annotations map[string]string, opts *config.KanikoOptions) (name.Digest, error) {
+	ref, auth, rt, err := subjectAccess(subject, opts)
+	if err != nil {
+		return name.Digest{}, err
+	}
+	desc, err := remote.Get(ref, remote.WithAuth(auth), remote.WithTransport(rt), remote.WithContext(ctx))
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("retrieving subject %s: %w", subject, err)
+	}
+	referrer, err := image_util.NewReferrerTo(desc.Descriptor, artifactType, content, annotations)
+	if err != nil {
+		return name.Digest{}, err
+	}
+	if err := pushReferrer(ctx, ref.Context(), referrer, opts, auth, rt); err != nil {
+		return name.Digest{}, err
+	}
+	dig, err := referrer.Digest()
+	if err != nil {
+		return name.Digest{}, err
+	}
+	return ref.Context().Digest(dig.String()), nil
+}
+
+// ListReferrers returns the artifacts attached to the manifest subject names,
+// only those of artifactType unless it is empty. Registries without the
+// referrers API are read through the fallback tag.
+func ListReferrers(ctx context.Context, subject, artifactType string, opts *config.KanikoOptions) ([]v1.Descriptor, error) {
+	ref, auth, rt, err := subjectAccess(subject, opts)
+	if err != nil {
+		return nil, err
+	}
+	options := []remote.Option{remote.WithAuth(auth), remote.WithTransport(rt), remote.WithContext(ctx)}
+	digest, ok := ref.(name.Digest)
+	if !ok {
+		desc, err := remote.Head(ref, options...)
+		if err != nil {
+			return nil, fmt.Errorf("retrieving subject %s: %w", subject, err)
+		}
+		digest = ref.Context().Digest(desc.Digest.String())
+	}
+	if artifactType != "" {
+		options = append(options, remote.WithFilter("artifactType", artifactType))
+	}
+	idx, err := remote.Referrers(digest, options...)
+	if err != nil {
+		return nil, fmt.Errorf("listing referrers of %s: %w", digest, err)
+	}
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	return m.Manifests, nil
+}
+
+// subjectAccess parses subject and returns it with the credentials and
+// transport to push next to it.
+func subjectAccess(subject string, opts *config.KanikoOptions) (name.Reference, authn.Authenticator, http.RoundTripper, error) {
+	ref, err := name.ParseReference(subject, name.WeakValidation)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parsing subject %s: %w", subject, err)
+	}
+	registry, err := pushRegistry(ref.Context().Registry, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	switch r := ref.(type) {
+	case name.Tag:
+		r.Registry = registry
+		ref = r
+	case name.Digest:
+		r.Registry = registry
+		ref = r
+	}
+	auth, rt, err := pushAccess(ref.Context(), opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return ref, auth, rt, nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/osscontainertools/kaniko/testutil"
+)
+
+// fakeRegistry is an in-memory registry without the referrers API, just
+// enough of the distribution spec for go-containerregistry to push and pull.
+type fakeRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	uploads   map[string][]byte
+	manifests map[string][]byte
+	types     map[string]string
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{blobs: map[string][]byte{}, uploads: map[string][]byte{}, manifests: map[string][]byte{}, types: map[string]string{}}
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	body, _ := io.ReadAll(r.Body)
+	if repo, id, ok := strings.Cut(path, "/blobs/uploads/"); ok {
+		switch r.Method {
+		case http.MethodPost:
+			id := strconv.Itoa(len(f.uploads))
+			f.uploads[id] = nil
+			w.Header().Set("Location", "/v2/"+repo+"/blobs/uploads/"+id)
+			w.WriteHeader(http.StatusAccepted)
+		case http.MethodPatch:
+			f.uploads[id] = append(f.uploads[id], body...)
+			w.Header().Set("Location", r.URL.Path)
+			w.WriteHeader(http.StatusAccepted)
+		case http.MethodPut:
+			f.blobs[r.URL.Query().Get("digest")] = append(f.uploads[id], body...)
+			w.WriteHeader(http.StatusCreated)
+		}
+		return
+	}
+	if _, digest, ok := strings.Cut(path, "/blobs/"); ok {
+		b, found := f.blobs[digest]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(b)
+		}
+		return
+	}
+	if repo, ref, ok := strings.Cut(path, "/manifests/"); ok {
+		key := repo + "/" + ref
+		if r.Method == http.MethodPut {
+			digest, _, _ := v1.SHA256(strings.NewReader(string(body)))
+			for _, k := range []string{key, repo + "/" + digest.String()} {
+				f.manifests[k] = body
+				f.types[k] = r.Header.Get("Content-Type")
+			}
+			w.Header().Set("Docker-Content-Digest", digest.String())
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		b, found := f.manifests[key]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		digest, _, _ := v1.SHA256(strings.NewReader(string(b)))
+		w.Header().Set("Content-Type", f.types[key])
+		w.Header().Set("Docker-Content-Digest", digest.String())
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(b)
+		}
+		return
+	}
+	// /v2/ and the referrers API
+	if path == "" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func TestAttach(t *testing.T) {
+	server := httptest.NewServer(newFakeRegistry())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	opts := &config.KanikoOptions{}
+	opts.InsecureRegistries = []string{host}
+
+	subject := host + "/foo/bar:latest"
+	img, err := random.Image(64, 1)
+	testutil.CheckNoError(t, err)
+	ref, err := name.ParseReference(subject, name.Insecure)
+	testutil.CheckNoError(t, err)
+	testutil.CheckNoError(t, remote.Write(ref, img))
+	dig, err := img.Digest()
+	testutil.CheckNoError(t, err)
+
+	ctx := context.Background()
+	report, err := Attach(ctx, subject, "application/vnd.example.report+xml", []byte("<report/>"), map[string]string{"suite": "unit"}, opts)
+	testutil.CheckNoError(t, err)
+	_, err = Attach(ctx, subject, "application/spdx+json", []byte("{}"), nil, opts)
+	testutil.CheckNoError(t, err)
+
+	// the registry has no referrers API, the fallback tag lists them
+	referrers, err := ListReferrers(ctx, subject, "", opts)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, 2, len(referrers))
+
+	referrers, err = ListReferrers(ctx, host+"/foo/bar@"+dig.String(), "application/vnd.example.report+xml", opts)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, 1, len(referrers))
+	testutil.CheckDeepEqual(t, report.DigestStr(), referrers[0].Digest.String())
+
+	artifact, err := remote.Image(report)
+	testutil.CheckNoError(t, err)
+	m, err := artifact.Manifest()
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, dig, m.Subject.Digest)
+	testutil.CheckDeepEqual(t, "unit", m.Annotations["suite"])
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	image_util "github.com/osscontainertools/kaniko/pkg/image"
+)
+
+// The pre-defined annotation keys of the OCI image spec --auto-metadata sets.
+const (
+	ociSource     = "org.opencontainers.image.source"
+	ociRevision   = "org.opencontainers.image.revision"
+	ociCreated    = "org.opencontainers.image.created"
+	ociVersion    = "org.opencontainers.image.version"
+	ociBaseName   = "org.opencontainers.image.base.name"
+	ociBaseDigest = "org.opencontainers.image.base.digest"
+)
+
+// imageBase is the registry image a stage is built on, through the stages
+// it is based on. It is empty for stages built from scratch.
+type imageBase struct {
+	name   string
+	digest string
+	// labels are those of the image, which the stage inherits
+	labels map[string]string
+}
+
+// resolveImageBase describes img, the base image of stage as retrieved.
+func resolveImageBase(stage config.KanikoStage, img v1.Image, opts *config.KanikoOptions) (imageBase, error) {
+	baseName, ok, err := image_util.BaseImageName(stage, opts)
+	if err != nil || !ok {
+		return imageBase{}, err
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return imageBase{}, err
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return imageBase{}, err
+	}
+	return imageBase{name: baseName, digest: digest.String(), labels: cfg.Config.Labels}, nil
+}
+
+// applyAutoMetadata records the revision of the build context, the time
+// the image was created at and its base as the labels or annotations of img
+// --auto-metadata asks for. Labels the build sets itself, by --label or
+// LABEL, are kept, only those inherited from the base are replaced.
+func applyAutoMetadata(img v1.Image, base imageBase, created time.Time, opts *config.KanikoOptions) (v1.Image, error) {
+	metadata := map[string]string{}
+	if rev := opts.ContextRevision; rev != nil {
+		if rev.Source != "" {
+			metadata[ociSource] = rev.Source
+		}
+		metadata[ociRevision] = rev.Revision
+		if rev.Version != "" {
+			metadata[ociVersion] = rev.Version
+		}
+	}
+	// --reproducible dates the image at the epoch, there is no date to record
+	if !opts.Reproducible {
+		metadata[ociCreated] = created.UTC().Format(time.RFC3339)
+	}
+	if base.name != "" {
+		metadata[ociBaseName] = base.name
+		metadata[ociBaseDigest] = base.digest
+	}
+
+	if opts.AutoMetadata.Labels() {
+		cf, err := img.ConfigFile()
+		if err != nil {
+			return nil, err
+		}
+		cfg := cf.DeepCopy()
+		if cfg.Config.Labels == nil {
+			cfg.Config.Labels = map[string]string{}
+		}
+		for k, v := range metadata {
+			if current, ok := cfg.Config.Labels[k]; ok && current != base.labels[k] {
+				continue
+			}
+			cfg.Config.Labels[k] = v
+		}
+		img, err = mutate.ConfigFile(img, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.AutoMetadata.Annotations() {
+		img = mutate.Annotations(img, metadata).(v1.Image)
+	}
+	return img, nil
+}
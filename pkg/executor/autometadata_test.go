@@ -0,0 +1,93 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/osscontainertools/kaniko/testutil"
+)
+
+func Test_applyAutoMetadata(t *testing.T) {
+	img, err := random.Image(64, 1)
+	testutil.CheckNoError(t, err)
+	cf, err := img.ConfigFile()
+	testutil.CheckNoError(t, err)
+	cfg := cf.DeepCopy()
+	// one label inherited from the base, one set by the Dockerfile
+	cfg.Config.Labels = map[string]string{
+		ociSource:  "https://example.com/base.git",
+		ociVersion: "from-dockerfile",
+	}
+	img, err = mutate.ConfigFile(img, cfg)
+	testutil.CheckNoError(t, err)
+
+	base := imageBase{
+		name:   "debian:12",
+		digest: "sha256:0123456789012345678901234567890123456789012345678901234567890123",
+		labels: map[string]string{ociSource: "https://example.com/base.git"},
+	}
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	opts := &config.KanikoOptions{
+		AutoMetadata: config.AutoMetadataAll,
+		ContextRevision: &config.ContextRevision{
+			Source:   "https://example.com/app.git",
+			Revision: "4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+			Version:  "v1.2.3",
+		},
+	}
+	expected := map[string]string{
+		ociSource:     "https://example.com/app.git",
+		ociRevision:   "4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+		ociVersion:    "v1.2.3",
+		ociCreated:    "2026-01-02T03:04:05Z",
+		ociBaseName:   "debian:12",
+		ociBaseDigest: base.digest,
+	}
+
+	got, err := applyAutoMetadata(img, base, created, opts)
+	testutil.CheckNoError(t, err)
+	m, err := got.Manifest()
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, expected, m.Annotations)
+	cf, err = got.ConfigFile()
+	testutil.CheckNoError(t, err)
+	expected[ociVersion] = "from-dockerfile"
+	testutil.CheckDeepEqual(t, expected, cf.Config.Labels)
+
+	// labels only, without a git checkout and reproducible
+	opts.AutoMetadata = config.AutoMetadataLabels
+	opts.ContextRevision = nil
+	opts.Reproducible = true
+	got, err = applyAutoMetadata(img, base, created, opts)
+	testutil.CheckNoError(t, err)
+	m, err = got.Manifest()
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, 0, len(m.Annotations))
+	cf, err = got.ConfigFile()
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, map[string]string{
+		ociSource:     "https://example.com/base.git",
+		ociVersion:    "from-dockerfile",
+		ociBaseName:   "debian:12",
+		ociBaseDigest: base.digest,
+	}, cf.Config.Labels)
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package executor
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -45,6 +46,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 	"github.com/osscontainertools/kaniko/pkg/assert"
+	"github.com/osscontainertools/kaniko/pkg/buildcontext"
 	"github.com/osscontainertools/kaniko/pkg/cache"
 	"github.com/osscontainertools/kaniko/pkg/commands"
 	"github.com/osscontainertools/kaniko/pkg/config"
@@ -52,9 +54,11 @@ import (
 	"github.com/osscontainertools/kaniko/pkg/dockerfile"
 	image_util "github.com/osscontainertools/kaniko/pkg/image"
 	"github.com/osscontainertools/kaniko/pkg/image/remote"
+	"github.com/osscontainertools/kaniko/pkg/logging"
 	"github.com/osscontainertools/kaniko/pkg/mounts"
 	"github.com/osscontainertools/kaniko/pkg/snapshot"
 	"github.com/osscontainertools/kaniko/pkg/timing"
+	"github.com/osscontainertools/kaniko/pkg/tracing"
 	"github.com/osscontainertools/kaniko/pkg/util"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
@@ -71,21 +75,28 @@ var (
 )
 
 type snapShotter interface {
-	Init() error
-	TakeSnapshotFS() (string, int, error)
-	TakeSnapshot([]string, bool) (string, int, error)
+	Init(context.Context) error
+	TakeSnapshotFS(context.Context) (string, int, error)
+	TakeSnapshot(context.Context, []string, bool) (string, int, error)
 }
 
 // stageBuilder contains all fields necessary to build one stage of a Dockerfile
 type stageBuilder struct {
+	ctx             context.Context
 	index           int
+	name            string
+	baseName        string
+	firstStep       int // build-wide number of the first command, for output prefixes
 	final           bool
+	push            bool
 	image           v1.Image
 	cf              *v1.ConfigFile
 	baseImageDigest string
 	cmds            []commands.DockerCommand
 	lines           []int // source line per command, aligned with cmds
 	args            *dockerfile.BuildArgs
+	linkLayers      []int // indices of the layers COPY --link and ADD --link added to image
+	linkEpoch       *time.Time
 }
 
 type stageCacheInfo struct {
@@ -166,7 +177,7 @@ func makeSnapshotter(opts *config.KanikoOptions) (*snapshot.Snapshotter, error)
 }
 
 // newStageBuilder returns a new type stageBuilder which contains all the information required to build the stage
-func newStageBuilder(sourceImage v1.Image, args *dockerfile.BuildArgs, opts *config.KanikoOptions, stage config.KanikoStage, fileContext util.FileContext) (*stageBuilder, error) {
+func newStageBuilder(ctx context.Context, sourceImage v1.Image, args *dockerfile.BuildArgs, opts *config.KanikoOptions, stage config.KanikoStage, fileContext util.FileContext) (*stageBuilder, error) {
 	_opts := *opts
 	if !stage.Push {
 		_opts.Labels = []string{}
@@ -209,16 +220,22 @@ func newStageBuilder(sourceImage v1.Image, args *dockerfile.BuildArgs, opts *con
 		return nil, err
 	}
 	s := &stageBuilder{
+		ctx:             ctx,
 		index:           stage.Index,
+		name:            stageName(stage),
+		baseName:        stage.BaseName,
 		final:           stage.Final,
+		push:            stage.Push,
 		image:           sourceImage,
 		cf:              imageConfig,
 		baseImageDigest: digest.String(),
 		args:            args.Clone(),
+		linkEpoch:       linkEpoch(ctx, opts),
 	}
 
+	cmdCtx := config.WithSourceDateEpoch(ctx, s.linkEpoch)
 	for _, cmd := range stage.Commands {
-		command, err := commands.GetCommand(cmd, fileContext, opts.Secrets, opts.RunV2, opts.CacheCopyLayers, opts.CacheRunLayers)
+		command, err := commands.GetCommand(cmdCtx, cmd, fileContext, opts.Secrets, opts.URLCacheDir, opts.RunV2, opts.CacheCopyLayers, opts.CacheRunLayers)
 		if err != nil {
 			return nil, err
 		}
@@ -229,6 +246,18 @@ func newStageBuilder(sourceImage v1.Image, args *dockerfile.BuildArgs, opts *con
 	return s, nil
 }
 
+// linkEpoch is what COPY --link and ADD --link date their files to. With
+// FF_KANIKO_REPRODUCIBLE_PRESERVE_BASE_LAYERS their layers are pushed as built
+// instead of through mutate.Canonical, so --reproducible dates them to the
+// Unix epoch when SOURCE_DATE_EPOCH is not set.
+func linkEpoch(ctx context.Context, opts *config.KanikoOptions) *time.Time {
+	if epoch := config.SourceDateEpoch(ctx); epoch != nil || !opts.Reproducible {
+		return epoch
+	}
+	epoch := time.Unix(0, 0).UTC()
+	return &epoch
+}
+
 func initConfig(img partial.WithConfigFile, opts *config.KanikoOptions) (*v1.ConfigFile, error) {
 	imageConfig, err := img.ConfigFile()
 	if err != nil {
@@ -278,14 +307,14 @@ func isOCILayout(path string) bool {
 	return strings.HasPrefix(path, "oci:")
 }
 
-func crossStageCacheKey(command commands.DockerCommand, stageFinalCacheKeys map[int]string, externalImageDigests map[string]string) (string, bool) {
+func crossStageCacheKey(command commands.DockerCommand, platform string, stageFinalCacheKeys map[int]string, externalImageDigests map[string]string) (string, bool) {
 	copyCmd, ok := commands.CastAbstractCopyCommand(command)
 	if !ok || copyCmd.From() == "" {
 		return "", false
 	}
 	fromIdx, err := strconv.Atoi(copyCmd.From())
 	if err != nil {
-		digest, ok := externalImageDigests[copyCmd.From()]
+		digest, ok := externalImageDigests[util.DependencyKey(copyCmd.From(), platform)]
 		return digest, ok
 	}
 	cacheKey, ok := stageFinalCacheKeys[fromIdx]
@@ -324,16 +353,34 @@ func populateCompositeKey(command commands.DockerCommand, files []string, compos
 	}
 	compositeKey.AddKey(keyString)
 
+	if keyer, ok := command.(commands.RemoteSourceKeyer); ok {
+		keys, err := keyer.RemoteSourceKeys(replacementEnvs)
+		if err != nil {
+			return compositeKey, fmt.Errorf("resolving remote sources: %w", err)
+		}
+		compositeKey.AddKey(keys...)
+	}
+
 	if stageFinalCacheKeys != nil {
 		// mz334: COPY --from shortcut — use the source stage's cache key or the external image digest instead of hashing files.
-		cacheKey, ok := crossStageCacheKey(command, stageFinalCacheKeys, externalImageDigests)
+		cacheKey, ok := crossStageCacheKey(command, fileContext.Platform, stageFinalCacheKeys, externalImageDigests)
 		if ok {
 			compositeKey.AddKey(cacheKey)
 			return compositeKey, nil
 		}
 		return compositeKey, fmt.Errorf("shortcut key not found")
 	} else if files != nil {
+		// COPY --parents reproduces the source paths, so they are part of the result
+		copyCmd, ok := commands.CastAbstractCopyCommand(command)
+		parents := ok && copyCmd.Parents()
 		for _, f := range files {
+			if parents {
+				rel, err := filepath.Rel(fileContext.Root, f)
+				if err != nil {
+					return compositeKey, err
+				}
+				compositeKey.AddKey(rel)
+			}
 			if err := compositeKey.AddPath(f, fileContext); err != nil {
 				return compositeKey, err
 			}
@@ -345,6 +392,38 @@ func populateCompositeKey(command commands.DockerCommand, files []string, compos
 	return compositeKey, nil
 }
 
+// isLink reports whether command builds its layer in isolation, single
+// snapshot mode has no use for such layers.
+func isLink(command commands.DockerCommand, opts *config.KanikoOptions) bool {
+	l, ok := command.(commands.Linker)
+	return ok && l.Link() && !opts.SingleSnapshot
+}
+
+// populateLinkKey keys a COPY --link or ADD --link by the command, its
+// sources, where and as whom cfg makes it copy them and the epoch its files
+// are dated to, so its layer is found again on top of any base. The running key takes in the link key, the
+// commands after it still depend on it.
+func populateLinkKey(command commands.DockerCommand, files []string, compositeKey CompositeCache, args *dockerfile.BuildArgs, cfg *v1.Config, fileContext util.FileContext, epoch *time.Time) (CompositeCache, string, error) {
+	linkKey, err := populateCompositeKey(command, files, *NewCompositeCache("link"), args, cfg.Env, fileContext, nil, nil)
+	if err != nil {
+		return compositeKey, "", err
+	}
+	if epoch != nil {
+		linkKey.AddKey(fmt.Sprintf("%s=%d", config.SourceDateEpochArg, epoch.Unix()))
+	}
+	placement, err := command.(commands.Linker).LinkKey(cfg, args)
+	if err != nil {
+		return compositeKey, "", fmt.Errorf("failed to resolve link destination: %w", err)
+	}
+	linkKey.AddKey(placement...)
+	lk, err := linkKey.Hash()
+	if err != nil {
+		return compositeKey, "", fmt.Errorf("failed to hash link key: %w", err)
+	}
+	compositeKey.AddKey(lk)
+	return compositeKey, lk, nil
+}
+
 func redirectCacheKey(inferredKey CompositeCache, layerCache cache.LayerCache) (*CompositeCache, error) {
 	inferredCk, err := inferredKey.Hash()
 	if err != nil {
@@ -403,9 +482,11 @@ func (s *stageBuilder) optimize(compositeKeyPtr *CompositeCache, cfg v1.Config,
 			// source files do not exist during precompute or after elimination.
 			copyCmd, isCopy := commands.CastAbstractCopyCommand(command)
 			crossStageCopy := isCopy && copyCmd.From() != ""
+			linked := isLink(command, opts)
+			var linkKey string
 			inferred := false
 			precomputed := false
-			if crossStageCopy && config.FF.InferCrossStageCacheKey && opts.CacheCopyLayers && opts.CacheRunLayers {
+			if crossStageCopy && !linked && config.FF.InferCrossStageCacheKey && opts.CacheCopyLayers && opts.CacheRunLayers {
 				inferredKey, err := populateCompositeKey(command, nil, compositeKey.Clone(), args, cfg.Env, fileContext, stageFinalCacheKeys, externalImageDigests)
 				if err == nil {
 					inferredCK, err := inferredKey.Hash()
@@ -463,7 +544,11 @@ func (s *stageBuilder) optimize(compositeKeyPtr *CompositeCache, cfg v1.Config,
 				if err != nil {
 					return "", ci, v1.Config{}, fmt.Errorf("failed to get files used from context: %w", err)
 				}
-				compositeKey, err = populateCompositeKey(command, files, compositeKey, args, cfg.Env, fileContext, nil, nil)
+				if linked {
+					compositeKey, linkKey, err = populateLinkKey(command, files, compositeKey, args, &cfg, fileContext, s.linkEpoch)
+				} else {
+					compositeKey, err = populateCompositeKey(command, files, compositeKey, args, cfg.Env, fileContext, nil, nil)
+				}
 				if err != nil {
 					return "", ci, v1.Config{}, err
 				}
@@ -474,9 +559,13 @@ func (s *stageBuilder) optimize(compositeKeyPtr *CompositeCache, cfg v1.Config,
 			if err != nil {
 				return "", ci, v1.Config{}, fmt.Errorf("failed to hash composite key: %w", err)
 			}
+			finalCacheKey = ck
+			// a link layer is cached by its own key, independent of the layers below
+			if linkKey != "" {
+				ck = linkKey
+			}
 
 			logrus.Debugf("Optimize: cache key for command %v %v", command.String(), ck)
-			finalCacheKey = ck
 			ci.cacheKeys[i] = ck
 
 			// a precompute-resolved copy must apply its cached layer even after
@@ -531,8 +620,54 @@ func (s *stageBuilder) optimize(compositeKeyPtr *CompositeCache, cfg v1.Config,
 	return finalCacheKey, ci, cfg, nil
 }
 
+// registerSecrets masks the values of all --secret options in the build output.
+// Secrets that can't be read are skipped here, RUN reports them when mounted.
+func registerSecrets(secrets config.SecretOptions) {
+	for _, s := range secrets {
+		if s.Type == "env" {
+			if val, ok := os.LookupEnv(s.Src); ok {
+				logging.RegisterSecret(val)
+			}
+			continue
+		}
+		if b, err := os.ReadFile(s.Src); err == nil {
+			logging.RegisterSecret(string(b))
+		}
+	}
+}
+
+// step identifies the command at index for captured process output.
+func (s *stageBuilder) step(index int) logging.Step {
+	return logging.Step{
+		ID:    s.firstStep + index,
+		Stage: s.name,
+		Index: index + 1,
+		Total: len(s.cmds),
+	}
+}
+
+// stageName is the name a stage is referred to by in output, unnamed stages
+// are numbered like buildkit does.
+func stageName(stage config.KanikoStage) string {
+	if stage.Name != "" {
+		return stage.Name
+	}
+	return fmt.Sprintf("stage-%d", stage.Index)
+}
+
+// context returns the build context, stageBuilders constructed directly in
+// tests have none.
+func (s *stageBuilder) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
 func (s *stageBuilder) build(compositeKey CompositeCache, opts *config.KanikoOptions, fileContext util.FileContext, snapshotter snapShotter, crossStageDeps bool, stageFinalCacheKeys map[int]string, externalImageDigests map[string]string, layerCache cache.LayerCache) error {
 	assert.Assert("executor.stagebuilder.config-nonnull", s.cf != nil, "stageBuilder (index %d) has nil config file", s.index)
+	stageSection := logging.StartStage(s.index, fmt.Sprintf("[%s] FROM %s", s.name, s.baseName))
+	defer stageSection.End()
 	// Unpack file system to root if we need to.
 	shouldUnpack := false
 	for _, cmd := range s.cmds {
@@ -551,10 +686,17 @@ func (s *stageBuilder) build(compositeKey CompositeCache, opts *config.KanikoOpt
 	if s.final && opts.Materialize {
 		shouldUnpack = true
 	}
+	// the SBOM is read off the filesystem, which must hold all of the image
+	if s.push && opts.SBOM != config.SBOMFormatNone {
+		shouldUnpack = true
+	}
 	if s.index == 0 && opts.InitialFSUnpacked {
 		shouldUnpack = false
 	}
 
+	if err := s.context().Err(); err != nil {
+		return err
+	}
 	if shouldUnpack {
 		t := timing.Start("FS Unpacking")
 
@@ -576,7 +718,7 @@ func (s *stageBuilder) build(compositeKey CompositeCache, opts *config.KanikoOpt
 	initSnapshotTaken := false
 	if opts.SingleSnapshot {
 		t := timing.Start("Initial FS snapshot")
-		err := snapshotter.Init()
+		err := snapshotter.Init(s.context())
 		t.End()
 		if err != nil {
 			return err
@@ -596,25 +738,37 @@ func (s *stageBuilder) build(compositeKey CompositeCache, opts *config.KanikoOpt
 
 	cacheGroup := errgroup.Group{}
 	var cmdTimer trace.Span
+	var stepSection *logging.Section
 	// stop on the way out too: an unended span is never exported
 	defer func() {
 		if cmdTimer != nil {
 			cmdTimer.End()
 		}
+		if stepSection != nil {
+			stepSection.End()
+		}
 	}()
 	for index, command := range s.cmds {
 		if command == nil {
 			continue
 		}
+		if stepSection != nil {
+			stepSection.End()
+			stepSection = nil
+		}
+		if err := s.context().Err(); err != nil {
+			return fmt.Errorf("build cancelled: %w", err)
+		}
 
 		cmdTimer = timing.Start("Command")
 
 		// mz334: cross-stage copies key off the inferred pointer first, their
 		// source stage may be eliminated and its files never materialize. The
 		// inferred key also serves to push a pointer below.
+		linked := isLink(command, opts)
 		inferred := false
 		var inferredCacheKey string
-		if opts.Cache && config.FF.InferCrossStageCacheKey && opts.CacheCopyLayers && opts.CacheRunLayers {
+		if opts.Cache && !linked && config.FF.InferCrossStageCacheKey && opts.CacheCopyLayers && opts.CacheRunLayers {
 			copyCmd, isCopy := commands.CastAbstractCopyCommand(command)
 			if isCopy && copyCmd.From() != "" {
 				inferredKey, err := populateCompositeKey(command, nil, compositeKey.Clone(), s.args, s.cf.Config.Env, fileContext, stageFinalCacheKeys, externalImageDigests)
@@ -636,22 +790,23 @@ func (s *stageBuilder) build(compositeKey CompositeCache, opts *config.KanikoOpt
 		}
 		// If the command uses files from the context, add them.
 		var files []string
+		var linkKey string
 		if !inferred {
 			var err error
 			files, err = command.FilesUsedFromContext(&s.cf.Config, s.args)
 			if err != nil {
 				return fmt.Errorf("failed to get files used from context: %w", err)
 			}
-			if opts.Cache {
+			if opts.Cache && linked {
+				compositeKey, linkKey, err = populateLinkKey(command, files, compositeKey, s.args, &s.cf.Config, fileContext, s.linkEpoch)
+			} else if opts.Cache {
 				compositeKey, err = populateCompositeKey(command, files, compositeKey, s.args, s.cf.Config.Env, fileContext, nil, nil)
-				if err != nil {
-					return err
-				}
+			}
+			if err != nil {
+				return err
 			}
 		}
 
-		logrus.Info(command.String())
-
 		isCacheCommand := func() bool {
 			switch command.(type) {
 			case commands.Cached:
@@ -660,6 +815,8 @@ func (s *stageBuilder) build(compositeKey CompositeCache, opts *config.KanikoOpt
 				return false
 			}
 		}()
+		stepSection = logging.StartStep(s.step(index), command.String(), isCacheCommand)
+		logrus.Info(command.String())
 
 		if timing.TracingEnabled() {
 			phase := "kaniko"
@@ -688,7 +845,7 @@ func (s *stageBuilder) build(compositeKey CompositeCache, opts *config.KanikoOpt
 			// Take initial snapshot if command does not expect to return
 			// a list of files.
 			t := timing.StartChild(cmdTimer, "Initial FS snapshot")
-			err := snapshotter.Init()
+			err := snapshotter.Init(s.context())
 			t.End()
 			if err != nil {
 				return err
@@ -696,9 +853,32 @@ func (s *stageBuilder) build(compositeKey CompositeCache, opts *config.KanikoOpt
 			initSnapshotTaken = true
 		}
 
+		var output *logging.StepOutput
+		var proc *commands.Process
+		if c, ok := command.(commands.ProcessRunner); ok {
+			var err error
+			output, err = logging.NewStepOutput(s.step(index), opts.RunLogDir)
+			if err != nil {
+				return err
+			}
+			proc = &commands.Process{
+				Stdout: output.Stdout,
+				Stderr: output.Stderr,
+				Env:    tracing.TraceEnv(cmdTimer),
+			}
+			c.SetProcess(proc)
+		}
 		execTimer := timing.StartChild(cmdTimer, "Execute")
 		err := command.ExecuteCommand(&s.cf.Config, s.args)
 		execTimer.End()
+		if output != nil {
+			if cerr := output.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("closing run output: %w", cerr)
+			}
+		}
+		if proc != nil {
+			cmdTimer.SetAttributes(timing.RusageAttributes(proc.State)...)
+		}
 		if err != nil {
 			return fmt.Errorf("failed to execute command: %w", err)
 		}
@@ -711,6 +891,10 @@ func (s *stageBuilder) build(compositeKey CompositeCache, opts *config.KanikoOpt
 			logrus.Debugf("Build: skipping snapshot for [%v]", command.String())
 			continue
 		}
+		layersBefore, err := s.layerCount()
+		if err != nil {
+			return err
+		}
 		if isCacheCommand {
 			v := command.(commands.Cached)
 			layer := v.Layer()
@@ -720,15 +904,23 @@ func (s *stageBuilder) build(compositeKey CompositeCache, opts *config.KanikoOpt
 				logrus.Info("No files were changed, appending empty layer to config. No layer added to image.")
 			} else {
 				var err error
-				s.image, err = saveLayerToImage(s.image, layer, command.String(), opts)
+				s.image, err = saveLayerToImage(s.context(), s.image, layer, command.String(), opts)
 				if err != nil {
 					return fmt.Errorf("failed to save layer: %w", err)
 				}
 			}
 		} else {
-			tarPath, snapshotted, err := takeSnapshot(files, command.ShouldDetectDeletedFiles(), opts, snapshotter)
-			if err != nil {
-				return fmt.Errorf("failed to take snapshot: %w", err)
+			var tarPath string
+			var snapshotted int
+			var err error
+			if l, ok := command.(commands.LinkLayer); ok && linked && l.LinkTarPath() != "" {
+				// the layer was built in isolation, the rootfs is not consulted
+				tarPath = l.LinkTarPath()
+			} else {
+				tarPath, snapshotted, err = takeSnapshot(s.context(), files, command.ShouldDetectDeletedFiles(), opts, snapshotter)
+				if err != nil {
+					return fmt.Errorf("failed to take snapshot: %w", err)
+				}
 			}
 
 			unpacked := shouldUnpack || (s.index == 0 && opts.InitialFSUnpacked)
@@ -750,6 +942,9 @@ func (s *stageBuilder) build(compositeKey CompositeCache, opts *config.KanikoOpt
 				if err != nil {
 					return fmt.Errorf("failed to hash composite key: %w", err)
 				}
+				if linkKey != "" {
+					ck = linkKey
+				}
 
 				logrus.Debugf("Build: cache key for command %v %v", command.String(), ck)
 
@@ -774,11 +969,20 @@ func (s *stageBuilder) build(compositeKey CompositeCache, opts *config.KanikoOpt
 					}
 				}
 			}
-			s.image, err = saveSnapshotToImage(s.image, command.String(), tarPath, opts)
+			s.image, err = saveSnapshotToImage(s.context(), s.image, command.String(), tarPath, opts)
 			if err != nil {
 				return fmt.Errorf("failed to save snapshot to image: %w", err)
 			}
 		}
+		if linked {
+			layers, err := s.layerCount()
+			if err != nil {
+				return err
+			}
+			if layers > layersBefore {
+				s.linkLayers = append(s.linkLayers, layersBefore)
+			}
+		}
 	}
 
 	if err := cacheGroup.Wait(); err != nil {
@@ -788,20 +992,28 @@ func (s *stageBuilder) build(compositeKey CompositeCache, opts *config.KanikoOpt
 	return nil
 }
 
-func takeSnapshot(files []string, shdDelete bool, opts *config.KanikoOptions, snapshotter snapShotter) (string, int, error) {
+func (s *stageBuilder) layerCount() (int, error) {
+	layers, err := s.image.Layers()
+	if err != nil {
+		return 0, err
+	}
+	return len(layers), nil
+}
+
+func takeSnapshot(ctx context.Context, files []string, shdDelete bool, opts *config.KanikoOptions, snapshotter snapShotter) (string, int, error) {
 	var snapshot string
 	var snapshotted int
 	var err error
 
 	t := timing.Start("Snapshotting FS")
 	if files == nil || opts.SingleSnapshot {
-		snapshot, snapshotted, err = snapshotter.TakeSnapshotFS()
+		snapshot, snapshotted, err = snapshotter.TakeSnapshotFS(ctx)
 	} else {
 		if !config.FF.VolumeSkipMkdir {
 			// Volumes are very weird. They get snapshotted in the next command.
 			files = append(files, util.Volumes()...)
 		}
-		snapshot, snapshotted, err = snapshotter.TakeSnapshot(files, shdDelete)
+		snapshot, snapshotted, err = snapshotter.TakeSnapshot(ctx, files, shdDelete)
 	}
 	t.End()
 	return snapshot, snapshotted, err
@@ -822,7 +1034,7 @@ func shouldTakeSnapshot(isMetadataCmd bool, isLastCommand bool, opts *config.Kan
 	return !isMetadataCmd
 }
 
-func saveSnapshotToImage(image v1.Image, createdBy string, tarPath string, opts *config.KanikoOptions) (v1.Image, error) {
+func saveSnapshotToImage(ctx context.Context, image v1.Image, createdBy string, tarPath string, opts *config.KanikoOptions) (v1.Image, error) {
 	imageMediaType, err := image.MediaType()
 	if err != nil {
 		return nil, err
@@ -837,7 +1049,7 @@ func saveSnapshotToImage(image v1.Image, createdBy string, tarPath string, opts
 		return image, nil
 	}
 
-	return saveLayerToImage(image, layer, createdBy, opts)
+	return saveLayerToImage(ctx, image, layer, createdBy, opts)
 }
 
 func saveSnapshotToLayer(tarPath string, imageMediaType types.MediaType, opts *config.KanikoOptions) (v1.Layer, error) {
@@ -994,7 +1206,7 @@ func convertLayerMediaType(layer v1.Layer, image v1.Image, opts *config.KanikoOp
 	return layer, nil
 }
 
-func saveLayerToImage(image v1.Image, layer v1.Layer, createdBy string, opts *config.KanikoOptions) (v1.Image, error) {
+func saveLayerToImage(ctx context.Context, image v1.Image, layer v1.Layer, createdBy string, opts *config.KanikoOptions) (v1.Image, error) {
 	assert.Assert("executor.savelayer.layer-nonnull", layer != nil, "saveLayerToImage called with nil layer")
 	layer, err := convertLayerMediaType(layer, image, opts)
 	if err != nil {
@@ -1017,18 +1229,33 @@ func saveLayerToImage(image v1.Image, layer v1.Layer, createdBy string, opts *co
 		layer = el
 	}
 
+	history := v1.History{
+		Author:    constants.Author,
+		CreatedBy: createdBy,
+	}
+	if epoch := config.SourceDateEpoch(ctx); epoch != nil {
+		history.Created = v1.Time{Time: *epoch}
+	}
 	return mutate.Append(image,
 		mutate.Addendum{
-			Layer: layer,
-			History: v1.History{
-				Author:    constants.Author,
-				CreatedBy: createdBy,
-			},
+			Layer:   layer,
+			History: history,
 		},
 	)
 }
 
-func CalculateDependencies(stages []config.KanikoStage, opts *config.KanikoOptions) (map[int][]string, error) {
+// newBaseCompositeCache starts the cache key of a stage built on the base
+// image with baseImageDigest. Layers clamped to SOURCE_DATE_EPOCH differ
+// from unclamped ones, so the epoch is part of the key.
+func newBaseCompositeCache(ctx context.Context, baseImageDigest string) *CompositeCache {
+	compositeKey := NewCompositeCache(baseImageDigest)
+	if epoch := config.SourceDateEpoch(ctx); epoch != nil {
+		compositeKey.AddKey(fmt.Sprintf("%s=%d", config.SourceDateEpochArg, epoch.Unix()))
+	}
+	return compositeKey
+}
+
+func CalculateDependencies(ctx context.Context, stages []config.KanikoStage, opts *config.KanikoOptions) (map[int][]string, error) {
 	images := make(map[int]v1.Image)
 	depGraph := map[int][]string{}
 	for _, s := range stages {
@@ -1042,7 +1269,7 @@ func CalculateDependencies(stages []config.KanikoStage, opts *config.KanikoOptio
 		} else if s.Name == constants.NoBaseImage {
 			image = image_util.EmptyBaseImage
 		} else {
-			image, err = image_util.RetrieveSourceImage(s, opts)
+			image, err = image_util.RetrieveSourceImage(ctx, s, opts)
 			if err != nil {
 				return nil, err
 			}
@@ -1105,7 +1332,7 @@ type pushedLayer struct {
 	key  v1.Hash
 }
 
-func RenderStages(stages []config.KanikoStage, cacheInfo []*stageCacheInfo, opts *config.KanikoOptions, fileContext util.FileContext, crossStageDependencies map[int][]string, layerCache *memoizedLayerCache, externalImageDigests map[string]string, sharedRemote map[string]bool) (retErr error) {
+func RenderStages(ctx context.Context, stages []config.KanikoStage, cacheInfo []*stageCacheInfo, opts *config.KanikoOptions, fileContext util.FileContext, crossStageDependencies map[int][]string, layerCache *memoizedLayerCache, externalImageDigests map[string]string, sharedRemote map[string]bool) (retErr error) {
 	printf := func(format string, args ...any) {
 		if retErr == nil {
 			_, retErr = fmt.Fprintf(Out, format, args...)
@@ -1146,7 +1373,7 @@ func RenderStages(stages []config.KanikoStage, cacheInfo []*stageCacheInfo, opts
 				printf("  STREAM %s\n", s.BaseName)
 			}
 			if s.BaseImageDigest != "" {
-				base, err := image_util.RetrieveSourceImage(s, opts)
+				base, err := image_util.RetrieveSourceImage(ctx, s, opts)
 				if err != nil {
 					return err
 				}
@@ -1160,7 +1387,7 @@ func RenderStages(stages []config.KanikoStage, cacheInfo []*stageCacheInfo, opts
 			}
 		}
 		for jdx, c := range s.Commands {
-			command, err := commands.GetCommand(c, fileContext, opts.Secrets, opts.RunV2, opts.CacheCopyLayers, opts.CacheRunLayers)
+			command, err := commands.GetCommand(ctx, c, stageFileContext(fileContext, s, opts), opts.Secrets, opts.URLCacheDir, opts.RunV2, opts.CacheCopyLayers, opts.CacheRunLayers)
 			if err != nil {
 				return err
 			}
@@ -1271,16 +1498,47 @@ func RenderStages(stages []config.KanikoStage, cacheInfo []*stageCacheInfo, opts
 
 // DoBuild executes building the Dockerfile
 func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
+	return Build(context.Background(), opts)
+}
+
+// Build executes building the Dockerfile described by opts. Cancelling ctx
+// aborts in-flight registry requests and kills running RUN processes.
+// The state of the build is carried on ctx, the registries that remain
+// package level (manifest cache, build mounts, secrets to redact) are reset
+// on entry so sequential builds in the same process don't observe each other;
+// config.FF, config.RootDir, config.KanikoDir and the timing tracer remain
+// process-wide, so concurrent builds are not supported.
+func Build(ctx context.Context, opts *config.KanikoOptions) (image v1.Image, retErr error) {
+	remote.ResetManifestCache()
+	mounts.Reset()
+	logging.ResetSecrets()
+	registerSecrets(opts.Secrets)
+	state, err := newBuildState(opts)
+	if err != nil {
+		return nil, err
+	}
+	ctx = state.context(ctx)
+	defer func() {
+		if retErr == nil {
+			retErr = state.save()
+		}
+	}()
 	t := timing.Start("Total Build Time")
 	defer t.End()
 	stageFinalCacheKeys := make(map[int]string)
 
-	stages, metaArgs, err := dockerfile.ParseStages(opts)
+	// the provenance statement hashes the Dockerfile as it was parsed, which
+	// a remote one can only be read once for
+	dockerfileContent, err := dockerfile.Read(opts.DockerfilePath)
+	if err != nil {
+		return nil, err
+	}
+	stages, metaArgs, err := dockerfile.ParseStagesFrom(dockerfileContent, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	kanikoStages, err := dockerfile.MakeKanikoStages(opts, stages, metaArgs)
+	kanikoStages, err := dockerfile.MakeKanikoStages(ctx, opts, stages, metaArgs)
 	if err != nil {
 		return nil, err
 	}
@@ -1289,8 +1547,9 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 	if err != nil {
 		return nil, err
 	}
+	fileContext.Contexts = directoryContexts(opts.BuildContexts)
 
-	crossStageDependencies, err := CalculateDependencies(kanikoStages, opts)
+	crossStageDependencies, err := CalculateDependencies(ctx, kanikoStages, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -1299,10 +1558,29 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 	assert.Assert("executor.build.stages-nonempty", len(kanikoStages) > 0, "no stages to build")
 
 	// Some stages may refer to other random images, not previous stages
-	externalImageDigests, extraStageImages, err := resolveExtraStageDigests(kanikoStages, opts)
+	externalImageDigests, extraStageImages, err := resolveExtraStageDigests(ctx, kanikoStages, opts)
 	if err != nil {
 		return nil, err
 	}
+	if opts.VerifyBaseKey != "" || opts.VerifyBasePolicy != "" {
+		// Retrieving a base verifies it, do so for every stage before the
+		// first one extracts anything.
+		for _, stage := range kanikoStages {
+			if stage.BaseImageStoredLocally {
+				continue
+			}
+			if _, err := image_util.RetrieveSourceImage(ctx, stage, opts); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var prov *provenanceRecorder
+	if opts.Provenance != config.ProvenanceNone {
+		prov, err = newProvenanceRecorder(opts, dockerfileContent, kanikoStages, externalImageDigests)
+		if err != nil {
+			return nil, fmt.Errorf("recording provenance: %w", err)
+		}
+	}
 	// legacy warmer overrides images override digest method to not return the digest
 	// as they get stored in a tarball and digest is lost in the process.
 	// But this also means that our defensive store and load here can't play nicely with them.
@@ -1332,7 +1610,7 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 			if stage.BaseImageStoredLocally {
 				baseImage = images[stage.BaseImageIndex]
 			} else {
-				baseImage, err = image_util.RetrieveSourceImage(stage, opts)
+				baseImage, err = image_util.RetrieveSourceImage(ctx, stage, opts)
 				if err != nil {
 					return nil, fmt.Errorf("precompute: failed to get baseImage: %w", err)
 				}
@@ -1346,7 +1624,8 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 			}
 			assert.Assert("executor.build.stage-order", args != nil, "stages must be processed in order: base stage %d not yet in stageArgs", stage.BaseImageIndex)
 
-			sb, err := newStageBuilder(baseImage, args, opts, stage, fileContext)
+			stageContext := stageFileContext(fileContext, stage, opts)
+			sb, err := newStageBuilder(ctx, baseImage, args, opts, stage, stageContext)
 			if err != nil {
 				return nil, err
 			}
@@ -1357,14 +1636,14 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 					compositeKey = ResumeCompositeCache(cacheKey)
 				}
 			} else {
-				compositeKey = NewCompositeCache(sb.baseImageDigest)
+				compositeKey = newBaseCompositeCache(ctx, sb.baseImageDigest)
 			}
 
 			cfg := sb.cf.Config
 			if stage.BaseImageStoredLocally {
 				cfg = stageConfigs[stage.BaseImageIndex]
 			}
-			finalCacheKey, ci, resultCfg, err := sb.optimize(compositeKey, cfg, sb.args, opts, fileContext, layerCache, stageFinalCacheKeys, externalImageDigests, false)
+			finalCacheKey, ci, resultCfg, err := sb.optimize(compositeKey, cfg, sb.args, opts, stageContext, layerCache, stageFinalCacheKeys, externalImageDigests, false)
 			if err != nil {
 				return nil, fmt.Errorf("precompute: failed to optimize stage %d: %w", stage.Index, err)
 			}
@@ -1452,7 +1731,7 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 	}
 
 	if opts.Dryrun || config.EnvBool("KANIKO_PRINT_PLAN") {
-		err := RenderStages(kanikoStages, cacheInfo, opts, fileContext, crossStageDependencies, layerCache, externalImageDigests, sharedRemote)
+		err := RenderStages(ctx, kanikoStages, cacheInfo, opts, fileContext, crossStageDependencies, layerCache, externalImageDigests, sharedRemote)
 		if err != nil {
 			return nil, err
 		}
@@ -1477,7 +1756,7 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 	if opts.PreserveContext {
 		if len(kanikoStages) > 1 || opts.PreCleanup || opts.Cleanup {
 			logrus.Info("Creating snapshot of build context")
-			tarball, _, err = snapshotter.TakeSnapshotFS()
+			tarball, _, err = snapshotter.TakeSnapshotFS(ctx)
 			if err != nil {
 				return nil, err
 			}
@@ -1511,11 +1790,22 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 	}
 
 	var pushImage v1.Image
+	stageBases := map[int]imageBase{}
+	stageLinkLayers := map[int][]int{}
+	step := 1
 	for _, stage := range kanikoStages {
-		baseImage, err := retrieveBaseImage(stage, opts, sharedRemote[stage.BaseImageDigest])
+		baseImage, err := retrieveBaseImage(ctx, stage, opts, sharedRemote[stage.BaseImageDigest])
 		if err != nil {
 			return nil, fmt.Errorf("failed to get baseImage: %w", err)
 		}
+		if stage.BaseImageStoredLocally {
+			stageBases[stage.Index] = stageBases[stage.BaseImageIndex]
+		} else if opts.AutoMetadata != config.AutoMetadataNone || opts.MetadataFile != "" {
+			stageBases[stage.Index], err = resolveImageBase(stage, baseImage, opts)
+			if err != nil {
+				return nil, err
+			}
+		}
 		if config.FF.NoPropagateAnnotations {
 			baseImage = image_util.WithoutAnnotations(baseImage)
 		}
@@ -1526,12 +1816,17 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 		}
 		assert.Assert("executor.build.stage-order", args != nil, "stages must be processed in order: base stage %d not yet in stageArgs", stage.BaseImageIndex)
 		// args is a pointer but is cloned inside newStageBuilder, so sharing it is safe.
+		stageContext := stageFileContext(fileContext, stage, opts)
 		sb, err := newStageBuilder(
-			baseImage, args, opts, stage,
-			fileContext)
+			ctx, baseImage, args, opts, stage,
+			stageContext)
 		if err != nil {
 			return nil, err
 		}
+		if stage.BaseImageStoredLocally {
+			// the base stage's link layers are at the same indices of this image
+			sb.linkLayers = slices.Clone(stageLinkLayers[stage.BaseImageIndex])
+		}
 		logrus.Infof("Building stage '%v' [idx: '%v', base-idx: '%v']",
 			stage.BaseName, stage.Index, stage.BaseImageIndex)
 
@@ -1543,12 +1838,12 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 			}
 		}
 		if compositeKey == nil {
-			compositeKey = NewCompositeCache(sb.baseImageDigest)
+			compositeKey = newBaseCompositeCache(ctx, sb.baseImageDigest)
 		}
 
 		// Apply optimizations to the instructions.
 		precomputedKey := stageFinalCacheKeys[stage.Index]
-		finalCacheKey, buildCi, _, err := sb.optimize(compositeKey, sb.cf.Config, sb.args.Clone(), opts, fileContext, layerCache, stageFinalCacheKeys, externalImageDigests, true)
+		finalCacheKey, buildCi, _, err := sb.optimize(compositeKey, sb.cf.Config, sb.args.Clone(), opts, stageContext, layerCache, stageFinalCacheKeys, externalImageDigests, true)
 		if err != nil {
 			return nil, fmt.Errorf("failed to optimize instructions: %w", err)
 		}
@@ -1570,11 +1865,14 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 
 		stageArgs[stage.Index] = sb.args
 		crossStageDeps := len(crossStageDependencies[stage.Index]) > 0
-		err = sb.build(*compositeKey, opts, fileContext, snapshotter, crossStageDeps, stageFinalCacheKeys, externalImageDigests, layerCache)
+		sb.firstStep = step
+		step += len(sb.cmds)
+		err = sb.build(*compositeKey, opts, stageContext, snapshotter, crossStageDeps, stageFinalCacheKeys, externalImageDigests, layerCache)
 		if err != nil {
 			return nil, fmt.Errorf("error building stage: %w", err)
 		}
 
+		stageLinkLayers[stage.Index] = sb.linkLayers
 		reviewConfig(stage, &sb.cf.Config)
 
 		sourceImage, err := mutate.Config(sb.image, sb.cf.Config)
@@ -1586,13 +1884,13 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 		if err != nil {
 			return nil, err
 		}
-		if opts.CustomPlatform == "" {
+		if platformName := stagePlatform(stage, opts); platformName == "" {
 			configFile.OS = runtime.GOOS
 			configFile.Architecture = runtime.GOARCH
 		} else {
-			platform, err := v1.ParsePlatform(opts.CustomPlatform)
+			platform, err := v1.ParsePlatform(platformName)
 			if err != nil {
-				return nil, fmt.Errorf("invalid platform %q: %w", opts.CustomPlatform, err)
+				return nil, fmt.Errorf("invalid platform %q: %w", platformName, err)
 			}
 			configFile.OS = platform.OS
 			configFile.Architecture = platform.Architecture
@@ -1608,11 +1906,16 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 		logrus.Debugf("Mapping stage idx %v to cachekey %v", stage.Index, finalCacheKey)
 
 		if stage.Push {
-			sourceImage, err = mutate.CreatedAt(sourceImage, v1.Time{Time: time.Now()})
+			created := time.Now()
+			if epoch := state.epoch; epoch != nil {
+				created = *epoch
+			}
+			sourceImage, err = mutate.CreatedAt(sourceImage, v1.Time{Time: created})
 			if err != nil {
 				return nil, err
 			}
 			if opts.Reproducible {
+				built := sourceImage
 				sourceImage, err = mutate.Canonical(sourceImage)
 				if err != nil {
 					return nil, err
@@ -1622,6 +1925,18 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 					if err != nil {
 						return nil, err
 					}
+					// link layers don't depend on the layers below them, kept as built
+					// they stay shared with the cache and every image they are copied into
+					sourceImage, err = image_util.ReplaceLayers(sourceImage, built, sb.linkLayers)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+			if opts.AutoMetadata != config.AutoMetadataNone {
+				sourceImage, err = applyAutoMetadata(sourceImage, stageBases[stage.Index], created, opts)
+				if err != nil {
+					return nil, err
 				}
 			}
 			if len(opts.Annotations) > 0 {
@@ -1632,6 +1947,21 @@ func DoBuild(opts *config.KanikoOptions) (image v1.Image, retErr error) {
 				return nil, err
 			}
 			pushImage = sourceImage
+			if opts.SBOM != config.SBOMFormatNone {
+				pushImage, err = attachSBOM(ctx, pushImage, opts)
+				if err != nil {
+					return nil, fmt.Errorf("generating sbom: %w", err)
+				}
+			}
+			if prov != nil {
+				pushImage, err = prov.attach(pushImage, opts)
+				if err != nil {
+					return nil, fmt.Errorf("generating provenance: %w", err)
+				}
+			}
+			if opts.MetadataFile != "" {
+				pushImage = recordBuildMetadata(pushImage, stageBases, externalImageDigests, finalCacheKey, opts)
+			}
 		}
 		if stage.Final {
 			// Final stage must be last, so by definition after Push stage.
@@ -1763,10 +2093,16 @@ func deduplicatePaths(paths []string) []string {
 	return deduped
 }
 
-func resolveExtraStageDigests(stages []config.KanikoStage, opts *config.KanikoOptions) (map[string]string, map[string]v1.Image, error) {
+// resolveExtraStageDigests resolves the images COPY --from reads, by their
+// util.DependencyKey.
+func resolveExtraStageDigests(ctx context.Context, stages []config.KanikoStage, opts *config.KanikoOptions) (map[string]string, map[string]v1.Image, error) {
 	t := timing.Start("Resolving Extra Stage Digests")
 	defer t.End()
 
+	policy, err := dockerfile.LoadBaseImagePolicy(opts.BaseImagePolicy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading --base-image-policy: %w", err)
+	}
 	externalImageDigests := make(map[string]string)
 	images := make(map[string]v1.Image)
 	for _, s := range stages {
@@ -1787,27 +2123,78 @@ func resolveExtraStageDigests(stages []config.KanikoStage, opts *config.KanikoOp
 				continue
 			}
 
-			if _, ok := images[c.From]; ok {
+			// stages built for other platforms read the image pulled for theirs
+			platform := stagePlatform(s, opts)
+			key := util.DependencyKey(c.From, platform)
+			if _, ok := images[key]; ok {
 				continue
 			}
+			if err := policy.CheckSource("COPY --from", c.Location(), c.From, opts.BuildContexts); err != nil {
+				return nil, nil, err
+			}
 
-			// This must be an image name, fetch its manifest.
-			logrus.Debugf("Found extra base image stage %s", c.From)
-			sourceImage, err := remote.RetrieveRemoteImage(c.From, opts.RegistryOptions, opts.CustomPlatform)
+			var sourceImage v1.Image
+			var err error
+			if source, ok := opts.BuildContexts[c.From]; ok {
+				// Directory contexts are read in place, only images are fetched.
+				if !buildcontext.IsImage(source) {
+					continue
+				}
+				logrus.Debugf("Found extra build context %s", c.From)
+				sourceImage, err = image_util.RetrieveContextImage(ctx, source, platform, opts)
+			} else {
+				// This must be an image name, fetch its manifest.
+				logrus.Debugf("Found extra base image stage %s", c.From)
+				sourceImage, err = remote.RetrieveRemoteImage(ctx, c.From, opts.RegistryOptions, platform)
+				if err == nil {
+					err = image_util.VerifyBaseImage(ctx, c.From, sourceImage, opts)
+				}
+			}
 			if err != nil {
 				return nil, nil, err
 			}
+			if err := policy.CheckCreated("COPY --from", c.Location(), c.From, opts.BuildContexts, sourceImage); err != nil {
+				return nil, nil, err
+			}
 			digest, err := sourceImage.Digest()
 			if err != nil {
 				return nil, nil, err
 			}
-			externalImageDigests[c.From] = digest.String()
-			images[c.From] = sourceImage
+			externalImageDigests[key] = digest.String()
+			images[key] = sourceImage
 		}
 	}
 	return externalImageDigests, images, nil
 }
 
+// stagePlatform returns the platform a stage is built for, its FROM --platform
+// or else the --custom-platform.
+func stagePlatform(stage config.KanikoStage, opts *config.KanikoOptions) string {
+	if stage.Platform != "" {
+		return stage.Platform
+	}
+	return opts.CustomPlatform
+}
+
+// stageFileContext is fileContext for the commands of stage, a COPY
+// --from=<image> reads the image pulled for the stage's platform.
+func stageFileContext(fileContext util.FileContext, stage config.KanikoStage, opts *config.KanikoOptions) util.FileContext {
+	fileContext.Platform = stagePlatform(stage, opts)
+	return fileContext
+}
+
+// directoryContexts returns the named build contexts that are directories,
+// by then every such context has been unpacked to a local path.
+func directoryContexts(contexts map[string]string) map[string]string {
+	dirs := make(map[string]string)
+	for name, source := range contexts {
+		if !buildcontext.IsImage(source) {
+			dirs[name] = source
+		}
+	}
+	return dirs
+}
+
 func downloadExtraStages(images map[string]v1.Image, externalImageDigests map[string]string, sharedRemote map[string]bool) error {
 	t := timing.Start("Fetching Extra Stages")
 	defer t.End()
@@ -1914,7 +2301,7 @@ func sharedRemoteImages(stages []config.KanikoStage, externalImageDigests map[st
 	return shared
 }
 
-func retrieveBaseImage(stage config.KanikoStage, opts *config.KanikoOptions, shared bool) (v1.Image, error) {
+func retrieveBaseImage(ctx context.Context, stage config.KanikoStage, opts *config.KanikoOptions, shared bool) (v1.Image, error) {
 	if shared {
 		stored, err := loadSharedBase(stage.BaseImageDigest)
 		if err == nil {
@@ -1922,7 +2309,7 @@ func retrieveBaseImage(stage config.KanikoStage, opts *config.KanikoOptions, sha
 			return stored, nil
 		}
 	}
-	img, err := image_util.RetrieveSourceImage(stage, opts)
+	img, err := image_util.RetrieveSourceImage(ctx, stage, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -19,19 +19,26 @@ package executor
 import (
 	"archive/tar"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/containerd/platforms"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 	"github.com/osscontainertools/kaniko/pkg/cache"
@@ -340,7 +347,7 @@ COPY --from=second /bar /bat
 			defer func() {
 				dockerfile.GetRemoteOnBuild = original
 			}()
-			dockerfile.GetRemoteOnBuild = func(baseName string, _ []instructions.ArgCommand, _ *config.KanikoOptions) ([]string, string, error) {
+			dockerfile.GetRemoteOnBuild = func(_ context.Context, baseName, _ string, _ []instructions.ArgCommand, _ *config.KanikoOptions) ([]string, string, error) {
 				switch baseName {
 				case "alpine":
 					// if image is "alpine" then add ONBUILD to its config
@@ -361,11 +368,11 @@ COPY --from=second /bar /bat
 				t.Errorf("Failed to parse test dockerfile to stages: %s", err)
 			}
 
-			kanikoStages, err := dockerfile.MakeKanikoStages(opts, testStages, metaArgs)
+			kanikoStages, err := dockerfile.MakeKanikoStages(context.Background(), opts, testStages, metaArgs)
 			if err != nil {
 				t.Errorf("Failed to parse stages to Kaniko Stages: %s", err)
 			}
-			got, err := CalculateDependencies(kanikoStages, opts)
+			got, err := CalculateDependencies(context.Background(), kanikoStages, opts)
 			if err != nil {
 				t.Errorf("got error: %s,", err)
 			}
@@ -851,7 +858,7 @@ func Test_stageBuilder_populateCompositeKey(t *testing.T) {
 			}
 
 			fc := util.FileContext{Root: "workspace"}
-			dockerCommand1, err := commands.GetCommand(instructions1[0], fc, config.SecretOptions{}, false, true, true)
+			dockerCommand1, err := commands.GetCommand(context.Background(), instructions1[0], fc, config.SecretOptions{}, "", false, true, true)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -861,7 +868,7 @@ func Test_stageBuilder_populateCompositeKey(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			dockerCommand2, err := commands.GetCommand(instructions[0], fc, config.SecretOptions{}, false, true, true)
+			dockerCommand2, err := commands.GetCommand(context.Background(), instructions[0], fc, config.SecretOptions{}, "", false, true, true)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -1049,7 +1056,7 @@ func Test_stageBuilder_build(t *testing.T) {
 				t.Errorf("Failed to parse test dockerfile to stages: %s", err)
 			}
 
-			kanikoStages, err := dockerfile.MakeKanikoStages(opts, testStages, metaArgs)
+			kanikoStages, err := dockerfile.MakeKanikoStages(context.Background(), opts, testStages, metaArgs)
 			if err != nil {
 				t.Errorf("Failed to parse stages to Kaniko Stages: %s", err)
 			}
@@ -1115,7 +1122,7 @@ COPY %s foo.txt
 				t.Errorf("Failed to parse test dockerfile to stages: %s", err)
 			}
 
-			kanikoStages, err := dockerfile.MakeKanikoStages(opts, testStages, metaArgs)
+			kanikoStages, err := dockerfile.MakeKanikoStages(context.Background(), opts, testStages, metaArgs)
 			if err != nil {
 				t.Errorf("Failed to parse stages to Kaniko Stages: %s", err)
 			}
@@ -1191,7 +1198,7 @@ COPY %s bar.txt
 				t.Errorf("Failed to parse test dockerfile to stages: %s", err)
 			}
 
-			kanikoStages, err := dockerfile.MakeKanikoStages(opts, testStages, metaArgs)
+			kanikoStages, err := dockerfile.MakeKanikoStages(context.Background(), opts, testStages, metaArgs)
 			if err != nil {
 				t.Errorf("Failed to parse stages to Kaniko Stages: %s", err)
 			}
@@ -1264,7 +1271,7 @@ RUN foobar
 				t.Errorf("Failed to parse test dockerfile to stages: %s", err)
 			}
 
-			kanikoStages, err := dockerfile.MakeKanikoStages(opts, testStages, metaArgs)
+			kanikoStages, err := dockerfile.MakeKanikoStages(context.Background(), opts, testStages, metaArgs)
 			if err != nil {
 				t.Errorf("Failed to parse stages to Kaniko Stages: %s", err)
 			}
@@ -1528,9 +1535,11 @@ func getCommands(fileContext util.FileContext, cmds []instructions.Command, cach
 	outCommands := make([]commands.DockerCommand, 0)
 	for _, c := range cmds {
 		cmd, err := commands.GetCommand(
+			context.Background(),
 			c,
 			fileContext,
 			config.SecretOptions{},
+			"",
 			false,
 			cacheCopy,
 			cacheRun,
@@ -1629,7 +1638,7 @@ func Test_stageBuild_populateCompositeKeyForCopyCommand(t *testing.T) {
 			}
 
 			fc := util.FileContext{Root: "workspace"}
-			copyCommand, err := commands.GetCommand(instructions[0], fc, config.SecretOptions{}, false, true, true)
+			copyCommand, err := commands.GetCommand(context.Background(), instructions[0], fc, config.SecretOptions{}, "", false, true, true)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -1670,6 +1679,99 @@ func Test_stageBuild_populateCompositeKeyForCopyCommand(t *testing.T) {
 	}
 }
 
+func Test_stageBuild_populateCompositeKeyForCopyParents(t *testing.T) {
+	dir := t.TempDir()
+	testutil.CheckNoError(t, testutil.SetupFiles(dir, map[string]string{
+		"a/package.json": "same",
+		"b/package.json": "same",
+	}))
+	fc := util.FileContext{Root: dir}
+
+	key := func(command, file string) string {
+		t.Helper()
+		instructions, err := dockerfile.ParseCommands([]string{command})
+		testutil.CheckNoError(t, err)
+		cmd, err := commands.GetCommand(context.Background(), instructions[0], fc, config.SecretOptions{}, "", false, true, true)
+		testutil.CheckNoError(t, err)
+		ck, err := populateCompositeKey(cmd, []string{filepath.Join(dir, file)}, CompositeCache{}, dockerfile.NewBuildArgs([]string{}), []string{}, fc, nil, nil)
+		testutil.CheckNoError(t, err)
+		k, err := ck.Hash()
+		testutil.CheckNoError(t, err)
+		return k
+	}
+
+	// the flattened copies are identical, the ones with parents are not
+	if key("COPY */package.json /app/", "a/package.json") != key("COPY */package.json /app/", "b/package.json") {
+		t.Error("expected the same key for files with the same content")
+	}
+	if key("COPY --parents */package.json /app/", "a/package.json") == key("COPY --parents */package.json /app/", "b/package.json") {
+		t.Error("expected the source path in the key of COPY --parents")
+	}
+}
+
+func Test_stageBuild_populateLinkKey(t *testing.T) {
+	dir := t.TempDir()
+	testutil.CheckNoError(t, testutil.SetupFiles(dir, map[string]string{
+		"app/main.go": "main",
+	}))
+	fc := util.FileContext{Root: dir}
+	instructions, err := dockerfile.ParseCommands([]string{"COPY --link app /app"})
+	testutil.CheckNoError(t, err)
+	cmd, err := commands.GetCommand(context.Background(), instructions[0], fc, config.SecretOptions{}, "", false, true, true)
+	testutil.CheckNoError(t, err)
+	if !isLink(cmd, &config.KanikoOptions{}) || isLink(cmd, &config.KanikoOptions{SingleSnapshot: true}) {
+		t.Error("expected COPY --link to build its layer in isolation outside of single snapshot mode")
+	}
+
+	keys := func(base string) (string, string) {
+		t.Helper()
+		ck, lk, err := populateLinkKey(cmd, []string{filepath.Join(dir, "app/main.go")}, *NewCompositeCache(base), dockerfile.NewBuildArgs([]string{}), &v1.Config{}, fc, nil)
+		testutil.CheckNoError(t, err)
+		k, err := ck.Hash()
+		testutil.CheckNoError(t, err)
+		return k, lk
+	}
+
+	// the layer key ignores the base, the running key does not
+	ck1, lk1 := keys("sha256:base1")
+	ck2, lk2 := keys("sha256:base2")
+	testutil.CheckDeepEqual(t, lk1, lk2)
+	if ck1 == ck2 {
+		t.Error("expected the running key to depend on the base")
+	}
+
+	// but it depends on where and as whom the files are copied
+	linkKey := func(instruction string, cfg *v1.Config) string {
+		t.Helper()
+		instructions, err := dockerfile.ParseCommands([]string{instruction})
+		testutil.CheckNoError(t, err)
+		cmd, err := commands.GetCommand(context.Background(), instructions[0], fc, config.SecretOptions{}, "", false, true, true)
+		testutil.CheckNoError(t, err)
+		_, lk, err := populateLinkKey(cmd, []string{filepath.Join(dir, "app/main.go")}, *NewCompositeCache("sha256:base"), dockerfile.NewBuildArgs([]string{}), cfg, fc, nil)
+		testutil.CheckNoError(t, err)
+		return lk
+	}
+	relative := linkKey("COPY --link app .", &v1.Config{WorkingDir: "/src"})
+	if relative == linkKey("COPY --link app .", &v1.Config{WorkingDir: "/other"}) {
+		t.Error("expected a relative destination under another WORKDIR to change the link key")
+	}
+	if linkKey("COPY --link app /app", &v1.Config{Env: []string{"OWNER=1000"}}) == linkKey("COPY --link --chown=$OWNER app /app", &v1.Config{Env: []string{"OWNER=1000"}}) {
+		t.Error("expected --chown to change the link key")
+	}
+	if linkKey("COPY --link app /app", &v1.Config{User: "nobody"}) == lk1 {
+		t.Error("expected USER to change the link key")
+	}
+
+	// and on the epoch its files are dated to
+	epoch := linkEpoch(context.Background(), &config.KanikoOptions{Reproducible: true})
+	testutil.CheckDeepEqual(t, int64(0), epoch.Unix())
+	_, lk, err := populateLinkKey(cmd, []string{filepath.Join(dir, "app/main.go")}, *NewCompositeCache("sha256:base1"), dockerfile.NewBuildArgs([]string{}), &v1.Config{}, fc, epoch)
+	testutil.CheckNoError(t, err)
+	if lk == lk1 {
+		t.Error("expected the epoch to change the link key")
+	}
+}
+
 func Test_stageBuilder_saveSnapshotToLayer(t *testing.T) {
 	dir, files := tempDirAndFile(t)
 	type fields struct {
@@ -1880,3 +1982,85 @@ func Test_stageBuilder_convertLayerMediaType(t *testing.T) {
 		})
 	}
 }
+
+func Test_saveLayerToImage_SourceDateEpoch(t *testing.T) {
+	layer, err := random.Layer(64, types.DockerLayer)
+	testutil.CheckNoError(t, err)
+
+	img, err := saveLayerToImage(context.Background(), empty.Image, layer, "RUN true", &config.KanikoOptions{})
+	testutil.CheckNoError(t, err)
+	cfg, err := img.ConfigFile()
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, true, cfg.History[0].Created.IsZero())
+
+	epoch := time.Unix(1700000000, 0).UTC()
+	ctx := config.WithSourceDateEpoch(context.Background(), &epoch)
+	img, err = saveLayerToImage(ctx, empty.Image, layer, "RUN true", &config.KanikoOptions{})
+	testutil.CheckNoError(t, err)
+	cfg, err = img.ConfigFile()
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, epoch, cfg.History[0].Created.Time)
+
+	// clamped layers must not be taken from the cache of unclamped builds
+	unclamped := newBaseCompositeCache(context.Background(), "sha256:base")
+	clamped := newBaseCompositeCache(ctx, "sha256:base")
+	unclampedKey, err := unclamped.Hash()
+	testutil.CheckNoError(t, err)
+	clampedKey, err := clamped.Hash()
+	testutil.CheckNoError(t, err)
+	if unclampedKey == clampedKey {
+		t.Errorf("cache key %s does not depend on %s", clampedKey, config.SourceDateEpochArg)
+	}
+}
+
+func Test_resolveExtraStageDigests_Platforms(t *testing.T) {
+	server := httptest.NewServer(newFakeRegistry())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	src := host + "/foo/tools:latest"
+
+	digests := map[string]string{}
+	idx := v1.ImageIndex(empty.Index)
+	for _, p := range []string{"linux/amd64", "linux/arm64"} {
+		img, err := random.Image(64, 1)
+		testutil.CheckNoError(t, err)
+		platform, err := v1.ParsePlatform(p)
+		testutil.CheckNoError(t, err)
+		cf, err := img.ConfigFile()
+		testutil.CheckNoError(t, err)
+		cf.OS, cf.Architecture = platform.OS, platform.Architecture
+		img, err = mutate.ConfigFile(img, cf)
+		testutil.CheckNoError(t, err)
+		digest, err := img.Digest()
+		testutil.CheckNoError(t, err)
+		digests[p] = digest.String()
+		idx = mutate.AppendManifests(idx, mutate.IndexAddendum{Add: img, Descriptor: v1.Descriptor{Platform: platform}})
+	}
+	ref, err := name.ParseReference(src, name.Insecure)
+	testutil.CheckNoError(t, err)
+	testutil.CheckNoError(t, remote.WriteIndex(ref, idx))
+
+	cmds, err := dockerfile.ParseCommands([]string{"COPY --from=" + src + " /bin/tool /bin/tool"})
+	testutil.CheckNoError(t, err)
+	stages := []config.KanikoStage{
+		{Index: 0, Platform: "linux/amd64", Commands: cmds},
+		{Index: 1, Platform: "linux/arm64", Commands: cmds},
+		{Index: 2, Platform: "linux/amd64", Commands: cmds},
+	}
+	opts := &config.KanikoOptions{}
+	opts.InsecureRegistries = []string{host}
+
+	externalImageDigests, images, err := resolveExtraStageDigests(context.Background(), stages, opts)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, map[string]string{
+		util.DependencyKey(src, "linux/amd64"): digests["linux/amd64"],
+		util.DependencyKey(src, "linux/arm64"): digests["linux/arm64"],
+	}, externalImageDigests)
+	testutil.CheckDeepEqual(t, 2, len(images))
+
+	// each stage reads the files of the image pulled for its platform
+	for _, p := range []string{"linux/amd64", "linux/arm64"} {
+		root := util.FileContext{Platform: p}.From(src).Root
+		testutil.CheckDeepEqual(t, filepath.Join(config.KanikoInterStageDepsDir, util.DependencyKey(src, p)), root)
+	}
+}
@@ -20,6 +20,7 @@ package executor
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"errors"
 	"io"
 
@@ -37,16 +38,16 @@ type fakeSnapShotter struct {
 	initialized bool
 }
 
-func (f *fakeSnapShotter) Init() error {
+func (f *fakeSnapShotter) Init(_ context.Context) error {
 	f.initialized = true
 	return nil
 }
 
-func (f *fakeSnapShotter) TakeSnapshotFS() (string, int, error) {
+func (f *fakeSnapShotter) TakeSnapshotFS(_ context.Context) (string, int, error) {
 	return f.tarPath, 0, nil
 }
 
-func (f *fakeSnapShotter) TakeSnapshot(_ []string, _ bool) (string, int, error) {
+func (f *fakeSnapShotter) TakeSnapshot(_ context.Context, _ []string, _ bool) (string, int, error) {
 	return f.tarPath, 0, nil
 }
 
@@ -0,0 +1,130 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/osscontainertools/kaniko/pkg/constants"
+	image_util "github.com/osscontainertools/kaniko/pkg/image"
+	"github.com/osscontainertools/kaniko/pkg/util"
+)
+
+// withBuildMetadata is the image Build returns carrying what Build knows of
+// it that the image itself does not tell, for the --metadata-file Push
+// writes. Like image_util.WithReferrers it is still the image as far as
+// digests, manifests and layers go.
+type withBuildMetadata struct {
+	v1.Image
+	// baseDigests maps the base and COPY --from images to their digests
+	baseDigests map[string]string
+	cacheKey    string
+}
+
+// Referrers passes on the artifacts attached to the image.
+func (w *withBuildMetadata) Referrers() []v1.Image {
+	return image_util.Referrers(w.Image)
+}
+
+// metadataFile is the --metadata-file, named after the keys of
+// `docker buildx build --metadata-file` with kaniko's own additions.
+type metadataFile struct {
+	Digest       string            `json:"containerimage.digest"`
+	ConfigDigest string            `json:"containerimage.config.digest"`
+	Descriptor   v1.Descriptor     `json:"containerimage.descriptor"`
+	ImageName    string            `json:"image.name,omitempty"`
+	BaseDigests  map[string]string `json:"kaniko.base.digests,omitempty"`
+	CacheKey     string            `json:"kaniko.cache.key,omitempty"`
+}
+
+// recordBuildMetadata returns image carrying its metadata, built on the
+// images in stageBases and externalImageDigests with the final cache key
+// cacheKey.
+func recordBuildMetadata(image v1.Image, stageBases map[int]imageBase, externalImageDigests map[string]string, cacheKey string, opts *config.KanikoOptions) v1.Image {
+	m := &withBuildMetadata{Image: image, baseDigests: map[string]string{}, cacheKey: cacheKey}
+	for _, base := range stageBases {
+		if base.name != "" {
+			m.baseDigests[base.name] = base.digest
+		}
+	}
+	for key, digest := range externalImageDigests {
+		from, _ := util.SplitDependencyKey(key)
+		// a build context stands in for the image it names
+		if source, ok := opts.BuildContexts[from]; ok {
+			ref, isRef := strings.CutPrefix(source, constants.DockerImageContextPrefix)
+			if !isRef {
+				continue
+			}
+			from = ref
+		}
+		m.baseDigests[from] = digest
+	}
+	return m
+}
+
+// writeMetadataFile writes the metadata of image, pushed to destRefs, to
+// path. What Build recorded is included if image is the one it returned.
+func writeMetadataFile(path string, image v1.Image, destRefs []name.Tag) error {
+	digest, err := image.Digest()
+	if err != nil {
+		return err
+	}
+	configDigest, err := image.ConfigName()
+	if err != nil {
+		return err
+	}
+	mediaType, err := image.MediaType()
+	if err != nil {
+		return err
+	}
+	size, err := image.Size()
+	if err != nil {
+		return err
+	}
+	manifest, err := image.Manifest()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, destRef := range destRefs {
+		names = append(names, destRef.Name())
+	}
+	m := metadataFile{
+		Digest:       digest.String(),
+		ConfigDigest: configDigest.String(),
+		Descriptor: v1.Descriptor{
+			MediaType:   mediaType,
+			Digest:      digest,
+			Size:        size,
+			Annotations: manifest.Annotations,
+		},
+		ImageName: strings.Join(names, ","),
+	}
+	if w, ok := image.(*withBuildMetadata); ok {
+		m.BaseDigests = w.baseDigests
+		m.CacheKey = w.cacheKey
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeDigestFile(path, append(b, '\n'))
+}
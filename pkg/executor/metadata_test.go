@@ -0,0 +1,100 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	image_util "github.com/osscontainertools/kaniko/pkg/image"
+	"github.com/osscontainertools/kaniko/testutil"
+)
+
+func TestWriteMetadataFile(t *testing.T) {
+	img, err := random.Image(64, 1)
+	testutil.CheckNoError(t, err)
+	img = mutate.Annotations(img, map[string]string{ociRevision: "abc"}).(v1.Image)
+	digest, err := img.Digest()
+	testutil.CheckNoError(t, err)
+	configDigest, err := img.ConfigName()
+	testutil.CheckNoError(t, err)
+	size, err := img.Size()
+	testutil.CheckNoError(t, err)
+	other, err := random.Image(64, 1)
+	testutil.CheckNoError(t, err)
+	var destRefs []name.Tag
+	for _, dest := range []string{"gcr.io/foo/bar:1.0", "gcr.io/foo/bar:latest"} {
+		ref, err := name.NewTag(dest)
+		testutil.CheckNoError(t, err)
+		destRefs = append(destRefs, ref)
+	}
+
+	opts := &config.KanikoOptions{BuildContexts: map[string]string{"tools": "docker-image://busybox:1.36", "src": "/workspace/src"}}
+	stageBases := map[int]imageBase{
+		0: {name: "golang:1.22", digest: "sha256:1111111111111111111111111111111111111111111111111111111111111111"},
+		1: {name: "golang:1.22", digest: "sha256:1111111111111111111111111111111111111111111111111111111111111111"},
+		2: {},
+	}
+	externalImageDigests := map[string]string{
+		"tools":    "sha256:2222222222222222222222222222222222222222222222222222222222222222",
+		"src":      "sha256:3333333333333333333333333333333333333333333333333333333333333333",
+		"alpine:3": "sha256:4444444444444444444444444444444444444444444444444444444444444444",
+	}
+	built := recordBuildMetadata(image_util.WithReferrers(img, other), stageBases, externalImageDigests, "cachekey", opts)
+	testutil.CheckDeepEqual(t, 1, len(image_util.Referrers(built)))
+
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	testutil.CheckNoError(t, writeMetadataFile(path, built, destRefs))
+	b, err := os.ReadFile(path)
+	testutil.CheckNoError(t, err)
+	var got map[string]any
+	testutil.CheckNoError(t, json.Unmarshal(b, &got))
+	testutil.CheckDeepEqual(t, map[string]any{
+		"containerimage.digest":        digest.String(),
+		"containerimage.config.digest": configDigest.String(),
+		"containerimage.descriptor": map[string]any{
+			"mediaType":   "application/vnd.docker.distribution.manifest.v2+json",
+			"digest":      digest.String(),
+			"size":        float64(size),
+			"annotations": map[string]any{ociRevision: "abc"},
+		},
+		"image.name": "gcr.io/foo/bar:1.0,gcr.io/foo/bar:latest",
+		"kaniko.base.digests": map[string]any{
+			"golang:1.22":  "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+			"busybox:1.36": "sha256:2222222222222222222222222222222222222222222222222222222222222222",
+			"alpine:3":     "sha256:4444444444444444444444444444444444444444444444444444444444444444",
+		},
+		"kaniko.cache.key": "cachekey",
+	}, got)
+
+	// an image Build did not return, as pushed by executor push
+	testutil.CheckNoError(t, writeMetadataFile(path, other, nil))
+	b, err = os.ReadFile(path)
+	testutil.CheckNoError(t, err)
+	got = nil
+	testutil.CheckNoError(t, json.Unmarshal(b, &got))
+	_, hasCacheKey := got["kaniko.cache.key"]
+	_, hasName := got["image.name"]
+	testutil.CheckDeepEqual(t, false, hasCacheKey || hasName)
+}
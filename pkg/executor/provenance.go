@@ -0,0 +1,192 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/osscontainertools/kaniko/pkg/constants"
+	image_util "github.com/osscontainertools/kaniko/pkg/image"
+	"github.com/osscontainertools/kaniko/pkg/logging"
+	"github.com/osscontainertools/kaniko/pkg/provenance"
+	"github.com/osscontainertools/kaniko/pkg/util"
+	"github.com/osscontainertools/kaniko/pkg/version"
+)
+
+// provenanceRecorder holds what the provenance statement records about the
+// inputs of a build, taken before stages the cache serves are dropped.
+type provenanceRecorder struct {
+	started      time.Time
+	dependencies []provenance.ResourceDescriptor
+	stages       []provenance.Stage
+}
+
+func newProvenanceRecorder(opts *config.KanikoOptions, dockerfile []byte, stages []config.KanikoStage, externalImageDigests map[string]string) (*provenanceRecorder, error) {
+	p := &provenanceRecorder{started: time.Now()}
+	if opts.ContextSource.URI != "" {
+		p.dependencies = append(p.dependencies, provenance.ResourceDescriptor{
+			URI:    opts.ContextSource.URI,
+			Digest: opts.ContextSource.Digest,
+		})
+	}
+	sum := sha256.Sum256(dockerfile)
+	p.dependencies = append(p.dependencies, provenance.ResourceDescriptor{
+		Name:   "dockerfile",
+		Digest: map[string]string{"sha256": hex.EncodeToString(sum[:])},
+	})
+	for _, s := range stages {
+		platform := stagePlatform(s, opts)
+		p.stages = append(p.stages, provenance.Stage{
+			Name:     stageName(s),
+			Base:     s.BaseName,
+			Platform: platform,
+			Digest:   s.BaseImageDigest,
+		})
+		if s.BaseImageStoredLocally || s.BaseImageDigest == "" || s.BaseName == constants.NoBaseImage {
+			continue
+		}
+		p.addImage(s.BaseName, platform, s.BaseImageDigest)
+	}
+	for _, key := range slices.Sorted(maps.Keys(externalImageDigests)) {
+		ref, platform := util.SplitDependencyKey(key)
+		p.addImage(ref, platform, externalImageDigests[key])
+	}
+	return p, nil
+}
+
+// credentialWords mark the names of build args that likely hold credentials.
+var credentialWords = []string{"TOKEN", "PASSWORD", "PASSWD", "SECRET", "KEY", "CREDENTIAL"}
+
+// credentialArg reports whether the build arg name looks like it holds a
+// credential, its value is then left out of the provenance.
+func credentialArg(name string) bool {
+	name = strings.ToUpper(name)
+	return slices.ContainsFunc(credentialWords, func(w string) bool { return strings.Contains(name, w) })
+}
+
+// addImage records an image the build read, once however many stages use it.
+func (p *provenanceRecorder) addImage(ref, platform, digest string) {
+	d := provenance.ResourceDescriptor{
+		URI:    provenance.ImageURI(ref, platform),
+		Digest: provenance.Digest(digest),
+	}
+	for _, dep := range p.dependencies {
+		if dep.URI == d.URI {
+			return
+		}
+	}
+	p.dependencies = append(p.dependencies, d)
+}
+
+// attach builds the statement for img and attaches it as a referrer.
+func (p *provenanceRecorder) attach(img v1.Image, opts *config.KanikoOptions) (v1.Image, error) {
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+	var subjects []provenance.ResourceDescriptor
+	for _, dest := range opts.Destinations {
+		ref, err := name.NewTag(dest, name.WeakValidation)
+		if err != nil {
+			return nil, fmt.Errorf("getting tag for destination: %w", err)
+		}
+		if !slices.ContainsFunc(subjects, func(s provenance.ResourceDescriptor) bool { return s.Name == ref.Context().Name() }) {
+			subjects = append(subjects, provenance.ResourceDescriptor{Name: ref.Context().Name(), Digest: provenance.Digest(digest.String())})
+		}
+	}
+	if len(subjects) == 0 {
+		subjects = append(subjects, provenance.ResourceDescriptor{Digest: provenance.Digest(digest.String())})
+	}
+
+	ext := provenance.ExternalParameters{
+		Context:    opts.ContextSource.URI,
+		Dockerfile: dockerfileName(opts),
+		Target:     opts.Target,
+		Platform:   opts.CustomPlatform,
+	}
+	internal := provenance.InternalParameters{
+		FeatureFlags: config.ActiveFeatureFlags(),
+	}
+	if opts.Provenance == config.ProvenanceMax {
+		ext.BuildArgs = map[string]string{}
+		for _, arg := range opts.BuildArgs {
+			k, v, _ := strings.Cut(arg, "=")
+			if credentialArg(k) {
+				v = logging.Redacted
+			}
+			ext.BuildArgs[k] = logging.Redact(v)
+		}
+		internal.Stages = p.stages
+	}
+	run := provenance.RunDetails{
+		Builder: provenance.Builder{
+			ID:      provenance.BuilderID,
+			Version: map[string]string{"kaniko": version.Version()},
+		},
+	}
+	// timestamps would make the statement differ between otherwise identical builds
+	if !opts.Reproducible {
+		run.Metadata = &provenance.Metadata{
+			StartedOn:  p.started.UTC().Format(time.RFC3339),
+			FinishedOn: time.Now().UTC().Format(time.RFC3339),
+		}
+	}
+	statement := provenance.Statement{
+		Type:          provenance.StatementType,
+		Subject:       subjects,
+		PredicateType: provenance.PredicateType,
+		Predicate: provenance.Provenance{
+			BuildDefinition: provenance.BuildDefinition{
+				BuildType:            provenance.BuildType,
+				ExternalParameters:   ext,
+				InternalParameters:   internal,
+				ResolvedDependencies: p.dependencies,
+			},
+			RunDetails: run,
+		},
+	}
+	doc, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	referrer, err := image_util.NewReferrer(img, provenance.MediaType, doc, map[string]string{
+		provenance.PredicateTypeAnnotation: provenance.PredicateType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return image_util.WithReferrers(img, referrer), nil
+}
+
+// dockerfileName is the Dockerfile as the user gave it, "Dockerfile" when it
+// was passed inline or on stdin.
+func dockerfileName(opts *config.KanikoOptions) string {
+	if opts.DockerfileName != "" {
+		return opts.DockerfileName
+	}
+	return "Dockerfile"
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	image_util "github.com/osscontainertools/kaniko/pkg/image"
+	"github.com/osscontainertools/kaniko/pkg/logging"
+	"github.com/osscontainertools/kaniko/pkg/provenance"
+	"github.com/osscontainertools/kaniko/testutil"
+)
+
+func TestProvenance(t *testing.T) {
+	const baseDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	dir := t.TempDir()
+	logging.RegisterSecret("hunter22")
+	t.Cleanup(logging.ResetSecrets)
+
+	stages := []config.KanikoStage{
+		{Name: "build", BaseName: "debian:bookworm", BaseImageDigest: baseDigest, Platform: "linux/arm64"},
+		{BaseName: "build", BaseImageStoredLocally: true, Index: 1, Final: true, Push: true},
+	}
+	external := map[string]string{"busybox:1.36": baseDigest}
+
+	for _, mode := range []config.ProvenanceMode{config.ProvenanceMin, config.ProvenanceMax} {
+		t.Run(string(mode), func(t *testing.T) {
+			opts := &config.KanikoOptions{
+				Destinations:   []string{"gcr.io/foo/bar:1", "gcr.io/foo/bar:latest"},
+				BuildArgs:      []string{"VERSION=1.2", "TOKEN=hunter22", "NPM_TOKEN=npm_abc", "aws_secret_access_key=wJalr", "DEPLOY_KEY=abc"},
+				SrcContext:     dir,
+				DockerfilePath: "/kaniko/Dockerfile",
+				DockerfileName: "docker/Dockerfile",
+				CustomPlatform: "linux/amd64",
+				Provenance:     mode,
+				Reproducible:   true,
+				ContextSource: config.ContextSource{
+					URI:    "git+https://github.com/foo/bar.git@refs/heads/main",
+					Digest: map[string]string{"gitCommit": "0123456789abcdef0123456789abcdef01234567"},
+				},
+			}
+			p, err := newProvenanceRecorder(opts, []byte("FROM debian:bookworm\n"), stages, external)
+			testutil.CheckNoError(t, err)
+			img, err := random.Image(1024, 1)
+			testutil.CheckNoError(t, err)
+			img, err = p.attach(img, opts)
+			testutil.CheckNoError(t, err)
+
+			referrers := image_util.Referrers(img)
+			testutil.CheckDeepEqual(t, 1, len(referrers))
+			m, err := referrers[0].Manifest()
+			testutil.CheckNoError(t, err)
+			testutil.CheckDeepEqual(t, provenance.PredicateType, m.Annotations[provenance.PredicateTypeAnnotation])
+			layers, err := referrers[0].Layers()
+			testutil.CheckNoError(t, err)
+			rc, err := layers[0].Compressed()
+			testutil.CheckNoError(t, err)
+			defer rc.Close()
+			b, err := io.ReadAll(rc)
+			testutil.CheckNoError(t, err)
+			var st provenance.Statement
+			testutil.CheckNoError(t, json.Unmarshal(b, &st))
+
+			digest, err := img.Digest()
+			testutil.CheckNoError(t, err)
+			testutil.CheckDeepEqual(t, []provenance.ResourceDescriptor{{Name: "gcr.io/foo/bar", Digest: map[string]string{"sha256": digest.Hex}}}, st.Subject)
+			def := st.Predicate.BuildDefinition
+			testutil.CheckDeepEqual(t, "docker/Dockerfile", def.ExternalParameters.Dockerfile)
+			testutil.CheckDeepEqual(t, opts.ContextSource.URI, def.ResolvedDependencies[0].URI)
+			testutil.CheckDeepEqual(t, "dockerfile", def.ResolvedDependencies[1].Name)
+			// sha256 of "FROM debian:bookworm\n", not of the file at DockerfilePath
+			testutil.CheckDeepEqual(t, map[string]string{"sha256": "b40b243fd2eeec9a8293d97005f544e35bd2e11ac388da264ce2c936ec6b9601"}, def.ResolvedDependencies[1].Digest)
+			testutil.CheckDeepEqual(t, "pkg:docker/debian@bookworm?platform=linux%2Farm64", def.ResolvedDependencies[2].URI)
+			testutil.CheckDeepEqual(t, "pkg:docker/busybox@1.36", def.ResolvedDependencies[3].URI)
+			testutil.CheckDeepEqual(t, 4, len(def.ResolvedDependencies))
+			testutil.CheckDeepEqual(t, (*provenance.Metadata)(nil), st.Predicate.RunDetails.Metadata)
+			if mode == config.ProvenanceMin {
+				testutil.CheckDeepEqual(t, map[string]string(nil), def.ExternalParameters.BuildArgs)
+				testutil.CheckDeepEqual(t, 0, len(def.InternalParameters.Stages))
+			} else {
+				testutil.CheckDeepEqual(t, map[string]string{
+					"VERSION":               "1.2",
+					"TOKEN":                 logging.Redacted,
+					"NPM_TOKEN":             logging.Redacted,
+					"aws_secret_access_key": logging.Redacted,
+					"DEPLOY_KEY":            logging.Redacted,
+				}, def.ExternalParameters.BuildArgs)
+				testutil.CheckDeepEqual(t, []provenance.Stage{
+					{Name: "build", Base: "debian:bookworm", Platform: "linux/arm64", Digest: baseDigest},
+					{Name: "stage-1", Base: "build", Platform: "linux/amd64"},
+				}, def.InternalParameters.Stages)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ package executor
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -42,7 +43,9 @@ import (
 	"github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/pkg/constants"
 	"github.com/osscontainertools/kaniko/pkg/creds"
+	image_util "github.com/osscontainertools/kaniko/pkg/image"
 	"github.com/osscontainertools/kaniko/pkg/mounts"
+	"github.com/osscontainertools/kaniko/pkg/signing"
 	"github.com/osscontainertools/kaniko/pkg/timing"
 	"github.com/osscontainertools/kaniko/pkg/util"
 	"github.com/osscontainertools/kaniko/pkg/version"
@@ -195,6 +198,11 @@ func writeDigestFile(path string, digestByteArray []byte) error {
 // A dummy destination would be set when --no-push is set to true and --tar-path
 // is not empty with empty --destinations.
 func DoPush(image v1.Image, opts *config.KanikoOptions) error {
+	return Push(context.Background(), image, opts)
+}
+
+// Push is DoPush with a context; cancelling ctx aborts in-flight uploads.
+func Push(ctx context.Context, image v1.Image, opts *config.KanikoOptions) error {
 	assert.Assert("executor.push.image-nonnull", image != nil, "DoPush called with nil image")
 
 	t := timing.Start("Total Push Time")
@@ -230,6 +238,11 @@ func DoPush(image v1.Image, opts *config.KanikoOptions) error {
 		if err := path.AppendImage(image); err != nil {
 			return fmt.Errorf("appending image: %w", err)
 		}
+		for _, referrer := range image_util.Referrers(image) {
+			if err := path.AppendImage(referrer); err != nil {
+				return fmt.Errorf("appending referrer: %w", err)
+			}
+		}
 	}
 
 	if opts.NoPush && len(opts.Destinations) == 0 {
@@ -270,6 +283,12 @@ func DoPush(image v1.Image, opts *config.KanikoOptions) error {
 		}
 	}
 
+	if opts.MetadataFile != "" {
+		if err := writeMetadataFile(opts.MetadataFile, image, destRefs); err != nil {
+			return fmt.Errorf("writing metadata file failed: %w", err)
+		}
+	}
+
 	if opts.TarPath != "" {
 		tagToImage := map[name.Tag]v1.Image{}
 
@@ -287,28 +306,27 @@ func DoPush(image v1.Image, opts *config.KanikoOptions) error {
 		return nil
 	}
 
-	// continue pushing unless an error occurs
-	for _, destRef := range destRefs {
-		registryName := destRef.Registry.Name()
-		if opts.Insecure || opts.InsecureRegistries.Contains(registryName) {
-			newReg, err := name.NewRegistry(registryName, name.WeakValidation, name.Insecure)
-			if err != nil {
-				return fmt.Errorf("getting new insecure registry: %w", err)
-			}
-			destRef.Registry = newReg
+	var signer *signing.Signer
+	if opts.SignKey != "" {
+		var err error
+		signer, err = signing.LoadSigner(opts.SignKey, os.Getenv(signing.PasswordEnv))
+		if err != nil {
+			return fmt.Errorf("loading signing key: %w", err)
 		}
+	}
 
-		pushAuth, err := creds.GetKeychain(&opts.RegistryOptions).Resolve(destRef.Context())
+	// continue pushing unless an error occurs
+	attached := map[string]bool{}
+	for _, destRef := range destRefs {
+		var err error
+		destRef.Registry, err = pushRegistry(destRef.Registry, opts)
 		if err != nil {
-			return fmt.Errorf("resolving pushAuth: %w", err)
+			return err
 		}
-
-		localRt, err := util.MakeTransport(opts.RegistryOptions, registryName)
+		pushAuth, rt, err := pushAccess(destRef.Context(), opts)
 		if err != nil {
-			return fmt.Errorf("making transport for registry %q: %w", registryName, err)
+			return err
 		}
-		tr := newRetry(localRt)
-		rt := &withUserAgent{t: tr}
 
 		logrus.Infof("Pushing image to %s", destRef.String())
 		pushImage := image
@@ -322,10 +340,10 @@ func DoPush(image v1.Image, opts *config.KanikoOptions) error {
 				return err
 			}
 			digest := destRef.Context().Digest(dig.String())
-			err = remote.Write(destRef, pushImage, remote.WithAuth(pushAuth), remote.WithTransport(rt))
+			err = remote.Write(destRef, pushImage, remote.WithAuth(pushAuth), remote.WithTransport(rt), remote.WithContext(ctx))
 			if err != nil && config.FF.CrossRepoMount {
 				logrus.Debugf("Cross-repository mount failed; retrying plain blob upload: %v", err)
-				err = remote.Write(destRef, image, remote.WithAuth(pushAuth), remote.WithTransport(rt))
+				err = remote.Write(destRef, image, remote.WithAuth(pushAuth), remote.WithTransport(rt), remote.WithContext(ctx))
 			}
 			if err != nil {
 				if !opts.PushIgnoreImmutableTagErrors {
@@ -353,10 +371,114 @@ func DoPush(image v1.Image, opts *config.KanikoOptions) error {
 		if err := util.Retry(retryFunc, opts.PushRetry, 1000); err != nil {
 			return fmt.Errorf("failed to push to destination %s: %w", destRef, err)
 		}
+
+		// referrers and signatures are addressed by digest, tags of the same
+		// repository share them
+		if attached[destRef.Context().Name()] {
+			continue
+		}
+		attached[destRef.Context().Name()] = true
+		referrers := image_util.Referrers(image)
+		for _, referrer := range referrers {
+			if err := pushReferrer(ctx, destRef.Context(), referrer, opts, pushAuth, rt); err != nil {
+				return err
+			}
+		}
+		if signer != nil {
+			for _, img := range append([]v1.Image{image}, referrers...) {
+				if err := pushSignature(ctx, destRef.Context(), img, signer, opts, pushAuth, rt); err != nil {
+					return err
+				}
+			}
+		}
 	}
 	return writeImageOutputs(image, destRefs)
 }
 
+// pushRegistry returns registry, switched to plain HTTP if pushes to it are
+// insecure.
+func pushRegistry(registry name.Registry, opts *config.KanikoOptions) (name.Registry, error) {
+	registryName := registry.Name()
+	if !opts.Insecure && !opts.InsecureRegistries.Contains(registryName) {
+		return registry, nil
+	}
+	newReg, err := name.NewRegistry(registryName, name.WeakValidation, name.Insecure)
+	if err != nil {
+		return name.Registry{}, fmt.Errorf("getting new insecure registry: %w", err)
+	}
+	return newReg, nil
+}
+
+// pushAccess returns the credentials and transport to push to repo with.
+func pushAccess(repo name.Repository, opts *config.KanikoOptions) (authn.Authenticator, http.RoundTripper, error) {
+	pushAuth, err := creds.GetKeychain(&opts.RegistryOptions).Resolve(repo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolving pushAuth: %w", err)
+	}
+	registryName := repo.RegistryStr()
+	localRt, err := util.MakeTransport(opts.RegistryOptions, registryName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("making transport for registry %q: %w", registryName, err)
+	}
+	tr := newRetry(localRt)
+	return pushAuth, &withUserAgent{t: tr}, nil
+}
+
+// pushReferrer pushes an artifact whose subject is the pushed image. A
+// registry without the referrers API gets the fallback tag index updated.
+func pushReferrer(ctx context.Context, repo name.Repository, referrer v1.Image, opts *config.KanikoOptions, auth authn.Authenticator, rt http.RoundTripper) error {
+	dig, err := referrer.Digest()
+	if err != nil {
+		return err
+	}
+	ref := repo.Digest(dig.String())
+	retryFunc := func() error {
+		return remote.Write(ref, referrer, remote.WithAuth(auth), remote.WithTransport(rt), remote.WithContext(ctx))
+	}
+	if err := util.Retry(retryFunc, opts.PushRetry, 1000); err != nil {
+		return fmt.Errorf("failed to push referrer %s: %w", ref, err)
+	}
+	logrus.Infof("Pushed referrer %s", ref)
+	return nil
+}
+
+// pushSignature signs img and adds the signature to its signature image in
+// repo, keeping the signatures already there.
+func pushSignature(ctx context.Context, repo name.Repository, img v1.Image, signer *signing.Signer, opts *config.KanikoOptions, auth authn.Authenticator, rt http.RoundTripper) error {
+	dig, err := img.Digest()
+	if err != nil {
+		return err
+	}
+	payload, err := signing.Payload(repo, dig)
+	if err != nil {
+		return err
+	}
+	sig, err := signer.Sign(payload)
+	if err != nil {
+		return fmt.Errorf("signing %s: %w", dig, err)
+	}
+	tag := signing.Tag(repo, dig)
+	retryFunc := func() error {
+		existing, err := remote.Image(tag, remote.WithAuth(auth), remote.WithTransport(rt), remote.WithContext(ctx))
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+			existing = nil
+		} else if err != nil {
+			return err
+		}
+		sigs, err := signing.Append(existing, payload, sig)
+		if err != nil {
+			return err
+		}
+		return remote.Write(tag, sigs, remote.WithAuth(auth), remote.WithTransport(rt), remote.WithContext(ctx))
+	}
+	if err := util.Retry(retryFunc, opts.PushRetry, 1000); err != nil {
+		return fmt.Errorf("failed to push signature %s: %w", tag, err)
+	}
+	logrus.Infof("Pushed signature %s", tag)
+	return nil
+}
+
 func writeImageOutputs(image v1.Image, destRefs []name.Tag) error {
 	dir := os.Getenv("BUILDER_OUTPUT")
 	if dir == "" {
@@ -440,6 +562,8 @@ func pushLayerToCache(opts *config.KanikoOptions, cacheKey string, tarPath strin
 	}
 	cacheOpts := *opts
 	cacheOpts.TarPath = ""              // tarPath doesn't make sense for Docker layers
+	cacheOpts.MetadataFile = ""         // the metadata file describes the built image
+	cacheOpts.SignKey = ""              // cache entries are not signed
 	cacheOpts.NoPush = opts.NoPushCache // we do not want to push cache if --no-push-cache is set.
 	cacheOpts.Destinations = []string{cache}
 	cacheOpts.InsecureRegistries = opts.InsecureRegistries
@@ -475,6 +599,8 @@ func pushCachePointer(opts *config.KanikoOptions, inferredKey, contentKey string
 
 	cacheOpts := *opts
 	cacheOpts.TarPath = ""
+	cacheOpts.MetadataFile = ""
+	cacheOpts.SignKey = ""
 	cacheOpts.NoPush = opts.NoPushCache
 	cacheOpts.Destinations = []string{dest}
 	cacheOpts.InsecureRegistries = opts.InsecureRegistries
@@ -18,20 +18,28 @@ package executor
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/layout"
 	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/google/go-containerregistry/pkg/v1/validate"
 	"github.com/osscontainertools/kaniko/pkg/config"
+	image_util "github.com/osscontainertools/kaniko/pkg/image"
 	"github.com/osscontainertools/kaniko/pkg/util"
 	"github.com/osscontainertools/kaniko/testutil"
 	"github.com/spf13/afero"
@@ -190,6 +198,45 @@ func TestOCILayoutPath(t *testing.T) {
 	testutil.CheckErrorAndDeepEqual(t, false, err, want, got)
 }
 
+func TestOCILayoutPath_Referrers(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	image, err := random.Image(1024, 1)
+	testutil.CheckNoError(t, err)
+	digest, err := image.Digest()
+	testutil.CheckNoError(t, err)
+	doc := []byte(`{"spdxVersion": "SPDX-2.3"}`)
+	referrer, err := image_util.NewReferrer(image, "application/spdx+json", doc, nil)
+	testutil.CheckNoError(t, err)
+
+	opts := config.KanikoOptions{
+		NoPush:        true,
+		OCILayoutPath: tmpDir,
+	}
+	testutil.CheckNoError(t, DoPush(image_util.WithReferrers(image, referrer), &opts))
+
+	layoutIndex, err := layout.ImageIndexFromPath(tmpDir)
+	testutil.CheckNoError(t, err)
+	im, err := layoutIndex.IndexManifest()
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, 2, len(im.Manifests))
+	testutil.CheckDeepEqual(t, digest, im.Manifests[0].Digest)
+	testutil.CheckDeepEqual(t, "application/spdx+json", im.Manifests[1].ArtifactType)
+
+	got, err := layoutIndex.Image(im.Manifests[1].Digest)
+	testutil.CheckNoError(t, err)
+	m, err := got.Manifest()
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, digest, m.Subject.Digest)
+	layers, err := got.Layers()
+	testutil.CheckNoError(t, err)
+	rc, err := layers[0].Compressed()
+	testutil.CheckNoError(t, err)
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	testutil.CheckErrorAndDeepEqual(t, false, err, doc, content)
+}
+
 func TestImageNameDigestFile(t *testing.T) {
 	image, err := random.Image(1024, 4)
 	if err != nil {
@@ -502,3 +549,59 @@ func TestWriteDigestFile(t *testing.T) {
 		}
 	})
 }
+
+func TestPushToCache_NotSigned(t *testing.T) {
+	registry := newFakeRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.CheckNoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	testutil.CheckNoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	testutil.CheckNoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	layer, err := random.Layer(64, types.DockerLayer)
+	testutil.CheckNoError(t, err)
+	rc, err := layer.Uncompressed()
+	testutil.CheckNoError(t, err)
+	defer rc.Close()
+	tarPath := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(tarPath)
+	testutil.CheckNoError(t, err)
+	_, err = io.Copy(f, rc)
+	testutil.CheckNoError(t, err)
+	testutil.CheckNoError(t, f.Close())
+
+	opts := &config.KanikoOptions{
+		Destinations: []string{host + "/foo/bar:latest"},
+		CacheRepo:    host + "/foo/cache",
+		SignKey:      keyPath,
+	}
+	opts.InsecureRegistries = []string{host}
+
+	sigs := func() []string {
+		t.Helper()
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+		var sigs []string
+		for k := range registry.manifests {
+			if strings.HasSuffix(k, ".sig") {
+				sigs = append(sigs, k)
+			}
+		}
+		return sigs
+	}
+
+	testutil.CheckNoError(t, pushLayerToCache(opts, "layerkey", tarPath, "RUN true"))
+	testutil.CheckNoError(t, pushCachePointer(opts, "inferredkey", "layerkey"))
+	testutil.CheckDeepEqual(t, 0, len(sigs()))
+
+	// while the image itself is signed
+	img, err := random.Image(64, 1)
+	testutil.CheckNoError(t, err)
+	testutil.CheckNoError(t, DoPush(img, opts))
+	testutil.CheckDeepEqual(t, 1, len(sigs()))
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	image_util "github.com/osscontainertools/kaniko/pkg/image"
+	"github.com/osscontainertools/kaniko/pkg/sbom"
+	"github.com/osscontainertools/kaniko/pkg/timing"
+	"github.com/osscontainertools/kaniko/pkg/util"
+	"github.com/sirupsen/logrus"
+)
+
+// attachSBOM scans the root filesystem, which holds the push stage when this
+// is called, and attaches the resulting document to img as a referrer.
+func attachSBOM(ctx context.Context, img v1.Image, opts *config.KanikoOptions) (v1.Image, error) {
+	t := timing.Start("SBOM Generation")
+	defer t.End()
+
+	// The build context sits on the same filesystem without being part of
+	// the image, a node_modules in it is not in the image.
+	srcContext := filepath.Clean(opts.SrcContext)
+	skip := func(path string) bool {
+		return util.CheckIgnoreList(path) || path == srcContext
+	}
+	pkgs, err := sbom.Scan(config.RootDir, skip)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+	subject := sbom.Subject{Digest: digest}
+	if len(opts.Destinations) > 0 {
+		if ref, err := name.NewTag(opts.Destinations[0], name.WeakValidation); err == nil {
+			subject.Name = ref.Context().Name()
+		}
+	}
+	created := time.Now()
+	if opts.Reproducible {
+		created = time.Unix(0, 0)
+	} else if epoch := config.SourceDateEpoch(ctx); epoch != nil {
+		created = *epoch
+	}
+	doc, err := sbom.Encode(string(opts.SBOM), pkgs, subject, created)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("Generated %s SBOM of %d packages", opts.SBOM, len(pkgs))
+
+	referrer, err := image_util.NewReferrer(img, sbom.MediaType(string(opts.SBOM)), doc, nil)
+	if err != nil {
+		return nil, err
+	}
+	return image_util.WithReferrers(img, referrer), nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/osscontainertools/kaniko/pkg/config"
+	image_util "github.com/osscontainertools/kaniko/pkg/image"
+	"github.com/osscontainertools/kaniko/pkg/image/remote"
+	"github.com/sirupsen/logrus"
+)
+
+// buildState is the state of a single Build. It is created on entry and
+// carried on the context Build threads through its stages, so builds in one
+// process don't observe each other.
+type buildState struct {
+	// epoch is SOURCE_DATE_EPOCH, nil if it is not set
+	epoch *time.Time
+	// lockfile is the --lockfile images are resolved through, nil without it
+	lockfile *remote.Lockfile
+}
+
+func newBuildState(opts *config.KanikoOptions) (*buildState, error) {
+	epoch, err := config.ParseSourceDateEpoch(opts.BuildArgs, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	if epoch != nil {
+		logrus.Infof("Clamping timestamps to %s=%d", config.SourceDateEpochArg, epoch.Unix())
+	}
+	state := &buildState{epoch: epoch}
+	if opts.Lockfile != "" {
+		state.lockfile, err = remote.LoadLockfile(opts.Lockfile, opts.LockfileMode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// context returns ctx carrying the state to the packages the build calls.
+func (b *buildState) context(ctx context.Context) context.Context {
+	ctx = config.WithSourceDateEpoch(ctx, b.epoch)
+	ctx = remote.WithLockfile(ctx, b.lockfile)
+	return image_util.WithVerifiedImages(ctx)
+}
+
+// save writes what the build recorded, once it succeeded.
+func (b *buildState) save() error {
+	if b.lockfile == nil {
+		return nil
+	}
+	return b.lockfile.Save()
+}
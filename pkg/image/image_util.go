@@ -17,10 +17,12 @@ limitations under the License.
 package image
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -54,21 +56,19 @@ var (
 	}()
 )
 
-// RetrieveSourceImage returns the base image of the stage at index
-func RetrieveSourceImage(stage config.KanikoStage, opts *config.KanikoOptions) (v1.Image, error) {
-	return RetrieveSourceImageInternal(stage.BaseName, stage.BaseImageStoredLocally, stage.BaseImageIndex, stage.MetaArgs, opts)
+// RetrieveSourceImage returns the base image of the stage at index, the
+// registry requests are bound to ctx.
+func RetrieveSourceImage(ctx context.Context, stage config.KanikoStage, opts *config.KanikoOptions) (v1.Image, error) {
+	return RetrieveSourceImageInternal(ctx, stage.BaseName, stage.Platform, stage.BaseImageStoredLocally, stage.BaseImageIndex, stage.MetaArgs, opts)
 }
 
-func RetrieveSourceImageInternal(baseName string, baseImageStoredLocally bool, baseImageIndex int, metaArgs []instructions.ArgCommand, opts *config.KanikoOptions) (v1.Image, error) {
-	var buildArgs []string
-
-	for _, marg := range metaArgs {
-		for _, arg := range marg.Args {
-			buildArgs = append(buildArgs, fmt.Sprintf("%s=%s", arg.Key, arg.ValueString()))
-		}
+// RetrieveSourceImageInternal resolves baseName for platform, the
+// --custom-platform if empty.
+func RetrieveSourceImageInternal(ctx context.Context, baseName, platform string, baseImageStoredLocally bool, baseImageIndex int, metaArgs []instructions.ArgCommand, opts *config.KanikoOptions) (v1.Image, error) {
+	if platform == "" {
+		platform = opts.CustomPlatform
 	}
-	buildArgs = append(buildArgs, opts.BuildArgs...)
-	currentBaseName, err := util.ResolveEnvironmentReplacement(baseName, buildArgs, false)
+	currentBaseName, err := resolveBaseName(baseName, metaArgs, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -83,18 +83,74 @@ func RetrieveSourceImageInternal(baseName string, baseImageStoredLocally bool, b
 		return retrieveOciImage(baseImageIndex)
 	}
 
-	// Finally, check if local caching is enabled
+	// A named build context stands in for the image it is named after
+	if source, ok := opts.BuildContexts[currentBaseName]; ok {
+		ref, isRef := strings.CutPrefix(source, constants.DockerImageContextPrefix)
+		if !isRef {
+			return RetrieveContextImage(ctx, source, platform, opts)
+		}
+		logrus.Infof("Using %s from build context %s", ref, currentBaseName)
+		currentBaseName = ref
+	}
+
+	image, err := retrieveImage(ctx, currentBaseName, platform, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyBaseImage(ctx, currentBaseName, image, opts); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+// resolveBaseName replaces the build args in baseName.
+func resolveBaseName(baseName string, metaArgs []instructions.ArgCommand, opts *config.KanikoOptions) (string, error) {
+	var buildArgs []string
+
+	for _, marg := range metaArgs {
+		for _, arg := range marg.Args {
+			buildArgs = append(buildArgs, fmt.Sprintf("%s=%s", arg.Key, arg.ValueString()))
+		}
+	}
+	buildArgs = append(buildArgs, opts.BuildArgs...)
+	return util.ResolveEnvironmentReplacement(baseName, buildArgs, false)
+}
+
+// BaseImageName returns the registry image stage is based on, as
+// RetrieveSourceImage resolves it. It is false if the base is
+// scratch, another stage or a build context that is not an image.
+func BaseImageName(stage config.KanikoStage, opts *config.KanikoOptions) (string, bool, error) {
+	if stage.BaseImageStoredLocally {
+		return "", false, nil
+	}
+	baseName, err := resolveBaseName(stage.BaseName, stage.MetaArgs, opts)
+	if err != nil || baseName == constants.NoBaseImage {
+		return "", false, err
+	}
+	if source, ok := opts.BuildContexts[baseName]; ok {
+		ref, isRef := strings.CutPrefix(source, constants.DockerImageContextPrefix)
+		if !isRef {
+			return "", false, nil
+		}
+		return ref, true, nil
+	}
+	return baseName, true, nil
+}
+
+// retrieveImage fetches a registry image, from the local cache if enabled.
+func retrieveImage(ctx context.Context, image, platform string, opts *config.KanikoOptions) (v1.Image, error) {
+	// Check if local caching is enabled
 	// If so, look in the local cache before trying the remote registry
 	if opts.Cache && opts.CacheDir != "" {
-		cachedImage, err := cachedImage(opts, currentBaseName)
+		cachedImage, err := cachedImage(ctx, opts, image, platform)
 		if err != nil {
 			switch {
 			case cache.IsNotFound(err):
-				logrus.Debugf("Image %v not found in cache", currentBaseName)
+				logrus.Debugf("Image %v not found in cache", image)
 			case cache.IsExpired(err):
-				logrus.Debugf("Image %v found in cache but was expired", currentBaseName)
+				logrus.Debugf("Image %v found in cache but was expired", image)
 			default:
-				logrus.Errorf("Error while retrieving image from cache: %v %v", currentBaseName, err)
+				logrus.Errorf("Error while retrieving image from cache: %v %v", image, err)
 			}
 		} else if cachedImage != nil {
 			return cachedImage, nil
@@ -104,7 +160,92 @@ func RetrieveSourceImageInternal(baseName string, baseImageStoredLocally bool, b
 	// Otherwise, initialize image as usual
 	t := timing.Start("Retrieving Source Image")
 	defer t.End()
-	return RetrieveRemoteImage(currentBaseName, opts.RegistryOptions, opts.CustomPlatform)
+	return RetrieveRemoteImage(ctx, image, opts.RegistryOptions, platform)
+}
+
+// RetrieveContextImage returns the image of a named build context for platform,
+// given as docker-image://ref or as oci-layout://path with an optional :tag or
+// @digest.
+func RetrieveContextImage(ctx context.Context, source, platform string, opts *config.KanikoOptions) (v1.Image, error) {
+	if platform == "" {
+		platform = opts.CustomPlatform
+	}
+	if ref, ok := strings.CutPrefix(source, constants.DockerImageContextPrefix); ok {
+		image, err := RetrieveRemoteImage(ctx, ref, opts.RegistryOptions, platform)
+		if err != nil {
+			return nil, err
+		}
+		if err := VerifyBaseImage(ctx, ref, image, opts); err != nil {
+			return nil, err
+		}
+		return image, nil
+	}
+	if path, ok := strings.CutPrefix(source, constants.OCILayoutContextPrefix); ok {
+		return layoutImage(path, platform)
+	}
+	return nil, fmt.Errorf("build context %s is not an image", source)
+}
+
+// layoutImage reads the image selected by spec from an OCI layout. Without a
+// tag or digest the layout must hold a single image. An index is resolved to
+// the image for platform.
+func layoutImage(spec, platform string) (v1.Image, error) {
+	path, digest, tag := spec, "", ""
+	if i := strings.LastIndex(spec, "@"); i != -1 {
+		path, digest = spec[:i], spec[i+1:]
+	} else if i := strings.LastIndex(spec, ":"); i > strings.LastIndex(spec, "/") {
+		path, tag = spec[:i], spec[i+1:]
+	}
+	logrus.Infof("Retrieving image from OCI layout %s", spec)
+	idx, err := layout.ImageIndexFromPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading OCI layout: %w", err)
+	}
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("reading OCI index manifest: %w", err)
+	}
+	var found []v1.Descriptor
+	for _, desc := range manifest.Manifests {
+		switch {
+		case digest != "" && desc.Digest.String() != digest:
+		case tag != "" && desc.Annotations["org.opencontainers.image.ref.name"] != tag:
+		default:
+			found = append(found, desc)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no image %s found in OCI layout", spec)
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("found %d images in OCI layout %s, select one with a tag or digest", len(found), path)
+	}
+	if !found[0].MediaType.IsIndex() {
+		return idx.Image(found[0].Digest)
+	}
+	child, err := idx.ImageIndex(found[0].Digest)
+	if err != nil {
+		return nil, err
+	}
+	return platformImage(child, platform)
+}
+
+// platformImage returns the image of an index that satisfies platform.
+func platformImage(idx v1.ImageIndex, platform string) (v1.Image, error) {
+	want, err := v1.ParsePlatform(platform)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	for _, desc := range manifest.Manifests {
+		if desc.Platform != nil && desc.Platform.Satisfies(*want) {
+			return idx.Image(desc.Digest)
+		}
+	}
+	return nil, fmt.Errorf("no image for platform %s found in index", platform)
 }
 
 func ociImage(index int) (v1.Image, error) {
@@ -134,7 +275,7 @@ func ociImage(index int) (v1.Image, error) {
 	return p.Image(hash)
 }
 
-func cachedImage(opts *config.KanikoOptions, image string) (v1.Image, error) {
+func cachedImage(ctx context.Context, opts *config.KanikoOptions, image, platform string) (v1.Image, error) {
 	ref, err := name.ParseReference(image, name.WeakValidation)
 	if err != nil {
 		return nil, err
@@ -160,7 +301,7 @@ func cachedImage(opts *config.KanikoOptions, image string) (v1.Image, error) {
 			return nil, err
 		}
 	}
-	img, err := remote.RetrieveRemoteImage(image, opts.RegistryOptions, opts.CustomPlatform)
+	img, err := remote.RetrieveRemoteImage(ctx, image, opts.RegistryOptions, platform)
 	if err != nil {
 		return nil, err
 	}
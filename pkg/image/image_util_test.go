@@ -18,10 +18,13 @@ package image
 
 import (
 	"bytes"
+	"context"
 	"testing"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 	"github.com/moby/buildkit/frontend/dockerfile/linter"
 	"github.com/moby/buildkit/frontend/dockerfile/parser"
@@ -50,12 +53,12 @@ func Test_StandardImage(t *testing.T) {
 	defer func() {
 		RetrieveRemoteImage = original
 	}()
-	mock := func(_ string, _ config.RegistryOptions, _ string) (v1.Image, error) {
+	mock := func(_ context.Context, _ string, _ config.RegistryOptions, _ string) (v1.Image, error) {
 		return nil, nil
 	}
 	RetrieveRemoteImage = mock
 	s := stages[0]
-	actual, err := RetrieveSourceImage(config.KanikoStage{
+	actual, err := RetrieveSourceImage(context.Background(), config.KanikoStage{
 		Name:     s.Name,
 		BaseName: s.BaseName,
 		Commands: s.Commands,
@@ -69,7 +72,7 @@ func Test_ScratchImage(t *testing.T) {
 		t.Error(err)
 	}
 	s := stages[1]
-	actual, err := RetrieveSourceImage(config.KanikoStage{
+	actual, err := RetrieveSourceImage(context.Background(), config.KanikoStage{
 		Name:     s.Name,
 		BaseName: s.BaseName,
 		Commands: s.Commands,
@@ -92,7 +95,7 @@ func Test_OciImage(t *testing.T) {
 	}
 	retrieveOciImage = mock
 	s := stages[2]
-	actual, err := RetrieveSourceImage(config.KanikoStage{
+	actual, err := RetrieveSourceImage(context.Background(), config.KanikoStage{
 		Name:                   s.Name,
 		BaseName:               s.BaseName,
 		Commands:               s.Commands,
@@ -108,7 +111,7 @@ func Test_ScratchImageFromMirror(t *testing.T) {
 		t.Error(err)
 	}
 	s := stages[1]
-	actual, err := RetrieveSourceImage(config.KanikoStage{
+	actual, err := RetrieveSourceImage(context.Background(), config.KanikoStage{
 		Name:     s.Name,
 		BaseName: s.BaseName,
 		Commands: s.Commands,
@@ -121,6 +124,90 @@ func Test_ScratchImageFromMirror(t *testing.T) {
 	testutil.CheckErrorAndDeepEqual(t, false, err, expected, actual)
 }
 
+func Test_BuildContextImage(t *testing.T) {
+	original := RetrieveRemoteImage
+	defer func() {
+		RetrieveRemoteImage = original
+	}()
+	var retrieved string
+	RetrieveRemoteImage = func(_ context.Context, image string, _ config.RegistryOptions, _ string) (v1.Image, error) {
+		retrieved = image
+		return nil, nil
+	}
+	opts := &config.KanikoOptions{BuildContexts: map[string]string{"base": "docker-image://alpine:3.19"}}
+	_, err := RetrieveSourceImageInternal(context.Background(), "base", "", false, -1, nil, opts)
+	testutil.CheckErrorAndDeepEqual(t, false, err, "alpine:3.19", retrieved)
+
+	opts.BuildContexts["base"] = "/workspace/base"
+	_, err = RetrieveSourceImageInternal(context.Background(), "base", "", false, -1, nil, opts)
+	testutil.CheckError(t, true, err)
+}
+
+func Test_BaseImageName(t *testing.T) {
+	opts := &config.KanikoOptions{
+		BuildArgs:     []string{"VERSION=12"},
+		BuildContexts: map[string]string{"base": "docker-image://alpine:3.19", "local": "/workspace/base"},
+	}
+	tests := []struct {
+		stage    config.KanikoStage
+		expected string
+		ok       bool
+	}{
+		{stage: config.KanikoStage{BaseName: "debian:${VERSION}"}, expected: "debian:12", ok: true},
+		{stage: config.KanikoStage{BaseName: "base"}, expected: "alpine:3.19", ok: true},
+		{stage: config.KanikoStage{BaseName: "local"}},
+		{stage: config.KanikoStage{BaseName: "scratch"}},
+		{stage: config.KanikoStage{BaseName: "builder", BaseImageStoredLocally: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stage.BaseName, func(t *testing.T) {
+			name, ok, err := BaseImageName(tt.stage, opts)
+			testutil.CheckErrorAndDeepEqual(t, false, err, tt.expected, name)
+			testutil.CheckDeepEqual(t, tt.ok, ok)
+		})
+	}
+}
+
+func Test_BuildContextOCILayout(t *testing.T) {
+	dir := t.TempDir()
+	p, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	images := map[string]v1.Image{}
+	for _, tag := range []string{"v1", "v2"} {
+		img, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.AppendImage(img, layout.WithAnnotations(map[string]string{
+			"org.opencontainers.image.ref.name": tag,
+		})); err != nil {
+			t.Fatal(err)
+		}
+		images[tag] = img
+	}
+	want, err := images["v2"].Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &config.KanikoOptions{CustomPlatform: "linux/amd64"}
+
+	for _, source := range []string{"oci-layout://" + dir + ":v2", "oci-layout://" + dir + "@" + want.String()} {
+		img, err := RetrieveContextImage(context.Background(), source, "", opts)
+		if err != nil {
+			t.Fatalf("%s: %v", source, err)
+		}
+		got, err := img.Digest()
+		testutil.CheckErrorAndDeepEqual(t, false, err, want, got)
+	}
+
+	_, err = RetrieveContextImage(context.Background(), "oci-layout://"+dir, "", opts)
+	testutil.CheckError(t, true, err)
+	_, err = RetrieveContextImage(context.Background(), "oci-layout://"+dir+":v3", "", opts)
+	testutil.CheckError(t, true, err)
+}
+
 // parse parses the contents of a Dockerfile and returns a list of commands
 func parse(s string) ([]instructions.Stage, error) {
 	p, err := parser.Parse(bytes.NewReader([]byte(s)))
@@ -0,0 +1,146 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// emptyConfig is the OCI empty descriptor's content, the config of an
+// artifact manifest that has nothing to configure.
+var emptyConfig = []byte("{}")
+
+const emptyConfigMediaType types.MediaType = "application/vnd.oci.empty.v1+json"
+
+// NewReferrer returns an OCI artifact manifest of artifactType holding
+// content as its single layer, with subject as its subject so registries list
+// it among subject's referrers.
+func NewReferrer(subject v1.Image, artifactType string, content []byte, annotations map[string]string) (v1.Image, error) {
+	desc, err := partial.Descriptor(subject)
+	if err != nil {
+		return nil, fmt.Errorf("describing subject: %w", err)
+	}
+	return NewReferrerTo(*desc, artifactType, content, annotations)
+}
+
+// NewReferrerTo is NewReferrer for a subject known by its descriptor, which
+// may also be an index.
+func NewReferrerTo(subject v1.Descriptor, artifactType string, content []byte, annotations map[string]string) (v1.Image, error) {
+	configDigest, _, err := v1.SHA256(bytes.NewReader(emptyConfig))
+	if err != nil {
+		return nil, err
+	}
+	blob := &blobLayer{content: content, mediaType: types.MediaType(artifactType)}
+	blob.digest, _, err = v1.SHA256(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	m := v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		ArtifactType:  artifactType,
+		Config: v1.Descriptor{
+			MediaType: emptyConfigMediaType,
+			Digest:    configDigest,
+			Size:      int64(len(emptyConfig)),
+			Data:      emptyConfig,
+		},
+		Layers: []v1.Descriptor{{
+			MediaType: blob.mediaType,
+			Digest:    blob.digest,
+			Size:      int64(len(content)),
+		}},
+		Subject: &v1.Descriptor{
+			MediaType: subject.MediaType,
+			Digest:    subject.Digest,
+			Size:      subject.Size,
+		},
+		Annotations: annotations,
+	}
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return partial.CompressedToImage(&artifact{manifest: raw, blob: blob})
+}
+
+type artifact struct {
+	manifest []byte
+	blob     *blobLayer
+}
+
+func (a *artifact) RawConfigFile() ([]byte, error)      { return emptyConfig, nil }
+func (a *artifact) MediaType() (types.MediaType, error) { return types.OCIManifestSchema1, nil }
+func (a *artifact) RawManifest() ([]byte, error)        { return a.manifest, nil }
+
+func (a *artifact) LayerByDigest(h v1.Hash) (partial.CompressedLayer, error) {
+	if h != a.blob.digest {
+		return nil, fmt.Errorf("unknown blob %s", h)
+	}
+	return a.blob, nil
+}
+
+// blobLayer is an artifact's content. It is stored as is, "compressed" only
+// in the sense that its bytes are the ones the registry holds.
+type blobLayer struct {
+	content   []byte
+	digest    v1.Hash
+	mediaType types.MediaType
+}
+
+func (b *blobLayer) Digest() (v1.Hash, error)            { return b.digest, nil }
+func (b *blobLayer) Size() (int64, error)                { return int64(len(b.content)), nil }
+func (b *blobLayer) MediaType() (types.MediaType, error) { return b.mediaType, nil }
+func (b *blobLayer) Compressed() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(b.content)), nil
+}
+
+// withReferrers is an image carrying artifacts to push alongside it.
+type withReferrers struct {
+	v1.Image
+	referrers []v1.Image
+}
+
+// WithReferrers returns img with referrers attached, which Push writes
+// wherever it writes img. The result is still img as far as digests,
+// manifests and layers go.
+func WithReferrers(img v1.Image, referrers ...v1.Image) v1.Image {
+	if w, ok := img.(*withReferrers); ok {
+		return &withReferrers{Image: w.Image, referrers: append(w.Referrers(), referrers...)}
+	}
+	return &withReferrers{Image: img, referrers: referrers}
+}
+
+func (w *withReferrers) Referrers() []v1.Image {
+	return append([]v1.Image(nil), w.referrers...)
+}
+
+// Referrers returns the artifacts attached to img by WithReferrers, also
+// through wrappers of img that pass them on with a Referrers method.
+func Referrers(img v1.Image) []v1.Image {
+	if w, ok := img.(interface{ Referrers() []v1.Image }); ok {
+		return w.Referrers()
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Lockfile pins image references to the digests they resolved to, per
+// platform, like go.sum does for modules. References already pinned to a
+// digest need no entry.
+type Lockfile struct {
+	path   string
+	mode   config.LockfileMode
+	images map[lockKey]v1.Hash
+}
+
+type lockKey struct {
+	reference string
+	platform  string
+}
+
+// lockEntry is an image as stored in the file.
+type lockEntry struct {
+	Reference string  `json:"reference"`
+	Platform  string  `json:"platform"`
+	Digest    v1.Hash `json:"digest"`
+}
+
+type lockDocument struct {
+	Images []lockEntry `json:"images"`
+}
+
+// LoadLockfile reads the lockfile at path for mode. In write mode the
+// lockfile starts out empty, whatever is in the file is replaced on Save.
+func LoadLockfile(path string, mode config.LockfileMode) (*Lockfile, error) {
+	l := &Lockfile{path: path, mode: mode, images: map[lockKey]v1.Hash{}}
+	if mode == config.LockfileModeWrite {
+		return l, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("lockfile %s does not exist, create it with --lockfile-mode=write", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc lockDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("parsing lockfile %s: %w", path, err)
+	}
+	for _, e := range doc.Images {
+		l.images[lockKey{e.Reference, e.Platform}] = e.Digest
+	}
+	return l, nil
+}
+
+type lockfileKey struct{}
+
+// WithLockfile returns a copy of ctx that resolves every image through l, or
+// through no lockfile if l is nil.
+func WithLockfile(ctx context.Context, l *Lockfile) context.Context {
+	return context.WithValue(ctx, lockfileKey{}, l)
+}
+
+// lockfileFrom returns the lockfile images resolved with ctx go through.
+func lockfileFrom(ctx context.Context) *Lockfile {
+	l, _ := ctx.Value(lockfileKey{}).(*Lockfile)
+	return l
+}
+
+// resolve returns the reference to fetch image for platform by. In enforce
+// mode that is the digest the lockfile pins it to.
+func (l *Lockfile) resolve(ref name.Reference, image, platform string) (name.Reference, error) {
+	if _, pinned := ref.(name.Digest); pinned || l.mode != config.LockfileModeEnforce {
+		return ref, nil
+	}
+	digest, ok := l.images[lockKey{image, platform}]
+	if !ok {
+		return nil, fmt.Errorf("%s for %s is not in lockfile %s, update it with --lockfile-mode=write", image, platform, l.path)
+	}
+	logrus.Infof("Resolved %s to %s from lockfile", image, digest)
+	return ref.Context().Digest(digest.String()), nil
+}
+
+// record notes the digest image resolved to for platform in write mode.
+func (l *Lockfile) record(ref name.Reference, image, platform string, img v1.Image) error {
+	if _, pinned := ref.(name.Digest); pinned || l.mode != config.LockfileModeWrite {
+		return nil
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return err
+	}
+	l.images[lockKey{image, platform}] = digest
+	return nil
+}
+
+// Save writes the images resolved in write mode to the lockfile, sorted so
+// that an unchanged build leaves the file unchanged.
+func (l *Lockfile) Save() error {
+	if l.mode != config.LockfileModeWrite {
+		return nil
+	}
+	doc := lockDocument{Images: []lockEntry{}}
+	for k, digest := range l.images {
+		doc.Images = append(doc.Images, lockEntry{Reference: k.reference, Platform: k.platform, Digest: digest})
+	}
+	slices.SortFunc(doc.Images, func(a, b lockEntry) int {
+		return cmp.Or(cmp.Compare(a.Reference, b.Reference), cmp.Compare(a.Platform, b.Platform))
+	})
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(l.path, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing lockfile: %w", err)
+	}
+	logrus.Infof("Wrote %d images to lockfile %s", len(doc.Images), l.path)
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/osscontainertools/kaniko/testutil"
+)
+
+func Test_Lockfile(t *testing.T) {
+	original := remoteImageFunc
+	defer func() {
+		remoteImageFunc = original
+		ResetManifestCache()
+	}()
+	img, err := random.Image(64, 1)
+	testutil.CheckNoError(t, err)
+	digest, err := img.Digest()
+	testutil.CheckNoError(t, err)
+	var fetched []string
+	remoteImageFunc = func(ref name.Reference, _ ...remote.Option) (v1.Image, error) {
+		fetched = append(fetched, ref.String())
+		return img, nil
+	}
+	path := filepath.Join(t.TempDir(), "kaniko.lock")
+	pinned := "gcr.io/foo/bar@" + digest.String()
+
+	// write records the digest each reference resolves to
+	ResetManifestCache()
+	l, err := LoadLockfile(path, config.LockfileModeWrite)
+	testutil.CheckNoError(t, err)
+	ctx := WithLockfile(context.Background(), l)
+	for _, platform := range []string{"linux/arm64", "linux/amd64"} {
+		_, err = RetrieveRemoteImage(ctx, "debian:12", config.RegistryOptions{}, platform)
+		testutil.CheckNoError(t, err)
+	}
+	_, err = RetrieveRemoteImage(ctx, pinned, config.RegistryOptions{}, "linux/amd64")
+	testutil.CheckNoError(t, err)
+	testutil.CheckNoError(t, l.Save())
+	b, err := os.ReadFile(path)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, `{
+  "images": [
+    {
+      "reference": "debian:12",
+      "platform": "linux/amd64",
+      "digest": "`+digest.String()+`"
+    },
+    {
+      "reference": "debian:12",
+      "platform": "linux/arm64",
+      "digest": "`+digest.String()+`"
+    }
+  ]
+}
+`, string(b))
+
+	// enforce fetches by the recorded digest only
+	ResetManifestCache()
+	fetched = nil
+	l, err = LoadLockfile(path, config.LockfileModeEnforce)
+	testutil.CheckNoError(t, err)
+	ctx = WithLockfile(context.Background(), l)
+	_, err = RetrieveRemoteImage(ctx, "debian:12", config.RegistryOptions{}, "linux/amd64")
+	testutil.CheckNoError(t, err)
+	_, err = RetrieveRemoteImage(ctx, pinned, config.RegistryOptions{}, "linux/amd64")
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, []string{"index.docker.io/library/debian@" + digest.String(), pinned}, fetched)
+	_, err = RetrieveRemoteImage(ctx, "debian:12", config.RegistryOptions{}, "linux/s390x")
+	testutil.CheckError(t, true, err)
+	_, err = RetrieveRemoteImage(ctx, "debian:13", config.RegistryOptions{}, "linux/amd64")
+	testutil.CheckError(t, true, err)
+
+	_, err = LoadLockfile(filepath.Join(t.TempDir(), "missing.lock"), config.LockfileModeEnforce)
+	testutil.CheckError(t, true, err)
+}
@@ -17,12 +17,17 @@ limitations under the License.
 package remote
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	"github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/pkg/creds"
 	"github.com/osscontainertools/kaniko/pkg/util"
@@ -32,13 +37,22 @@ import (
 var (
 	manifestCache   = make(map[string]v1.Image)
 	remoteImageFunc = remote.Image
+	remoteGetFunc   = remote.Get
 )
 
-// RetrieveRemoteImage retrieves the manifest for the specified image from the specified registry
-func RetrieveRemoteImage(image string, opts config.RegistryOptions, customPlatform string) (v1.Image, error) {
+// ResetManifestCache forgets every manifest resolved so far. A build starts with an
+// empty cache so that a tag moved between two builds in one process is resolved again.
+func ResetManifestCache() {
+	manifestCache = make(map[string]v1.Image)
+}
+
+// RetrieveRemoteImage retrieves the manifest for the specified image from the specified registry,
+// the registry requests are bound to ctx and go through the lockfile it carries.
+func RetrieveRemoteImage(ctx context.Context, image string, opts config.RegistryOptions, customPlatform string) (v1.Image, error) {
 	logrus.Infof("Retrieving image manifest %s", image)
 
-	cachedRemoteImage := manifestCache[image]
+	key := manifestKey(image, customPlatform)
+	cachedRemoteImage := manifestCache[key]
 	if cachedRemoteImage != nil {
 		logrus.Infof("Returning cached image manifest")
 		return cachedRemoteImage, nil
@@ -48,7 +62,26 @@ func RetrieveRemoteImage(image string, opts config.RegistryOptions, customPlatfo
 	if err != nil {
 		return nil, err
 	}
+	lockfile := lockfileFrom(ctx)
+	if lockfile != nil {
+		if ref, err = lockfile.resolve(ref, image, customPlatform); err != nil {
+			return nil, err
+		}
+	}
+
+	remoteImage, err := retrieveRemoteImage(ctx, ref, opts, customPlatform)
+	if remoteImage != nil {
+		manifestCache[key] = remoteImage
+		if lockfile != nil {
+			if err := lockfile.record(ref, image, customPlatform, remoteImage); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return remoteImage, err
+}
 
+func retrieveRemoteImage(ctx context.Context, ref name.Reference, opts config.RegistryOptions, customPlatform string) (v1.Image, error) {
 	if newRegURLs, found := opts.RegistryMaps[ref.Context().RegistryStr()]; found {
 		for _, registryMapping := range newRegURLs {
 			regToMapTo, repositoryPrefix := parseRegistryMapping(registryMapping)
@@ -64,7 +97,7 @@ func RetrieveRemoteImage(image string, opts config.RegistryOptions, customPlatfo
 
 			logrus.Infof("Retrieving image %s from mapped registry %s", remappedRef, regToMapTo)
 			retryFunc := func() (v1.Image, error) {
-				return remoteImageFunc(remappedRef, remoteOptions(regToMapTo, opts, customPlatform)...)
+				return remoteImageFunc(remappedRef, remoteOptions(ctx, regToMapTo, opts, customPlatform)...)
 			}
 
 			var remoteImage v1.Image
@@ -73,8 +106,6 @@ func RetrieveRemoteImage(image string, opts config.RegistryOptions, customPlatfo
 				continue
 			}
 
-			manifestCache[image] = remoteImage
-
 			return remoteImage, nil
 		}
 
@@ -84,26 +115,86 @@ func RetrieveRemoteImage(image string, opts config.RegistryOptions, customPlatfo
 	}
 
 	registryName := ref.Context().RegistryStr()
-	if opts.InsecurePull || opts.InsecureRegistries.Contains(registryName) {
-		newReg, err := name.NewRegistry(registryName, name.WeakValidation, name.Insecure)
-		if err != nil {
-			return nil, err
-		}
-		ref = setNewRegistry(ref, newReg)
+	ref, err := pullReference(ref, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	logrus.Infof("Retrieving image %s from registry %s", ref, registryName)
 
 	retryFunc := func() (v1.Image, error) {
-		return remoteImageFunc(ref, remoteOptions(registryName, opts, customPlatform)...)
+		return remoteImageFunc(ref, remoteOptions(ctx, registryName, opts, customPlatform)...)
 	}
 
-	var remoteImage v1.Image
-	if remoteImage, err = util.RetryWithResult(retryFunc, opts.ImageDownloadRetry, 1000); remoteImage != nil {
-		manifestCache[image] = remoteImage
+	return util.RetryWithResult(retryFunc, opts.ImageDownloadRetry, 1000)
+}
+
+// RetrieveDescriptor returns the descriptor of the manifest ref points at
+// and, if that is an index, the index manifest. Registry maps are not
+// consulted: the digest is the one the registry named by ref serves.
+func RetrieveDescriptor(ctx context.Context, ref name.Reference, opts config.RegistryOptions) (v1.Descriptor, *v1.IndexManifest, error) {
+	registryName := ref.Context().RegistryStr()
+	ref, err := pullReference(ref, opts)
+	if err != nil {
+		return v1.Descriptor{}, nil, err
 	}
+	retryFunc := func() (*remote.Descriptor, error) {
+		return remoteGetFunc(ref, remoteOptions(ctx, registryName, opts, "")...)
+	}
+	desc, err := util.RetryWithResult(retryFunc, opts.ImageDownloadRetry, 1000)
+	if err != nil {
+		return v1.Descriptor{}, nil, err
+	}
+	if !desc.MediaType.IsIndex() {
+		return desc.Descriptor, nil, nil
+	}
+	index, err := v1.ParseIndexManifest(bytes.NewReader(desc.Manifest))
+	if err != nil {
+		return v1.Descriptor{}, nil, err
+	}
+	return desc.Descriptor, index, nil
+}
 
-	return remoteImage, err
+// RetrieveSignatures returns the cosign signature image at tag, nil if there
+// is none.
+func RetrieveSignatures(ctx context.Context, tag name.Tag, opts config.RegistryOptions) (v1.Image, error) {
+	registryName := tag.RegistryStr()
+	ref, err := pullReference(tag, opts)
+	if err != nil {
+		return nil, err
+	}
+	retryFunc := func() (v1.Image, error) {
+		img, err := remoteImageFunc(ref, remoteOptions(ctx, registryName, opts, "")...)
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return img, err
+	}
+	return util.RetryWithResult(retryFunc, opts.ImageDownloadRetry, 1000)
+}
+
+// pullReference returns ref on a plain HTTP registry when pulls from its
+// registry are insecure.
+func pullReference(ref name.Reference, opts config.RegistryOptions) (name.Reference, error) {
+	registryName := ref.Context().RegistryStr()
+	if !opts.InsecurePull && !opts.InsecureRegistries.Contains(registryName) {
+		return ref, nil
+	}
+	newReg, err := name.NewRegistry(registryName, name.WeakValidation, name.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	return setNewRegistry(ref, newReg), nil
+}
+
+// manifestKey keys the manifest cache, a stage built for another platform
+// resolves the same reference to another image.
+func manifestKey(image, platform string) string {
+	if platform == "" {
+		return image
+	}
+	return image + "|" + platform
 }
 
 // remapRepository adds the {repositoryPrefix}/ to the original repo, and normalizes with an additional library/ if necessary
@@ -141,7 +232,7 @@ func setNewRegistry(ref name.Reference, newReg name.Registry) name.Reference {
 	}
 }
 
-func remoteOptions(registryName string, opts config.RegistryOptions, customPlatform string) []remote.Option {
+func remoteOptions(ctx context.Context, registryName string, opts config.RegistryOptions, customPlatform string) []remote.Option {
 	tr, err := util.MakeTransport(opts, registryName)
 	// The MakeTransport function will only return errors if there was a problem
 	// with registry certificates (Verification or mTLS)
@@ -155,7 +246,7 @@ func remoteOptions(registryName string, opts config.RegistryOptions, customPlatf
 		logrus.Fatalf("Invalid platform %q: %v", customPlatform, err)
 	}
 
-	return []remote.Option{remote.WithTransport(tr), remote.WithAuthFromKeychain(creds.GetKeychain(&opts)), remote.WithPlatform(*platform)}
+	return []remote.Option{remote.WithContext(ctx), remote.WithTransport(tr), remote.WithAuthFromKeychain(creds.GetKeychain(&opts)), remote.WithPlatform(*platform)}
 }
 
 // Parse the registry mapping
@@ -17,6 +17,7 @@ limitations under the License.
 package remote
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -175,15 +176,19 @@ func Test_remapRepository(t *testing.T) {
 func Test_RetrieveRemoteImage_manifestCache(t *testing.T) {
 	nonExistingImageName := "this_is_a_non_existing_image_reference"
 
-	if _, err := RetrieveRemoteImage(nonExistingImageName, config.RegistryOptions{}, ""); err == nil {
+	if _, err := RetrieveRemoteImage(context.Background(), nonExistingImageName, config.RegistryOptions{}, ""); err == nil {
 		t.Fatal("Expected call to fail because there is no manifest for this image.")
 	}
 
 	manifestCache[nonExistingImageName] = &mockImage{}
 
-	if image, err := RetrieveRemoteImage(nonExistingImageName, config.RegistryOptions{}, ""); image == nil || err != nil {
+	if image, err := RetrieveRemoteImage(context.Background(), nonExistingImageName, config.RegistryOptions{}, ""); image == nil || err != nil {
 		t.Fatal("Expected call to succeed because there is a manifest for this image in the cache.")
 	}
+
+	if _, err := RetrieveRemoteImage(context.Background(), nonExistingImageName, config.RegistryOptions{}, "linux/arm64"); err == nil {
+		t.Fatal("Expected call to fail because the cached manifest is for another platform.")
+	}
 }
 
 func Test_RetrieveRemoteImage_skipFallback(t *testing.T) {
@@ -202,7 +207,7 @@ func Test_RetrieveRemoteImage_skipFallback(t *testing.T) {
 		return &mockImage{}, nil
 	}
 
-	if _, err := RetrieveRemoteImage(image, opts, ""); err != nil {
+	if _, err := RetrieveRemoteImage(context.Background(), image, opts, ""); err != nil {
 		t.Fatalf("Expected call to succeed because fallback to default registry")
 	}
 
@@ -210,7 +215,7 @@ func Test_RetrieveRemoteImage_skipFallback(t *testing.T) {
 	// clean cached image
 	manifestCache = make(map[string]v1.Image)
 
-	if _, err := RetrieveRemoteImage(image, opts, ""); err == nil {
+	if _, err := RetrieveRemoteImage(context.Background(), image, opts, ""); err == nil {
 		t.Fatal("Expected call to fail because fallback to default registry is skipped")
 	}
 }
@@ -231,7 +236,7 @@ func Test_RetryRetrieveRemoteImageSucceeds(t *testing.T) {
 	// Clean cached image
 	manifestCache = make(map[string]v1.Image)
 
-	if _, err := RetrieveRemoteImage(image, opts, ""); err != nil {
+	if _, err := RetrieveRemoteImage(context.Background(), image, opts, ""); err != nil {
 		t.Fatal("Expected call to succeed because of retry")
 	}
 }
@@ -252,7 +257,7 @@ func Test_NoRetryRetrieveRemoteImageFails(t *testing.T) {
 	// Clean cached image
 	manifestCache = make(map[string]v1.Image)
 
-	if _, err := RetrieveRemoteImage(image, opts, ""); err == nil {
+	if _, err := RetrieveRemoteImage(context.Background(), image, opts, ""); err == nil {
 		t.Fatal("Expected call to fail because there is no retry")
 	}
 }
@@ -81,21 +81,15 @@ func ReplaceBase(img, base v1.Image) (v1.Image, error) {
 		}
 		if !h.EmptyLayer {
 			if li < len(baseLayers) {
-				mt, err := baseLayers[li].MediaType()
+				layer, mt, err := dockerLayer(baseLayers[li])
 				if err != nil {
 					return nil, err
 				}
-				switch mt {
-				case types.DockerLayer, types.DockerUncompressedLayer, types.DockerForeignLayer:
-					a.Layer = baseLayers[li]
-				case types.OCILayer:
-					a.Layer = &mediaTypeLayer{Layer: baseLayers[li], mediaType: types.DockerLayer}
-				case types.OCIUncompressedLayer:
-					a.Layer = &mediaTypeLayer{Layer: baseLayers[li], mediaType: types.DockerUncompressedLayer}
-				default:
+				if layer == nil {
 					logrus.Warnf("not preserving base layers: base image has %s layers, incompatible with a reproducible dockerv2 image", mt)
 					return img, nil
 				}
+				a.Layer = layer
 			} else {
 				a.Layer = imgLayers[li]
 			}
@@ -103,7 +97,81 @@ func ReplaceBase(img, base v1.Image) (v1.Image, error) {
 		}
 		addendums = append(addendums, a)
 	}
+	return restack(imgCfg, addendums)
+}
+
+// ReplaceLayers returns img with the layers at the given indices replaced by
+// the layers of src at the same indices, history and config are img's. Like
+// ReplaceBase it is a pure splice that trusts the caller's invariant that
+// those layers of img were derived from src's, ie. by mutate.Canonical.
+func ReplaceLayers(img, src v1.Image, indices []int) (v1.Image, error) {
+	if len(indices) == 0 {
+		return img, nil
+	}
+	imgCfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	imgLayers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+	srcLayers, err := src.Layers()
+	if err != nil {
+		return nil, err
+	}
+	assert.Assert("image.replacelayers.layer-count", len(srcLayers) == len(imgLayers),
+		"src has %d layers, img has %d", len(srcLayers), len(imgLayers))
+
+	replace := map[int]bool{}
+	for _, i := range indices {
+		replace[i] = true
+	}
+	addendums := make([]mutate.Addendum, 0, len(imgCfg.History))
+	li := 0
+	for _, h := range imgCfg.History {
+		a := mutate.Addendum{History: h}
+		if !h.EmptyLayer {
+			a.Layer = imgLayers[li]
+			if replace[li] {
+				layer, mt, err := dockerLayer(srcLayers[li])
+				if err != nil {
+					return nil, err
+				}
+				if layer == nil {
+					logrus.Warnf("not preserving layer %d: it is a %s layer, incompatible with a reproducible dockerv2 image", li, mt)
+				} else {
+					a.Layer = layer
+				}
+			}
+			li++
+		}
+		addendums = append(addendums, a)
+	}
+	return restack(imgCfg, addendums)
+}
+
+// dockerLayer returns l with its docker media type, or nil if its media type
+// has no docker equivalent.
+func dockerLayer(l v1.Layer) (v1.Layer, types.MediaType, error) {
+	mt, err := l.MediaType()
+	if err != nil {
+		return nil, "", err
+	}
+	switch mt {
+	case types.DockerLayer, types.DockerUncompressedLayer, types.DockerForeignLayer:
+		return l, mt, nil
+	case types.OCILayer:
+		return &mediaTypeLayer{Layer: l, mediaType: types.DockerLayer}, mt, nil
+	case types.OCIUncompressedLayer:
+		return &mediaTypeLayer{Layer: l, mediaType: types.DockerUncompressedLayer}, mt, nil
+	default:
+		return nil, mt, nil
+	}
+}
 
+// restack builds an image of addendums that otherwise keeps imgCfg.
+func restack(imgCfg *v1.ConfigFile, addendums []mutate.Addendum) (v1.Image, error) {
 	stacked, err := mutate.Append(empty.Image, addendums...)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,51 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/osscontainertools/kaniko/testutil"
+)
+
+func TestReplaceLayers(t *testing.T) {
+	img, err := random.Image(64, 3)
+	testutil.CheckNoError(t, err)
+	src, err := random.Image(64, 3)
+	testutil.CheckNoError(t, err)
+
+	got, err := ReplaceLayers(img, src, []int{1})
+	testutil.CheckNoError(t, err)
+
+	imgLayers, err := img.Layers()
+	testutil.CheckNoError(t, err)
+	srcLayers, err := src.Layers()
+	testutil.CheckNoError(t, err)
+	gotLayers, err := got.Layers()
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, 3, len(gotLayers))
+
+	for i, want := range []v1.Layer{imgLayers[0], srcLayers[1], imgLayers[2]} {
+		wantDiffID, err := want.DiffID()
+		testutil.CheckNoError(t, err)
+		gotDiffID, err := gotLayers[i].DiffID()
+		testutil.CheckNoError(t, err)
+		testutil.CheckDeepEqual(t, wantDiffID, gotDiffID)
+	}
+}
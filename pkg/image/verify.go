@@ -0,0 +1,112 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/osscontainertools/kaniko/pkg/image/remote"
+	"github.com/osscontainertools/kaniko/pkg/signing"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	retrieveDescriptor = remote.RetrieveDescriptor
+	retrieveSignatures = remote.RetrieveSignatures
+)
+
+type verifiedKey struct{}
+
+// WithVerifiedImages returns a copy of ctx that remembers the images verified
+// with it, a base is resolved more than once in a build.
+func WithVerifiedImages(ctx context.Context) context.Context {
+	return context.WithValue(ctx, verifiedKey{}, map[string]bool{})
+}
+
+// VerifyBaseImage checks that img, resolved from image, carries a cosign
+// signature by a key --verify-base-key or --verify-base-policy assign to its
+// repository. It does nothing unless one of them is set.
+func VerifyBaseImage(ctx context.Context, image string, img v1.Image, opts *config.KanikoOptions) error {
+	if opts.VerifyBaseKey == "" && opts.VerifyBasePolicy == "" {
+		return nil
+	}
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return err
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return err
+	}
+	id := ref.Context().Name() + "@" + digest.String()
+	verified, _ := ctx.Value(verifiedKey{}).(map[string]bool)
+	if verified[id] {
+		return nil
+	}
+	policy, err := signing.LoadPolicy(opts.VerifyBasePolicy, opts.VerifyBaseKey)
+	if err != nil {
+		return err
+	}
+	keys := policy.Keys(ref.Context())
+	if len(keys) == 0 {
+		return fmt.Errorf("verifying %s: no key for %s in --verify-base-policy", image, ref.Context())
+	}
+
+	// cosign signs the digest a reference resolves to, for a multi-platform
+	// image that is the index and img only one of its manifests.
+	digests := []v1.Hash{digest}
+	desc, index, err := retrieveDescriptor(ctx, ref, opts.RegistryOptions)
+	if err != nil {
+		return fmt.Errorf("verifying %s: %w", image, err)
+	}
+	if index != nil {
+		for _, m := range index.Manifests {
+			if m.Digest == digest {
+				digests = append(digests, desc.Digest)
+				break
+			}
+		}
+	}
+
+	for _, d := range digests {
+		sigs, err := retrieveSignatures(ctx, signing.Tag(ref.Context(), d), opts.RegistryOptions)
+		if err != nil {
+			return fmt.Errorf("retrieving signatures of %s: %w", image, err)
+		}
+		if sigs == nil {
+			continue
+		}
+		err = signing.VerifyImage(sigs, d, keys)
+		if errors.Is(err, signing.ErrNotSigned) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("verifying %s: %w", image, err)
+		}
+		logrus.Infof("Verified signature of %s@%s", ref.Context(), d)
+		if verified != nil {
+			verified[id] = true
+		}
+		return nil
+	}
+	return fmt.Errorf("verifying %s: %w for %s", image, signing.ErrNotSigned, digest)
+}
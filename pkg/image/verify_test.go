@@ -0,0 +1,107 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/osscontainertools/kaniko/pkg/signing"
+	"github.com/osscontainertools/kaniko/testutil"
+)
+
+func Test_VerifyBaseImage(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.CheckNoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	testutil.CheckNoError(t, err)
+	testutil.CheckNoError(t, os.WriteFile(filepath.Join(dir, "cosign.key"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	der, err = x509.MarshalPKIXPublicKey(&key.PublicKey)
+	testutil.CheckNoError(t, err)
+	pub := filepath.Join(dir, "cosign.pub")
+	testutil.CheckNoError(t, os.WriteFile(pub, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644))
+	signer, err := signing.LoadSigner(filepath.Join(dir, "cosign.key"), "")
+	testutil.CheckNoError(t, err)
+
+	img, err := random.Image(64, 1)
+	testutil.CheckNoError(t, err)
+	imgDigest, err := img.Digest()
+	testutil.CheckNoError(t, err)
+	indexDigest := v1.Hash{Algorithm: "sha256", Hex: "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}
+
+	repo := name.MustParseReference("gcr.io/foo/bar").Context()
+	sign := func(d v1.Hash) v1.Image {
+		payload, err := signing.Payload(repo, d)
+		testutil.CheckNoError(t, err)
+		sig, err := signer.Sign(payload)
+		testutil.CheckNoError(t, err)
+		sigs, err := signing.Append(nil, payload, sig)
+		testutil.CheckNoError(t, err)
+		return sigs
+	}
+
+	originalDescriptor, originalSignatures := retrieveDescriptor, retrieveSignatures
+	defer func() {
+		retrieveDescriptor, retrieveSignatures = originalDescriptor, originalSignatures
+	}()
+	retrieveDescriptor = func(_ context.Context, _ name.Reference, _ config.RegistryOptions) (v1.Descriptor, *v1.IndexManifest, error) {
+		return v1.Descriptor{Digest: indexDigest}, &v1.IndexManifest{Manifests: []v1.Descriptor{{Digest: imgDigest}}}, nil
+	}
+
+	tests := []struct {
+		name    string
+		signed  v1.Hash
+		opts    config.KanikoOptions
+		wantErr bool
+	}{
+		{name: "disabled"},
+		{name: "image signed", signed: imgDigest, opts: config.KanikoOptions{VerifyBaseKey: pub}},
+		{name: "index signed", signed: indexDigest, opts: config.KanikoOptions{VerifyBaseKey: pub}},
+		{name: "unsigned", opts: config.KanikoOptions{VerifyBaseKey: pub}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retrieveSignatures = func(_ context.Context, tag name.Tag, _ config.RegistryOptions) (v1.Image, error) {
+				if tt.signed.Hex == "" || tag != signing.Tag(repo, tt.signed) {
+					return nil, nil
+				}
+				return sign(tt.signed), nil
+			}
+			err := VerifyBaseImage(context.Background(), "gcr.io/foo/bar:latest", img, &tt.opts)
+			testutil.CheckError(t, tt.wantErr, err)
+		})
+	}
+
+	t.Run("no key for repository", func(t *testing.T) {
+		policy := filepath.Join(dir, "policy.json")
+		testutil.CheckNoError(t, os.WriteFile(policy, []byte(`{"quay.io": ["cosign.pub"]}`), 0o644))
+		err := VerifyBaseImage(context.Background(), "gcr.io/foo/bar:latest", img, &config.KanikoOptions{VerifyBasePolicy: policy})
+		testutil.CheckError(t, true, err)
+	})
+}
@@ -34,8 +34,15 @@ const (
 	FormatColor = "color"
 	// JSON format
 	FormatJSON = "json"
+	// Colored text format with collapsible sections for GitLab CI
+	FormatGitLab = "gitlab"
+	// Colored text format with collapsible groups for GitHub Actions
+	FormatGitHub = "github"
 )
 
+// outputFormat is the configured log format, captured process output follows it
+var outputFormat = FormatText
+
 // Configure sets the logrus logging level and formatter
 func Configure(level, format string, logTimestamp bool) error {
 	lvl, err := logrus.ParseLevel(level)
@@ -58,10 +65,19 @@ func Configure(level, format string, logTimestamp bool) error {
 		}
 	case FormatJSON:
 		formatter = &logrus.JSONFormatter{}
+	case FormatGitLab, FormatGitHub:
+		formatter = &ciFormatter{
+			Formatter: &logrus.TextFormatter{
+				ForceColors:   true,
+				FullTimestamp: logTimestamp,
+			},
+			format: format,
+		}
 	default:
-		return fmt.Errorf("not a valid log format: %q. Please specify one of (text, color, json)", format)
+		return fmt.Errorf("not a valid log format: %q. Please specify one of (text, color, json, gitlab, github)", format)
 	}
-	logrus.SetFormatter(formatter)
+	logrus.SetFormatter(&redactingFormatter{formatter})
+	outputFormat = format
 
 	return nil
 }
@@ -0,0 +1,209 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Step identifies the build instruction a line of process output belongs to.
+type Step struct {
+	// ID numbers the instruction across the whole build, starting at 1
+	ID int
+	// Stage is the stage name, or stage-<index> for unnamed stages
+	Stage string
+	// Index is the 1-based position of the instruction within its stage
+	Index int
+	// Total is the number of instructions in the stage
+	Total int
+}
+
+// String renders the step the way it prefixes output lines, ie. #3 [build 4/9]
+func (s Step) String() string {
+	return fmt.Sprintf("#%d [%s %d/%d]", s.ID, s.Stage, s.Index, s.Total)
+}
+
+// StepOutput captures the stdout and stderr of a process run for a Step.
+// Each line is prefixed with the step in text formats, or emitted as a
+// JSON event in the json format. If a log dir was given the unprefixed output
+// is additionally written to one file per step. Registered secrets are masked
+// in both.
+type StepOutput struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	stdout *lineWriter
+	stderr *lineWriter
+	file   *os.File
+}
+
+// for testing
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// NewStepOutput returns a StepOutput for step, writing per-step log files
+// below logDir unless it is empty.
+func NewStepOutput(step Step, logDir string) (*StepOutput, error) {
+	o := &StepOutput{}
+	var raw io.Writer
+	if logDir != "" {
+		if err := os.MkdirAll(logDir, 0o755); err != nil {
+			return nil, fmt.Errorf("creating run log dir: %w", err)
+		}
+		name := fmt.Sprintf("%03d-%s-%d.log", step.ID, step.Stage, step.Index)
+		f, err := os.Create(filepath.Join(logDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("creating run log file: %w", err)
+		}
+		o.file = f
+		raw = &syncWriter{w: f}
+	}
+	o.stdout = &lineWriter{out: stdout, raw: raw, step: step, stream: "stdout"}
+	o.stderr = &lineWriter{out: stderr, raw: raw, step: step, stream: "stderr"}
+	o.Stdout = o.stdout
+	o.Stderr = o.stderr
+	return o, nil
+}
+
+// Close flushes any unterminated trailing line and closes the log file.
+func (o *StepOutput) Close() error {
+	errs := []error{o.stdout.flush(), o.stderr.flush()}
+	if o.file != nil {
+		errs = append(errs, o.file.Close())
+	}
+	return errors.Join(errs...)
+}
+
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+type runEvent struct {
+	Time   string `json:"time"`
+	Level  string `json:"level"`
+	Msg    string `json:"msg"`
+	Step   int    `json:"step"`
+	Stage  string `json:"stage"`
+	Index  int    `json:"index"`
+	Total  int    `json:"total"`
+	Line   int    `json:"line"`
+	Stream string `json:"stream"`
+}
+
+// maxLineLength caps how much of a line is buffered, longer lines are
+// rendered in pieces of this length.
+const maxLineLength = 64 * 1024
+
+// lineWriter splits process output into lines, every complete line is
+// rendered with a single Write to out so lines of parallel writers don't interleave.
+// A carriage return ends a line too, progress bars redraw their line with it.
+type lineWriter struct {
+	out    io.Writer
+	raw    io.Writer
+	step   Step
+	stream string
+	buf    []byte
+	lines  int
+	// cr is set if the last line ended with \r, a \n right after it ends no line
+	cr bool
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for len(l.buf) > 0 {
+		if l.cr {
+			l.cr = false
+			if l.buf[0] == '\n' {
+				l.buf = l.buf[1:]
+				continue
+			}
+		}
+		i := bytes.IndexAny(l.buf, "\r\n")
+		if i < 0 && len(l.buf) < maxLineLength {
+			break
+		}
+		var line []byte
+		if i < 0 || i > maxLineLength {
+			line = l.buf[:maxLineLength]
+			l.buf = l.buf[maxLineLength:]
+		} else {
+			l.cr = l.buf[i] == '\r'
+			line = l.buf[:i]
+			l.buf = l.buf[i+1:]
+		}
+		if err := l.emit(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (l *lineWriter) flush() error {
+	if len(l.buf) == 0 {
+		return nil
+	}
+	line := l.buf
+	l.buf = nil
+	return l.emit(line)
+}
+
+func (l *lineWriter) emit(line []byte) error {
+	l.lines++
+	text := Redact(string(line))
+	if l.raw != nil {
+		if _, err := io.WriteString(l.raw, text+"\n"); err != nil {
+			return err
+		}
+	}
+	if outputFormat != FormatJSON {
+		_, err := fmt.Fprintf(l.out, "%s %s\n", l.step, text)
+		return err
+	}
+	b, err := json.Marshal(runEvent{
+		Time:   time.Now().Format(time.RFC3339),
+		Level:  "info",
+		Msg:    text,
+		Step:   l.step.ID,
+		Stage:  l.step.Stage,
+		Index:  l.step.Index,
+		Total:  l.step.Total,
+		Line:   l.lines,
+		Stream: l.stream,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = l.out.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/osscontainertools/kaniko/testutil"
+)
+
+func mockOutput(t *testing.T, format string) (*bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	origOut, origErr, origFormat := stdout, stderr, outputFormat
+	t.Cleanup(func() {
+		stdout, stderr, outputFormat = origOut, origErr, origFormat
+	})
+	stdout, stderr, outputFormat = &out, &errOut, format
+	return &out, &errOut
+}
+
+func TestStepOutput_Text(t *testing.T) {
+	out, errOut := mockOutput(t, FormatText)
+	step := Step{ID: 3, Stage: "build", Index: 4, Total: 9}
+
+	o, err := NewStepOutput(step, "")
+	testutil.CheckNoError(t, err)
+	o.Stdout.Write([]byte("hello\nwor"))
+	o.Stdout.Write([]byte("ld\r\npartial"))
+	o.Stderr.Write([]byte("oops\n"))
+	testutil.CheckNoError(t, o.Close())
+
+	testutil.CheckDeepEqual(t, "#3 [build 4/9] hello\n#3 [build 4/9] world\n#3 [build 4/9] partial\n", out.String())
+	testutil.CheckDeepEqual(t, "#3 [build 4/9] oops\n", errOut.String())
+}
+
+func TestStepOutput_CarriageReturnAndLongLines(t *testing.T) {
+	out, _ := mockOutput(t, FormatText)
+	step := Step{ID: 2, Stage: "build", Index: 1, Total: 1}
+
+	o, err := NewStepOutput(step, "")
+	testutil.CheckNoError(t, err)
+	o.Stdout.Write([]byte("10%\r50%\r"))
+	o.Stdout.Write([]byte("\n"))
+	o.Stdout.Write([]byte(strings.Repeat("x", maxLineLength+1)))
+	testutil.CheckNoError(t, o.Close())
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	testutil.CheckDeepEqual(t, 4, len(lines))
+	testutil.CheckDeepEqual(t, "#2 [build 1/1] 10%", lines[0])
+	testutil.CheckDeepEqual(t, "#2 [build 1/1] 50%", lines[1])
+	testutil.CheckDeepEqual(t, "#2 [build 1/1] "+strings.Repeat("x", maxLineLength), lines[2])
+	testutil.CheckDeepEqual(t, "#2 [build 1/1] x", lines[3])
+}
+
+func TestStepOutput_JSON(t *testing.T) {
+	_, errOut := mockOutput(t, FormatJSON)
+	step := Step{ID: 1, Stage: "stage-0", Index: 1, Total: 2}
+
+	o, err := NewStepOutput(step, "")
+	testutil.CheckNoError(t, err)
+	o.Stderr.Write([]byte("first\nsecond\n"))
+	testutil.CheckNoError(t, o.Close())
+
+	lines := strings.Split(strings.TrimSpace(errOut.String()), "\n")
+	testutil.CheckDeepEqual(t, 2, len(lines))
+	var ev runEvent
+	testutil.CheckNoError(t, json.Unmarshal([]byte(lines[1]), &ev))
+	ev.Time = ""
+	testutil.CheckDeepEqual(t, runEvent{
+		Level:  "info",
+		Msg:    "second",
+		Step:   1,
+		Stage:  "stage-0",
+		Index:  1,
+		Total:  2,
+		Line:   2,
+		Stream: "stderr",
+	}, ev)
+}
+
+func TestStepOutput_LogDir(t *testing.T) {
+	mockOutput(t, FormatText)
+	dir := filepath.Join(t.TempDir(), "logs")
+	step := Step{ID: 12, Stage: "build", Index: 2, Total: 5}
+
+	o, err := NewStepOutput(step, dir)
+	testutil.CheckNoError(t, err)
+	o.Stdout.Write([]byte("out\n"))
+	o.Stderr.Write([]byte("err\n"))
+	testutil.CheckNoError(t, o.Close())
+
+	b, err := os.ReadFile(filepath.Join(dir, "012-build-2.log"))
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, "out\nerr\n", string(b))
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Redacted replaces secret values in output
+	Redacted = "***"
+	// minSecretLength is the shortest value that gets masked, shorter values
+	// would mangle unrelated output.
+	minSecretLength = 4
+)
+
+var redactor = struct {
+	mu       sync.RWMutex
+	values   map[string]struct{}
+	replacer *strings.Replacer
+}{values: map[string]struct{}{}}
+
+// RegisterSecret masks value, as well as its base64 and URL-encoded forms,
+// in RUN output and in kaniko's own log output from now on. RUN output is
+// masked line by line, so each line of a multi-line value is masked on its own.
+func RegisterSecret(value string) {
+	var forms []string
+	for _, v := range []string{value, strings.TrimSpace(value)} {
+		if len(v) < minSecretLength {
+			continue
+		}
+		forms = append(forms,
+			v,
+			base64.StdEncoding.EncodeToString([]byte(v)),
+			base64.RawStdEncoding.EncodeToString([]byte(v)),
+			base64.URLEncoding.EncodeToString([]byte(v)),
+			base64.RawURLEncoding.EncodeToString([]byte(v)),
+			url.QueryEscape(v),
+			url.PathEscape(v),
+		)
+	}
+	if strings.Contains(strings.TrimSpace(value), "\n") {
+		for line := range strings.Lines(value) {
+			if line = strings.TrimSpace(line); len(line) >= minSecretLength {
+				forms = append(forms, line)
+			}
+		}
+	}
+	if len(forms) == 0 {
+		return
+	}
+
+	redactor.mu.Lock()
+	defer redactor.mu.Unlock()
+	changed := false
+	for _, f := range forms {
+		if _, ok := redactor.values[f]; !ok {
+			redactor.values[f] = struct{}{}
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	// longest first, so a value is never partially masked by one of its substrings
+	values := slices.SortedFunc(maps.Keys(redactor.values), func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+	})
+	oldnew := make([]string, 0, 2*len(values))
+	for _, v := range values {
+		oldnew = append(oldnew, v, Redacted)
+	}
+	redactor.replacer = strings.NewReplacer(oldnew...)
+}
+
+// ResetSecrets forgets all registered secrets.
+func ResetSecrets() {
+	redactor.mu.Lock()
+	defer redactor.mu.Unlock()
+	redactor.values = map[string]struct{}{}
+	redactor.replacer = nil
+}
+
+// Redact masks all registered secrets in s.
+func Redact(s string) string {
+	redactor.mu.RLock()
+	r := redactor.replacer
+	redactor.mu.RUnlock()
+	if r == nil {
+		return s
+	}
+	return r.Replace(s)
+}
+
+// redactingFormatter masks registered secrets in everything logged through logrus.
+// The message and fields are masked before they are formatted, the formatter
+// may quote or escape a secret into a form that no longer matches.
+type redactingFormatter struct {
+	logrus.Formatter
+}
+
+func (f *redactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	redacted := *entry
+	redacted.Message = Redact(entry.Message)
+	redacted.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		if s, ok := v.(string); ok {
+			v = Redact(s)
+		} else if s := fmt.Sprint(v); Redact(s) != s {
+			v = Redact(s)
+		}
+		redacted.Data[k] = v
+	}
+	return f.Formatter.Format(&redacted)
+}
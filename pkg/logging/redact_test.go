@@ -0,0 +1,102 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/osscontainertools/kaniko/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRedact(t *testing.T) {
+	t.Cleanup(ResetSecrets)
+	RegisterSecret("s3cr3t/p@ss\n")
+	RegisterSecret("abc")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "token=s3cr3t/p@ss", "token=***"},
+		{"base64", "Authorization: Basic czNjcjN0L3BAc3M=", "Authorization: Basic ***"},
+		{"base64 url", "czNjcjN0L3BAc3M", "***"},
+		{"query escaped", "https://host/?pw=s3cr3t%2Fp%40ss", "https://host/?pw=***"},
+		{"short values are kept", "abc", "abc"},
+		{"unrelated", "nothing to see", "nothing to see"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.CheckDeepEqual(t, tt.want, Redact(tt.in))
+		})
+	}
+}
+
+func TestRedact_Logrus(t *testing.T) {
+	t.Cleanup(ResetSecrets)
+	RegisterSecret("hunter22")
+
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&redactingFormatter{&logrus.TextFormatter{DisableColors: true, DisableTimestamp: true}})
+	logger.Infof("Args: [-c echo hunter22]")
+
+	testutil.CheckDeepEqual(t, "level=info msg=\"Args: [-c echo ***]\"\n", buf.String())
+}
+
+func TestRedact_LogrusJSON(t *testing.T) {
+	t.Cleanup(ResetSecrets)
+	RegisterSecret("hunter22\"quoted")
+
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&redactingFormatter{&logrus.JSONFormatter{DisableTimestamp: true}})
+	logger.WithField("arg", "hunter22\"quoted").Infof("Args: [-c echo hunter22\"quoted]")
+
+	testutil.CheckDeepEqual(t, `{"arg":"***","level":"info","msg":"Args: [-c echo ***]"}`+"\n", buf.String())
+}
+
+func TestStepOutput_Redacts(t *testing.T) {
+	t.Cleanup(ResetSecrets)
+	out, _ := mockOutput(t, FormatText)
+	RegisterSecret("hunter22")
+
+	o, err := NewStepOutput(Step{ID: 1, Stage: "stage-0", Index: 1, Total: 1}, "")
+	testutil.CheckNoError(t, err)
+	o.Stdout.Write([]byte("pass=hun"))
+	o.Stdout.Write([]byte("ter22\n"))
+	testutil.CheckNoError(t, o.Close())
+
+	testutil.CheckDeepEqual(t, "#1 [stage-0 1/1] pass=***\n", out.String())
+}
+
+func TestStepOutput_RedactsMultiLineSecrets(t *testing.T) {
+	t.Cleanup(ResetSecrets)
+	out, _ := mockOutput(t, FormatText)
+	RegisterSecret("-----BEGIN KEY-----\nMIIEvQIBADANBg\nkqhkiG9w0BAQEF\n-----END KEY-----\n")
+
+	o, err := NewStepOutput(Step{ID: 1, Stage: "stage-0", Index: 1, Total: 1}, "")
+	testutil.CheckNoError(t, err)
+	o.Stdout.Write([]byte("MIIEvQIBADANBg\n  kqhkiG9w0BAQEF\n"))
+	testutil.CheckNoError(t, o.Close())
+
+	testutil.CheckDeepEqual(t, "#1 [stage-0 1/1] ***\n#1 [stage-0 1/1]   ***\n", out.String())
+}
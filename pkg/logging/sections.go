@@ -0,0 +1,133 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sectionField carries a section marker to the ciFormatter, which renders it
+// in place of the log line.
+const sectionField = "kaniko.section"
+
+// Section is a collapsible part of the log in the gitlab and github formats,
+// it renders nothing in the other formats.
+type Section struct {
+	name   string
+	header string
+	nested bool
+	start  time.Time
+}
+
+type sectionMarker struct {
+	*Section
+	end bool
+	at  time.Time
+}
+
+// StartStage opens the section for a build stage.
+func StartStage(index int, header string) *Section {
+	return startSection(fmt.Sprintf("stage_%d", index), header, false)
+}
+
+// StartStep opens the section for an instruction, nested in its stage.
+// The cache status is part of the header as it is known up front.
+func StartStep(step Step, header string, cached bool) *Section {
+	if cached {
+		header += " [CACHED]"
+	}
+	return startSection(fmt.Sprintf("step_%d", step.ID), step.String()+" "+header, true)
+}
+
+func startSection(name, header string, nested bool) *Section {
+	s := &Section{name: name, header: header, nested: nested, start: time.Now()}
+	s.log(false)
+	return s
+}
+
+// End closes the section.
+func (s *Section) End() {
+	s.log(true)
+}
+
+// log writes the marker to the logrus output directly, it is not a log line
+// that --verbosity may filter: a missing start marker would leave the CI log
+// with unbalanced sections.
+func (s *Section) log(end bool) {
+	if outputFormat != FormatGitLab && outputFormat != FormatGitHub {
+		return
+	}
+	logger := logrus.StandardLogger()
+	entry := logger.WithField(sectionField, sectionMarker{Section: s, end: end, at: time.Now()})
+	entry.Level = logrus.InfoLevel
+	entry.Message = s.header
+	b, err := logger.Formatter.Format(entry)
+	if err != nil {
+		logrus.Warnf("Formatting section marker: %v", err)
+		return
+	}
+	if _, err := logger.Out.Write(b); err != nil {
+		logrus.Warnf("Writing section marker: %v", err)
+	}
+}
+
+// ciFormatter renders section markers for CI systems with collapsible logs
+// and defers everything else to the text formatter.
+type ciFormatter struct {
+	logrus.Formatter
+	format string
+}
+
+func (f *ciFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	m, ok := entry.Data[sectionField].(sectionMarker)
+	if !ok {
+		return f.Formatter.Format(entry)
+	}
+	duration := m.at.Sub(m.start).Round(time.Millisecond)
+	switch f.format {
+	case FormatGitLab:
+		// https://docs.gitlab.com/ci/jobs/job_logs/#custom-collapsible-sections
+		// GitLab shows the duration next to the header, derived from the timestamps.
+		if m.end {
+			return fmt.Appendf(nil, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", m.at.Unix(), m.name), nil
+		}
+		collapsed := ""
+		if m.nested {
+			collapsed = "[collapsed=true]"
+		}
+		return fmt.Appendf(nil, "\x1b[0Ksection_start:%d:%s%s\r\x1b[0K%s\n", m.at.Unix(), m.name, collapsed, m.header), nil
+	default:
+		// https://docs.github.com/actions/reference/workflow-commands-for-github-actions#grouping-log-lines
+		// Groups can't nest, so only steps are grouped and stages get a plain header line.
+		// Unlike GitLab's, the header can't carry the duration: it is rendered as soon as
+		// the group opens, and holding it back until the step ends would also hold back
+		// the step's output. The duration closes the group instead.
+		if !m.nested {
+			if m.end {
+				return fmt.Appendf(nil, "%s done in %s\n", m.header, duration), nil
+			}
+			return fmt.Appendf(nil, "%s\n", m.header), nil
+		}
+		if m.end {
+			return fmt.Appendf(nil, "done in %s\n::endgroup::\n", duration), nil
+		}
+		return fmt.Appendf(nil, "::group::%s\n", m.header), nil
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/osscontainertools/kaniko/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCIFormatter(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	end := start.Add(1500 * time.Millisecond)
+	stage := &Section{name: "stage_0", header: "[build] FROM alpine", start: start}
+	step := &Section{name: "step_3", header: "#3 [build 1/2] RUN make [CACHED]", nested: true, start: start}
+
+	tests := []struct {
+		format string
+		marker sectionMarker
+		want   string
+	}{
+		{FormatGitLab, sectionMarker{Section: stage, at: start}, "\x1b[0Ksection_start:1700000000:stage_0\r\x1b[0K[build] FROM alpine\n"},
+		{FormatGitLab, sectionMarker{Section: step, at: start}, "\x1b[0Ksection_start:1700000000:step_3[collapsed=true]\r\x1b[0K#3 [build 1/2] RUN make [CACHED]\n"},
+		{FormatGitLab, sectionMarker{Section: step, end: true, at: end}, "\x1b[0Ksection_end:1700000001:step_3\r\x1b[0K\n"},
+		{FormatGitHub, sectionMarker{Section: stage, at: start}, "[build] FROM alpine\n"},
+		{FormatGitHub, sectionMarker{Section: stage, end: true, at: end}, "[build] FROM alpine done in 1.5s\n"},
+		{FormatGitHub, sectionMarker{Section: step, at: start}, "::group::#3 [build 1/2] RUN make [CACHED]\n"},
+		{FormatGitHub, sectionMarker{Section: step, end: true, at: end}, "done in 1.5s\n::endgroup::\n"},
+	}
+	for _, tt := range tests {
+		f := &ciFormatter{Formatter: &logrus.TextFormatter{}, format: tt.format}
+		entry := logrus.WithField(sectionField, tt.marker)
+		got, err := f.Format(entry)
+		testutil.CheckNoError(t, err)
+		testutil.CheckDeepEqual(t, tt.want, string(got))
+	}
+}
+
+func TestStartStep_Header(t *testing.T) {
+	step := Step{ID: 3, Stage: "build", Index: 1, Total: 2}
+	s := StartStep(step, "RUN make", true)
+	testutil.CheckDeepEqual(t, "#3 [build 1/2] RUN make [CACHED]", s.header)
+	testutil.CheckDeepEqual(t, "step_3", s.name)
+}
+
+func TestSection_IgnoresLevel(t *testing.T) {
+	var out bytes.Buffer
+	logrus.SetOutput(&out)
+	defer logrus.SetOutput(os.Stderr)
+	testutil.CheckNoError(t, Configure("error", FormatGitLab, false))
+	defer func() { testutil.CheckNoError(t, Configure("info", FormatText, false)) }()
+
+	s := StartStage(0, "[build] FROM alpine")
+	logrus.Info("filtered")
+	s.End()
+	got := out.String()
+	if !strings.Contains(got, "section_start:") || !strings.Contains(got, "section_end:") {
+		t.Errorf("expected both section markers at --verbosity=error, got %q", got)
+	}
+	if strings.Contains(got, "filtered") {
+		t.Errorf("expected info lines to be filtered, got %q", got)
+	}
+}
@@ -59,6 +59,14 @@ func RecordImage(img v1.Image, repo name.Repository) {
 	}
 }
 
+// Reset forgets every recorded source. A build starts from an empty map, a repository
+// proven by an earlier build in the same process may have been deleted since.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	sources = map[v1.Hash][]name.Repository{}
+}
+
 // PlannedDigest names a layer that does not exist yet by the cache key that decides where it
 // will end up. Cache keys are sha256 hex like a digest, and never collide with one in practice.
 func PlannedDigest(cacheKey string) v1.Hash {
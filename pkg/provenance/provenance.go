@@ -0,0 +1,138 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package provenance holds the in-toto statement with a SLSA v1 provenance
+// predicate kaniko attaches to an image with --provenance.
+//
+// See https://slsa.dev/spec/v1.0/provenance for the meaning of each field.
+// What goes into the external and internal parameters is kaniko's own, the
+// buildType URI points at its documentation.
+package provenance
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	StatementType = "https://in-toto.io/Statement/v1"
+	PredicateType = "https://slsa.dev/provenance/v1"
+	// MediaType is the artifact type of a statement pushed as a referrer.
+	MediaType = "application/vnd.in-toto+json"
+	// PredicateTypeAnnotation carries the predicate type on the referrer
+	// manifest, so clients can tell statements apart without pulling them.
+	PredicateTypeAnnotation = "in-toto.io/predicate-type"
+
+	BuildType = "https://github.com/osscontainertools/kaniko#flag---provenance"
+	BuilderID = "https://github.com/osscontainertools/kaniko"
+)
+
+type Statement struct {
+	Type          string               `json:"_type"`
+	Subject       []ResourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     Provenance           `json:"predicate"`
+}
+
+type ResourceDescriptor struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+type Provenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   ExternalParameters   `json:"externalParameters"`
+	InternalParameters   InternalParameters   `json:"internalParameters"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies"`
+}
+
+// ExternalParameters are the inputs of the build the user controls.
+type ExternalParameters struct {
+	Context    string   `json:"context,omitempty"`
+	Dockerfile string   `json:"dockerfile"`
+	Target     []string `json:"target,omitempty"`
+	Platform   string   `json:"platform"`
+	// BuildArgs are only recorded in max mode, with registered secrets masked.
+	BuildArgs map[string]string `json:"buildArgs,omitempty"`
+}
+
+// InternalParameters are set by the kaniko deployment rather than the build.
+type InternalParameters struct {
+	FeatureFlags []string `json:"featureFlags,omitempty"`
+	// Stages are only recorded in max mode.
+	Stages []Stage `json:"stages,omitempty"`
+}
+
+// Stage is a stage of the Dockerfile and the base it was built on.
+type Stage struct {
+	Name     string `json:"name"`
+	Base     string `json:"base"`
+	Platform string `json:"platform,omitempty"`
+	Digest   string `json:"digest,omitempty"`
+}
+
+type RunDetails struct {
+	Builder  Builder   `json:"builder"`
+	Metadata *Metadata `json:"metadata,omitempty"`
+}
+
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+type Metadata struct {
+	StartedOn  string `json:"startedOn,omitempty"`
+	FinishedOn string `json:"finishedOn,omitempty"`
+}
+
+// ImageURI returns the purl of an image reference, as buildkit records base
+// images: Docker Hub images without their registry and library/ prefix.
+func ImageURI(ref, platform string) string {
+	uri := "pkg:docker/" + ref
+	if r, err := name.ParseReference(ref, name.WeakValidation); err == nil {
+		repo := r.Context().RepositoryStr()
+		if reg := r.Context().RegistryStr(); reg == name.DefaultRegistry {
+			repo = strings.TrimPrefix(repo, "library/")
+		} else {
+			repo = reg + "/" + repo
+		}
+		// the tag or digest takes the place of the purl version
+		uri = "pkg:docker/" + repo + "@" + r.Identifier()
+	}
+	if platform != "" {
+		uri += "?platform=" + url.QueryEscape(platform)
+	}
+	return uri
+}
+
+// Digest turns "algorithm:hex" into a DigestSet, nil if d is not a digest.
+func Digest(d string) map[string]string {
+	h, err := v1.NewHash(d)
+	if err != nil {
+		return nil
+	}
+	return map[string]string{h.Algorithm: h.Hex}
+}
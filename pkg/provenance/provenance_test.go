@@ -0,0 +1,46 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"testing"
+
+	"github.com/osscontainertools/kaniko/testutil"
+)
+
+func TestImageURI(t *testing.T) {
+	tests := []struct {
+		ref      string
+		platform string
+		want     string
+	}{
+		{"debian:bookworm", "linux/amd64", "pkg:docker/debian@bookworm?platform=linux%2Famd64"},
+		{"alpine", "", "pkg:docker/alpine@latest"},
+		{"bitnami/redis:7", "", "pkg:docker/bitnami/redis@7"},
+		{"gcr.io/distroless/static@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", "linux/arm64/v8", "pkg:docker/gcr.io/distroless/static@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef?platform=linux%2Farm64%2Fv8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			testutil.CheckDeepEqual(t, tt.want, ImageURI(tt.ref, tt.platform))
+		})
+	}
+}
+
+func TestDigest(t *testing.T) {
+	testutil.CheckDeepEqual(t, map[string]string{"sha256": "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}, Digest("sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"))
+	testutil.CheckDeepEqual(t, map[string]string(nil), Digest("bookworm"))
+}
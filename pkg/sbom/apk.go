@@ -0,0 +1,45 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"path/filepath"
+)
+
+// scanApk reads /lib/apk/db/installed, its records hold one single letter
+// field per line: P is the name, V the version, A the architecture and L
+// the license.
+func scanApk(root string, d distro) ([]Package, error) {
+	b, err := readFile(filepath.Join(root, "lib/apk/db/installed"))
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []Package
+	for _, s := range stanzas(string(b), ":") {
+		if s["P"] == "" {
+			continue
+		}
+		pkgs = append(pkgs, Package{
+			Type:    "apk",
+			Name:    s["P"],
+			Version: s["V"],
+			License: s["L"],
+			PURL:    purl("apk", d.namespace("alpine"), s["P"], s["V"], d.qualifiers(s["A"])),
+		})
+	}
+	return pkgs, nil
+}
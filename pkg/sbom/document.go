@@ -0,0 +1,271 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/github/go-spdx/v2/spdxexp"
+	"github.com/osscontainertools/kaniko/pkg/version"
+)
+
+// licenseExpression reports whether license is an SPDX license expression
+// over the SPDX license list, "MIT" or "GPL-2.0-only OR MIT". Anything else,
+// "GPLv2+" or "ASL 2.0", is free text, which SPDX documents only carry as an
+// extracted license and CycloneDX documents as a license name.
+func licenseExpression(license string) bool {
+	if license == "" {
+		return false
+	}
+	valid, _ := spdxexp.ValidateLicensesWithOptions([]string{license}, spdxexp.ValidateLicensesOptions{
+		FailAllLicenseRefs:  true,
+		FailAllDocumentRefs: true,
+	})
+	return valid
+}
+
+// name is the name a document gives the image, its repository when it has
+// one.
+func (s Subject) name() string {
+	if s.Name == "" {
+		return "image"
+	}
+	return s.Name
+}
+
+func (s Subject) purl() string {
+	q := url.Values{}
+	if s.Name != "" {
+		q.Set("repository_url", s.Name)
+	}
+	return purl("oci", "", path.Base(s.name()), s.Digest.String(), q)
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+	// ExtractedLicenses are the free text licenses packages declare
+	ExtractedLicenses []spdxExtractedLicense `json:"hasExtractedLicensingInfos,omitempty"`
+}
+
+type spdxExtractedLicense struct {
+	LicenseID     string `json:"licenseId"`
+	ExtractedText string `json:"extractedText"`
+	Name          string `json:"name"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// encodeSPDX renders an SPDX 2.3 document: the image as a package that
+// CONTAINS every package found.
+func encodeSPDX(pkgs []Package, subject Subject, created time.Time) ([]byte, error) {
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              subject.name(),
+		DocumentNamespace: "https://github.com/osscontainertools/kaniko/spdx/" + subject.Digest.Hex,
+		CreationInfo: spdxCreationInfo{
+			Created:  created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: kaniko-" + version.Version()},
+		},
+		Packages: []spdxPackage{{
+			SPDXID:           "SPDXRef-Image",
+			Name:             subject.name(),
+			VersionInfo:      subject.Digest.String(),
+			DownloadLocation: "NOASSERTION",
+			LicenseDeclared:  "NOASSERTION",
+			ExternalRefs:     []spdxExternalRef{spdxPurl(subject.purl())},
+		}},
+		Relationships: []spdxRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: "SPDXRef-Image",
+		}},
+	}
+	extracted := map[string]string{}
+	for i, p := range pkgs {
+		id := fmt.Sprintf("SPDXRef-Package-%s-%d", p.Type, i+1)
+		license := "NOASSERTION"
+		switch {
+		case p.License == "":
+		case licenseExpression(p.License):
+			license = p.License
+		default:
+			ref, ok := extracted[p.License]
+			if !ok {
+				ref = fmt.Sprintf("LicenseRef-%d", len(extracted)+1)
+				extracted[p.License] = ref
+				doc.ExtractedLicenses = append(doc.ExtractedLicenses, spdxExtractedLicense{
+					LicenseID:     ref,
+					ExtractedText: p.License,
+					Name:          p.License,
+				})
+			}
+			license = ref
+		}
+		doc.Packages = append(doc.Packages, spdxPackage{
+			SPDXID:           id,
+			Name:             p.Name,
+			VersionInfo:      p.Version,
+			DownloadLocation: "NOASSERTION",
+			LicenseDeclared:  license,
+			ExternalRefs:     []spdxExternalRef{spdxPurl(p.PURL)},
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      "SPDXRef-Image",
+			RelationshipType:   "CONTAINS",
+			RelatedSPDXElement: id,
+		})
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func spdxPurl(p string) spdxExternalRef {
+	return spdxExternalRef{
+		ReferenceCategory: "PACKAGE-MANAGER",
+		ReferenceType:     "purl",
+		ReferenceLocator:  p,
+	}
+}
+
+type cdxDocument struct {
+	BOMFormat    string         `json:"bomFormat"`
+	SpecVersion  string         `json:"specVersion"`
+	SerialNumber string         `json:"serialNumber"`
+	Version      int            `json:"version"`
+	Metadata     cdxMetadata    `json:"metadata"`
+	Components   []cdxComponent `json:"components"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type     string       `json:"type"`
+	BOMRef   string       `json:"bom-ref,omitempty"`
+	Name     string       `json:"name"`
+	Version  string       `json:"version,omitempty"`
+	PURL     string       `json:"purl,omitempty"`
+	Licenses []cdxLicense `json:"licenses,omitempty"`
+}
+
+type cdxLicense struct {
+	License    *cdxLicenseName `json:"license,omitempty"`
+	Expression string          `json:"expression,omitempty"`
+}
+
+type cdxLicenseName struct {
+	Name string `json:"name"`
+}
+
+// encodeCycloneDX renders a CycloneDX 1.5 document with the image as its
+// metadata component.
+func encodeCycloneDX(pkgs []Package, subject Subject, created time.Time) ([]byte, error) {
+	doc := cdxDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: serialNumber(subject),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: created.UTC().Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{{
+				Type:    "application",
+				Name:    "kaniko",
+				Version: version.Version(),
+			}}},
+			Component: cdxComponent{
+				Type:    "container",
+				BOMRef:  subject.purl(),
+				Name:    subject.name(),
+				Version: subject.Digest.String(),
+				PURL:    subject.purl(),
+			},
+		},
+		Components: []cdxComponent{},
+	}
+	for _, p := range pkgs {
+		c := cdxComponent{
+			Type:    "library",
+			BOMRef:  p.PURL,
+			Name:    p.Name,
+			Version: p.Version,
+			PURL:    p.PURL,
+		}
+		switch {
+		case p.License == "":
+		case licenseExpression(p.License):
+			c.Licenses = []cdxLicense{{Expression: p.License}}
+		default:
+			c.Licenses = []cdxLicense{{License: &cdxLicenseName{Name: p.License}}}
+		}
+		doc.Components = append(doc.Components, c)
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// serialNumber derives the document's UUID from the image digest, so the
+// same image always gets the same document.
+func serialNumber(subject Subject) string {
+	h := sha256.Sum256([]byte("cyclonedx:" + subject.Digest.String()))
+	h[6] = h[6]&0x0f | 0x80 // version 8, custom
+	h[8] = h[8]&0x3f | 0x80 // RFC 9562 variant
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// scanDpkg reads /var/lib/dpkg/status, and the status.d directory distroless
+// images keep one file per package in instead.
+func scanDpkg(root string, d distro) ([]Package, error) {
+	paths := []string{filepath.Join(root, "var/lib/dpkg/status")}
+	entries, _ := os.ReadDir(filepath.Join(root, "var/lib/dpkg/status.d"))
+	for _, e := range entries {
+		// distroless also keeps <pkg>.md5sums next to each status file
+		if e.Type().IsRegular() && !strings.HasSuffix(e.Name(), ".md5sums") {
+			paths = append(paths, filepath.Join(root, "var/lib/dpkg/status.d", e.Name()))
+		}
+	}
+	var pkgs []Package
+	for _, p := range paths {
+		b, err := readFile(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range stanzas(string(b), ": ") {
+			// status.d entries carry no Status field, they are installed by construction
+			if st, ok := s["Status"]; ok && !strings.HasSuffix(st, " installed") {
+				continue
+			}
+			if s["Package"] == "" {
+				continue
+			}
+			pkgs = append(pkgs, Package{
+				Type:    "deb",
+				Name:    s["Package"],
+				Version: s["Version"],
+				PURL:    purl("deb", d.namespace("debian"), s["Package"], s["Version"], d.qualifiers(s["Architecture"])),
+			})
+		}
+	}
+	return pkgs, nil
+}
+
+// stanzas splits the blank line separated records of a dpkg or apk database
+// into their fields. Continuation lines, which only multi-line fields like
+// Description have, are dropped.
+func stanzas(s, sep string) []map[string]string {
+	var out []map[string]string
+	cur := map[string]string{}
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			if len(cur) > 0 {
+				out = append(out, cur)
+				cur = map[string]string{}
+			}
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, sep); ok {
+			cur[k] = strings.TrimSpace(v)
+		}
+	}
+	if len(cur) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// appendGo adds the modules linked into the Go binary at p. Only executable
+// ELF files are opened by buildinfo, everything else is skipped on the first
+// four bytes.
+func appendGo(pkgs []Package, p string, e fs.DirEntry) []Package {
+	info, err := e.Info()
+	if err != nil || info.Mode()&0o111 == 0 {
+		return pkgs
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return pkgs
+	}
+	magic := make([]byte, 4)
+	_, err = io.ReadFull(f, magic)
+	f.Close()
+	if err != nil || !bytes.Equal(magic, []byte("\x7fELF")) {
+		return pkgs
+	}
+	bi, err := buildinfo.ReadFile(p)
+	if err != nil {
+		// not a Go binary
+		return pkgs
+	}
+	logrus.Debugf("Reading Go modules from %s", p)
+	if v := strings.TrimPrefix(bi.GoVersion, "go"); v != "" {
+		pkgs = append(pkgs, goPackage("stdlib", v))
+	}
+	// a binary built from a checkout rather than by go install has no main
+	// module version to report
+	if bi.Main.Path != "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		pkgs = append(pkgs, goPackage(bi.Main.Path, bi.Main.Version))
+	}
+	for _, dep := range bi.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		pkgs = append(pkgs, goPackage(dep.Path, dep.Version))
+	}
+	return pkgs
+}
+
+func goPackage(module, version string) Package {
+	namespace, name := path.Split(module)
+	return Package{
+		Type:    "golang",
+		Name:    module,
+		Version: version,
+		PURL:    purl("golang", strings.TrimSuffix(namespace, "/"), name, version, nil),
+	}
+}
+
+var pypiSeparators = regexp.MustCompile(`[-_.]+`)
+
+// appendPython adds the distribution described by the core metadata file at
+// p, the METADATA of a .dist-info or the PKG-INFO of an .egg-info.
+func appendPython(pkgs []Package, p string) []Package {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return pkgs
+	}
+	fields := stanzas(string(b), ": ")
+	if len(fields) == 0 || fields[0]["Name"] == "" || fields[0]["Version"] == "" {
+		return pkgs
+	}
+	m := fields[0]
+	license := m["License-Expression"]
+	if l := m["License"]; license == "" && l != "UNKNOWN" {
+		license = l
+	}
+	// PEP 503 normalisation, which is what the pypi purl type asks for
+	name := pypiSeparators.ReplaceAllString(strings.ToLower(m["Name"]), "-")
+	return append(pkgs, Package{
+		Type:    "pypi",
+		Name:    m["Name"],
+		Version: m["Version"],
+		License: license,
+		PURL:    purl("pypi", "", name, m["Version"], nil),
+	})
+}
+
+// appendNode adds the package whose manifest is at p, if it is installed
+// under a node_modules directory. A project's own package.json is not a
+// dependency of anything.
+func appendNode(pkgs []Package, p string) []Package {
+	dir := filepath.Dir(p)
+	parent := filepath.Dir(dir)
+	if strings.HasPrefix(filepath.Base(parent), "@") {
+		parent = filepath.Dir(parent)
+	}
+	if filepath.Base(parent) != "node_modules" {
+		return pkgs
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return pkgs
+	}
+	var m struct {
+		Name    string          `json:"name"`
+		Version string          `json:"version"`
+		License json.RawMessage `json:"license"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil || m.Name == "" || m.Version == "" {
+		return pkgs
+	}
+	// license is an SPDX expression, or in old packages an object with a type
+	var license string
+	if json.Unmarshal(m.License, &license) != nil {
+		var l struct {
+			Type string `json:"type"`
+		}
+		_ = json.Unmarshal(m.License, &l)
+		license = l.Type
+	}
+	namespace, name := "", m.Name
+	if scope, n, ok := strings.Cut(m.Name, "/"); ok {
+		namespace, name = scope, n
+	}
+	return append(pkgs, Package{
+		Type:    "npm",
+		Name:    m.Name,
+		Version: m.Version,
+		License: license,
+		PURL:    purl("npm", namespace, name, m.Version, nil),
+	})
+}
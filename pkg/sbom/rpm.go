@@ -0,0 +1,185 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// rpm header tags and types, from rpm's include/rpm/rpmtag.h.
+const (
+	rpmTagName    = 1000
+	rpmTagVersion = 1001
+	rpmTagRelease = 1002
+	rpmTagEpoch   = 1003
+	rpmTagLicense = 1014
+	rpmTagArch    = 1022
+
+	rpmTypeInt32      = 4
+	rpmTypeString     = 6
+	rpmTypeI18NString = 9
+)
+
+// rpmDBPaths are where rpm keeps its SQLite database, the first is the home
+// since Fedora 36 and /var/lib/rpm is only a symlink to it there.
+var rpmDBPaths = []string{
+	"usr/lib/sysimage/rpm/rpmdb.sqlite",
+	"var/lib/rpm/rpmdb.sqlite",
+}
+
+// rpmLegacyDBPaths are the Berkeley DB database of older systems and the ndb
+// database SUSE uses, neither of which we can read.
+var rpmLegacyDBPaths = []string{
+	"var/lib/rpm/Packages",
+	"var/lib/rpm/Packages.db",
+	"usr/lib/sysimage/rpm/Packages.db",
+}
+
+// scanRpm reads the Packages table of the rpm database, one header blob per
+// installed package. The Berkeley DB and ndb backends older and SUSE systems
+// use are not read.
+func scanRpm(root string, d distro) ([]Package, error) {
+	var b []byte
+	for _, p := range rpmDBPaths {
+		var err error
+		b, err = readFile(filepath.Join(root, p))
+		if err != nil {
+			return nil, err
+		}
+		if b != nil {
+			break
+		}
+	}
+	if b == nil {
+		for _, p := range rpmLegacyDBPaths {
+			if _, err := os.Stat(filepath.Join(root, p)); err == nil {
+				logrus.Warnf("Not listing rpm packages: /%s is not a SQLite rpm database, the only kind kaniko can read", p)
+				break
+			}
+		}
+		return nil, nil
+	}
+	db, err := openSQLite(b)
+	if err != nil {
+		return nil, fmt.Errorf("reading rpm database: %w", err)
+	}
+	rows, err := db.rows("Packages")
+	if err != nil {
+		return nil, fmt.Errorf("reading rpm database: %w", err)
+	}
+	var pkgs []Package
+	for _, row := range rows {
+		var blob []byte
+		for _, v := range row {
+			if bv, ok := v.([]byte); ok {
+				blob = bv
+			}
+		}
+		h, err := parseRpmHeader(blob)
+		if err != nil {
+			return nil, fmt.Errorf("reading rpm database: %w", err)
+		}
+		// imported signing keys are stored as packages too
+		if h.name == "" || h.name == "gpg-pubkey" {
+			continue
+		}
+		version := h.version + "-" + h.release
+		q := d.qualifiers(h.arch)
+		if h.epoch != "" {
+			q.Set("epoch", h.epoch)
+		}
+		pkgs = append(pkgs, Package{
+			Type:    "rpm",
+			Name:    h.name,
+			Version: version,
+			License: h.license,
+			PURL:    purl("rpm", d.namespace("redhat"), h.name, version, q),
+		})
+	}
+	return pkgs, nil
+}
+
+type rpmHeader struct {
+	name, version, release, epoch, arch, license string
+}
+
+var errRpmHeader = errors.New("malformed rpm header")
+
+// parseRpmHeader decodes the tags we need from a header blob: a count of
+// index entries and the size of the data store, both big-endian int32, then
+// the 16 byte index entries {tag, type, offset, count} and the data store
+// they point into.
+func parseRpmHeader(b []byte) (rpmHeader, error) {
+	var h rpmHeader
+	if len(b) < 8 {
+		return h, errRpmHeader
+	}
+	il := int(binary.BigEndian.Uint32(b))
+	dl := int(binary.BigEndian.Uint32(b[4:]))
+	if il < 0 || dl < 0 || il > len(b)/16 || 8+16*il+dl > len(b) {
+		return h, errRpmHeader
+	}
+	store := b[8+16*il : 8+16*il+dl]
+	for i := range il {
+		e := b[8+16*i:]
+		tag := binary.BigEndian.Uint32(e)
+		typ := binary.BigEndian.Uint32(e[4:])
+		off := int(binary.BigEndian.Uint32(e[8:]))
+		if off < 0 || off >= len(store) {
+			continue
+		}
+		var v string
+		switch typ {
+		case rpmTypeString, rpmTypeI18NString:
+			end := bytes.IndexByte(store[off:], 0)
+			if end < 0 {
+				return h, errRpmHeader
+			}
+			v = string(store[off : off+end])
+		case rpmTypeInt32:
+			if off+4 > len(store) {
+				return h, errRpmHeader
+			}
+			v = strconv.Itoa(int(int32(binary.BigEndian.Uint32(store[off:]))))
+		default:
+			continue
+		}
+		switch tag {
+		case rpmTagName:
+			h.name = v
+		case rpmTagVersion:
+			h.version = v
+		case rpmTagRelease:
+			h.release = v
+		case rpmTagEpoch:
+			h.epoch = v
+		case rpmTagLicense:
+			h.license = v
+		case rpmTagArch:
+			h.arch = v
+		}
+	}
+	return h, nil
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sbom lists the packages installed in a root filesystem and encodes
+// them as an SPDX or CycloneDX document.
+//
+// Only what package managers and language toolchains record on disk is read:
+// the dpkg, apk and rpm databases, the build info Go links into binaries, and
+// Python and Node package metadata. Files that arrived by any other route,
+// a curl | tar in a RUN or a COPY of a vendored library, are not in the list.
+package sbom
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	SPDX      = "spdx"
+	CycloneDX = "cyclonedx"
+)
+
+// Formats are the values accepted by --sbom.
+var Formats = []string{SPDX, CycloneDX}
+
+// Package is one installed package.
+type Package struct {
+	// Type is the purl type: deb, apk, rpm, golang, pypi or npm.
+	Type    string
+	Name    string
+	Version string
+	License string
+	PURL    string
+}
+
+// Subject is the image a document describes.
+type Subject struct {
+	Name   string
+	Digest v1.Hash
+}
+
+// distro is the ID and VERSION_ID of /etc/os-release, which purls of system
+// packages carry as their namespace and distro qualifier.
+type distro struct {
+	id      string
+	version string
+}
+
+// Scan lists the packages installed under root. skip is called with every
+// directory path and prunes the walk when it returns true.
+func Scan(root string, skip func(string) bool) ([]Package, error) {
+	d := readOSRelease(root)
+	var pkgs []Package
+	for _, scan := range []func(string, distro) ([]Package, error){scanDpkg, scanApk, scanRpm} {
+		found, err := scan(root, d)
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, found...)
+	}
+	err := filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			// unreadable directories can't hold anything we could read either
+			return nil //nolint:nilerr
+		}
+		if e.IsDir() {
+			if path != root && skip != nil && skip(path) {
+				return filepath.SkipDir
+			}
+			switch {
+			case strings.HasSuffix(e.Name(), ".dist-info"):
+				pkgs = appendPython(pkgs, filepath.Join(path, "METADATA"))
+			case strings.HasSuffix(e.Name(), ".egg-info"):
+				pkgs = appendPython(pkgs, filepath.Join(path, "PKG-INFO"))
+			}
+			return nil
+		}
+		if !e.Type().IsRegular() {
+			return nil
+		}
+		switch {
+		case strings.HasSuffix(e.Name(), ".egg-info"):
+			pkgs = appendPython(pkgs, path)
+		case e.Name() == "package.json":
+			pkgs = appendNode(pkgs, path)
+		default:
+			pkgs = appendGo(pkgs, path, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dedupe(pkgs), nil
+}
+
+// dedupe sorts pkgs by purl and drops repeats, a module linked into several
+// binaries or a package installed twice lists once.
+func dedupe(pkgs []Package) []Package {
+	slices.SortFunc(pkgs, func(a, b Package) int {
+		return cmp.Compare(a.PURL, b.PURL)
+	})
+	return slices.CompactFunc(pkgs, func(a, b Package) bool {
+		return a.PURL == b.PURL
+	})
+}
+
+func readOSRelease(root string) distro {
+	var d distro
+	for _, p := range []string{"etc/os-release", "usr/lib/os-release"} {
+		b, err := os.ReadFile(filepath.Join(root, p))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			k, v, ok := strings.Cut(strings.TrimSpace(line), "=")
+			if !ok {
+				continue
+			}
+			v = strings.Trim(v, `"'`)
+			switch k {
+			case "ID":
+				d.id = v
+			case "VERSION_ID":
+				d.version = v
+			}
+		}
+		break
+	}
+	return d
+}
+
+// qualifiers returns the distro qualifier of a system package purl.
+func (d distro) qualifiers(arch string) url.Values {
+	q := url.Values{}
+	if arch != "" {
+		q.Set("arch", arch)
+	}
+	if d.id != "" && d.version != "" {
+		q.Set("distro", d.id+"-"+d.version)
+	}
+	return q
+}
+
+// namespace is the purl namespace of a system package, falling back to the
+// package manager's home distribution when there is no os-release.
+func (d distro) namespace(fallback string) string {
+	if d.id != "" {
+		return d.id
+	}
+	return fallback
+}
+
+// purl formats a package URL, see https://github.com/package-url/purl-spec.
+// namespace may hold several slash separated segments.
+func purl(typ, namespace, name, version string, qualifiers url.Values) string {
+	var b strings.Builder
+	b.WriteString("pkg:" + typ + "/")
+	if namespace != "" {
+		for _, seg := range strings.Split(namespace, "/") {
+			b.WriteString(purlEscape(seg) + "/")
+		}
+	}
+	b.WriteString(purlEscape(name))
+	if version != "" {
+		b.WriteString("@" + purlEscape(version))
+	}
+	if len(qualifiers) > 0 {
+		b.WriteString("?" + qualifiers.Encode())
+	}
+	return b.String()
+}
+
+func purlEscape(s string) string {
+	return strings.NewReplacer("@", "%40", "+", "%2B").Replace(url.PathEscape(s))
+}
+
+// MediaType returns the media type of a document in format.
+func MediaType(format string) string {
+	switch format {
+	case SPDX:
+		return "application/spdx+json"
+	case CycloneDX:
+		return "application/vnd.cyclonedx+json"
+	}
+	return ""
+}
+
+// Encode renders pkgs as a document in format describing subject.
+func Encode(format string, pkgs []Package, subject Subject, created time.Time) ([]byte, error) {
+	switch format {
+	case SPDX:
+		return encodeSPDX(pkgs, subject, created)
+	case CycloneDX:
+		return encodeCycloneDX(pkgs, subject, created)
+	}
+	return nil, fmt.Errorf("unknown sbom format %q, must be one of %s", format, strings.Join(Formats, ", "))
+}
+
+// readFile reads a package database, a missing one is not an error: the
+// package manager just isn't installed.
+func readFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	logrus.Debugf("Reading packages from %s", path)
+	return b, nil
+}
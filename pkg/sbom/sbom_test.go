@@ -0,0 +1,228 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/osscontainertools/kaniko/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func purls(pkgs []Package) []string {
+	var out []string
+	for _, p := range pkgs {
+		out = append(out, p.PURL)
+	}
+	return out
+}
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	err := testutil.SetupFiles(root, map[string]string{
+		"etc/os-release": "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nID=debian\nVERSION_ID=\"12\"\n",
+		"var/lib/dpkg/status": `Package: libc6
+Status: install ok installed
+Architecture: amd64
+Version: 2.36-9+deb12u4
+Description: GNU C Library: Shared libraries
+ Contains the standard libraries that are used by nearly all programs on
+ the system.
+
+Package: removed
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.0
+`,
+		"var/lib/dpkg/status.d/tzdata":                                             "Package: tzdata\nVersion: 2024a-0+deb12u1\nArchitecture: all\n",
+		"var/lib/dpkg/status.d/tzdata.md5sums":                                     "d41d8cd98f00b204e9800998ecf8427e  usr/share/zoneinfo/UTC\n",
+		"usr/lib/python3/dist-packages/Requests_OAuthlib-1.3.1.dist-info/METADATA": "Metadata-Version: 2.1\nName: requests_oauthlib\nVersion: 1.3.1\nLicense: ISC\n\nlong description\nName: not-a-field\n",
+		"usr/lib/python3/dist-packages/six-1.16.0.egg-info":                        "Metadata-Version: 1.2\nName: six\nVersion: 1.16.0\nLicense: MIT License\n",
+		"app/package.json":                                      `{"name": "app", "version": "0.0.1"}`,
+		"app/node_modules/left-pad/package.json":                `{"name": "left-pad", "version": "1.3.0", "license": "WTFPL"}`,
+		"app/node_modules/@types/node/package.json":             `{"name": "@types/node", "version": "20.11.0", "license": {"type": "MIT"}}`,
+		"app/node_modules/left-pad/node_modules/x/package.json": `{"name": "x", "version": "1.0.0"}`,
+		"kaniko/node_modules/skipped/package.json":              `{"name": "skipped", "version": "1.0.0"}`,
+	})
+	testutil.CheckNoError(t, err)
+
+	pkgs, err := Scan(root, func(p string) bool { return p == filepath.Join(root, "kaniko") })
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, []string{
+		"pkg:deb/debian/libc6@2.36-9%2Bdeb12u4?arch=amd64&distro=debian-12",
+		"pkg:deb/debian/tzdata@2024a-0%2Bdeb12u1?arch=all&distro=debian-12",
+		"pkg:npm/%40types/node@20.11.0",
+		"pkg:npm/left-pad@1.3.0",
+		"pkg:npm/x@1.0.0",
+		"pkg:pypi/requests-oauthlib@1.3.1",
+		"pkg:pypi/six@1.16.0",
+	}, purls(pkgs))
+	licenses := map[string]string{}
+	for _, p := range pkgs {
+		licenses[p.Name] = p.License
+	}
+	testutil.CheckDeepEqual(t, "MIT", licenses["@types/node"])
+	testutil.CheckDeepEqual(t, "MIT License", licenses["six"])
+}
+
+func TestScan_Apk(t *testing.T) {
+	root := t.TempDir()
+	err := testutil.SetupFiles(root, map[string]string{
+		"etc/os-release": "ID=alpine\nVERSION_ID=3.19.1\n",
+		"lib/apk/db/installed": `C:Q1nTCXQwl1LT5uXEbrxvfmfpO9xvc=
+P:musl
+V:1.2.4_git20230717-r4
+A:x86_64
+L:MIT
+o:musl
+
+P:busybox
+V:1.36.1-r15
+A:x86_64
+L:GPL-2.0-only
+`,
+	})
+	testutil.CheckNoError(t, err)
+
+	pkgs, err := Scan(root, nil)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, []Package{
+		{Type: "apk", Name: "busybox", Version: "1.36.1-r15", License: "GPL-2.0-only", PURL: "pkg:apk/alpine/busybox@1.36.1-r15?arch=x86_64&distro=alpine-3.19.1"},
+		{Type: "apk", Name: "musl", Version: "1.2.4_git20230717-r4", License: "MIT", PURL: "pkg:apk/alpine/musl@1.2.4_git20230717-r4?arch=x86_64&distro=alpine-3.19.1"},
+	}, pkgs)
+}
+
+func TestScan_Rpm(t *testing.T) {
+	root := t.TempDir()
+	db, err := os.ReadFile("testdata/rpmdb.sqlite")
+	testutil.CheckNoError(t, err)
+	err = testutil.SetupFiles(root, map[string]string{
+		"etc/os-release":           "ID=fedora\nVERSION_ID=40\n",
+		"var/lib/rpm/rpmdb.sqlite": string(db),
+	})
+	testutil.CheckNoError(t, err)
+
+	pkgs, err := Scan(root, nil)
+	testutil.CheckNoError(t, err)
+	// the fixture holds bash with an overflowing header, openssl-libs with
+	// an epoch, a gpg-pubkey and enough filler to need an interior page
+	testutil.CheckDeepEqual(t, 62, len(pkgs))
+	testutil.CheckDeepEqual(t, Package{Type: "rpm", Name: "bash", Version: "5.2.26-3.fc40", License: "GPL-3.0-or-later", PURL: "pkg:rpm/fedora/bash@5.2.26-3.fc40?arch=x86_64&distro=fedora-40"}, pkgs[0])
+	testutil.CheckDeepEqual(t, "pkg:rpm/fedora/openssl-libs@3.2.1-2.fc40?arch=x86_64&distro=fedora-40&epoch=1", pkgs[1].PURL)
+	if slices.ContainsFunc(pkgs, func(p Package) bool { return p.Name == "gpg-pubkey" }) {
+		t.Error("gpg-pubkey should not be listed")
+	}
+}
+
+func TestScan_RpmBerkeleyDB(t *testing.T) {
+	root := t.TempDir()
+	err := testutil.SetupFiles(root, map[string]string{
+		"etc/os-release":       "ID=centos\nVERSION_ID=7\n",
+		"var/lib/rpm/Packages": "\x00\x06\x15\x61",
+	})
+	testutil.CheckNoError(t, err)
+
+	var out bytes.Buffer
+	logrus.SetOutput(&out)
+	defer logrus.SetOutput(os.Stderr)
+
+	pkgs, err := Scan(root, nil)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, 0, len(pkgs))
+	if !strings.Contains(out.String(), "/var/lib/rpm/Packages is not a SQLite rpm database") {
+		t.Errorf("expected a warning about the Berkeley DB database, got %q", out.String())
+	}
+}
+
+func TestScan_Go(t *testing.T) {
+	exe, err := os.Executable()
+	testutil.CheckNoError(t, err)
+	b, err := os.ReadFile(exe)
+	testutil.CheckNoError(t, err)
+	root := t.TempDir()
+	testutil.CheckNoError(t, os.MkdirAll(filepath.Join(root, "usr/bin"), 0o755))
+	testutil.CheckNoError(t, os.WriteFile(filepath.Join(root, "usr/bin/app"), b, 0o755))
+	// the same binary without the executable bit is not looked at
+	testutil.CheckNoError(t, os.WriteFile(filepath.Join(root, "usr/bin/data"), b, 0o644))
+
+	pkgs, err := Scan(root, nil)
+	testutil.CheckNoError(t, err)
+	if !slices.ContainsFunc(pkgs, func(p Package) bool { return p.Type == "golang" && p.Name == "stdlib" }) {
+		t.Errorf("expected the stdlib of the test binary, got %v", purls(pkgs))
+	}
+}
+
+func TestEncode(t *testing.T) {
+	pkgs := []Package{
+		{Type: "apk", Name: "musl", Version: "1.2.4-r4", License: "MIT", PURL: "pkg:apk/alpine/musl@1.2.4-r4"},
+		{Type: "pypi", Name: "six", Version: "1.16.0", License: "MIT License", PURL: "pkg:pypi/six@1.16.0"},
+		{Type: "rpm", Name: "bash", Version: "5.2.26-3.fc40", License: "GPLv2+", PURL: "pkg:rpm/fedora/bash@5.2.26-3.fc40"},
+		{Type: "pypi", Name: "attrs", Version: "23.2.0", License: "MIT License", PURL: "pkg:pypi/attrs@23.2.0"},
+		{Type: "deb", Name: "libc6", Version: "2.36-9", License: "GPL-2.0-only OR LGPL-2.1-or-later", PURL: "pkg:deb/debian/libc6@2.36-9"},
+		{Type: "deb", Name: "base-files", Version: "12.4", PURL: "pkg:deb/debian/base-files@12.4"},
+	}
+	subject := Subject{
+		Name:   "gcr.io/foo/bar",
+		Digest: v1.Hash{Algorithm: "sha256", Hex: "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+	}
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	b, err := Encode(SPDX, pkgs, subject, created)
+	testutil.CheckNoError(t, err)
+	var spdx spdxDocument
+	testutil.CheckNoError(t, json.Unmarshal(b, &spdx))
+	testutil.CheckDeepEqual(t, "2026-01-02T03:04:05Z", spdx.CreationInfo.Created)
+	testutil.CheckDeepEqual(t, 7, len(spdx.Packages))
+	testutil.CheckDeepEqual(t, "pkg:oci/bar@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef?repository_url=gcr.io%2Ffoo%2Fbar", spdx.Packages[0].ExternalRefs[0].ReferenceLocator)
+	testutil.CheckDeepEqual(t, "MIT", spdx.Packages[1].LicenseDeclared)
+	// licenses off the SPDX license list are extracted, once each
+	testutil.CheckDeepEqual(t, "LicenseRef-1", spdx.Packages[2].LicenseDeclared)
+	testutil.CheckDeepEqual(t, "LicenseRef-2", spdx.Packages[3].LicenseDeclared)
+	testutil.CheckDeepEqual(t, "LicenseRef-1", spdx.Packages[4].LicenseDeclared)
+	testutil.CheckDeepEqual(t, []spdxExtractedLicense{
+		{LicenseID: "LicenseRef-1", ExtractedText: "MIT License", Name: "MIT License"},
+		{LicenseID: "LicenseRef-2", ExtractedText: "GPLv2+", Name: "GPLv2+"},
+	}, spdx.ExtractedLicenses)
+	testutil.CheckDeepEqual(t, "GPL-2.0-only OR LGPL-2.1-or-later", spdx.Packages[5].LicenseDeclared)
+	testutil.CheckDeepEqual(t, "NOASSERTION", spdx.Packages[6].LicenseDeclared)
+	testutil.CheckDeepEqual(t, spdxRelationship{"SPDXRef-Image", "CONTAINS", spdx.Packages[2].SPDXID}, spdx.Relationships[2])
+
+	b, err = Encode(CycloneDX, pkgs, subject, created)
+	testutil.CheckNoError(t, err)
+	var cdx cdxDocument
+	testutil.CheckNoError(t, json.Unmarshal(b, &cdx))
+	testutil.CheckDeepEqual(t, "container", cdx.Metadata.Component.Type)
+	testutil.CheckDeepEqual(t, []cdxLicense{{Expression: "MIT"}}, cdx.Components[0].Licenses)
+	testutil.CheckDeepEqual(t, []cdxLicense{{License: &cdxLicenseName{Name: "MIT License"}}}, cdx.Components[1].Licenses)
+	testutil.CheckDeepEqual(t, []cdxLicense{{License: &cdxLicenseName{Name: "GPLv2+"}}}, cdx.Components[2].Licenses)
+	testutil.CheckDeepEqual(t, []cdxLicense{{Expression: "GPL-2.0-only OR LGPL-2.1-or-later"}}, cdx.Components[4].Licenses)
+	testutil.CheckDeepEqual(t, 0, len(cdx.Components[5].Licenses))
+	b2, err := Encode(CycloneDX, pkgs, subject, created)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, string(b), string(b2))
+
+	_, err = Encode("swid", pkgs, subject, created)
+	testutil.CheckError(t, true, err)
+}
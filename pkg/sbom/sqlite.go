@@ -0,0 +1,298 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// sqliteDB reads table rows straight out of a SQLite database file. rpm keeps
+// its database in SQLite since 4.16 and there is no driver vendored, but all
+// a scan needs is a full walk of one table's b-tree, which the file format
+// makes simple enough: no indexes, no journal replay, no writes.
+//
+// A database with a live -wal file can be missing its newest transactions;
+// rpm checkpoints on close, so a finished package install never leaves one.
+type sqliteDB struct {
+	data     []byte
+	pageSize int
+	usable   int
+}
+
+var errSQLiteCorrupt = errors.New("malformed sqlite database")
+
+func openSQLite(data []byte) (*sqliteDB, error) {
+	if len(data) < 100 || !bytes.HasPrefix(data, []byte("SQLite format 3\x00")) {
+		return nil, errors.New("not a sqlite database")
+	}
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, errSQLiteCorrupt
+	}
+	if enc := binary.BigEndian.Uint32(data[56:60]); enc > 1 {
+		return nil, fmt.Errorf("unsupported sqlite text encoding %d", enc)
+	}
+	return &sqliteDB{
+		data:     data,
+		pageSize: pageSize,
+		usable:   pageSize - int(data[20]),
+	}, nil
+}
+
+func (db *sqliteDB) page(n uint32) ([]byte, error) {
+	start := (int(n) - 1) * db.pageSize
+	if n == 0 || start+db.pageSize > len(db.data) {
+		return nil, errSQLiteCorrupt
+	}
+	return db.data[start : start+db.pageSize], nil
+}
+
+// rows returns every row of the named table.
+func (db *sqliteDB) rows(table string) ([][]any, error) {
+	var root uint32
+	err := db.walk(1, map[uint32]bool{}, func(row []any) error {
+		// sqlite_schema: type, name, tbl_name, rootpage, sql
+		if len(row) < 4 || row[0] != "table" || row[1] != table {
+			return nil
+		}
+		if n, ok := row[3].(int64); ok && n > 0 && n <= math.MaxUint32 {
+			root = uint32(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if root == 0 {
+		return nil, fmt.Errorf("no table %q", table)
+	}
+	var rows [][]any
+	err = db.walk(root, map[uint32]bool{}, func(row []any) error {
+		rows = append(rows, row)
+		return nil
+	})
+	return rows, err
+}
+
+// walk visits the rows of the table b-tree rooted at page n in rowid order.
+func (db *sqliteDB) walk(n uint32, seen map[uint32]bool, fn func([]any) error) error {
+	if seen[n] {
+		return errSQLiteCorrupt
+	}
+	seen[n] = true
+	p, err := db.page(n)
+	if err != nil {
+		return err
+	}
+	hdr := 0
+	if n == 1 {
+		hdr = 100
+	}
+	if len(p) < hdr+12 {
+		return errSQLiteCorrupt
+	}
+	cells := int(binary.BigEndian.Uint16(p[hdr+3:]))
+	switch p[hdr] {
+	case 0x0d: // table leaf
+		for i := range cells {
+			off, err := cellOffset(p, hdr+8, i)
+			if err != nil {
+				return err
+			}
+			payload, err := db.leafPayload(p[off:])
+			if err != nil {
+				return err
+			}
+			row, err := decodeRecord(payload)
+			if err != nil {
+				return err
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+	case 0x05: // table interior
+		for i := range cells {
+			off, err := cellOffset(p, hdr+12, i)
+			if err != nil {
+				return err
+			}
+			if off+4 > len(p) {
+				return errSQLiteCorrupt
+			}
+			if err := db.walk(binary.BigEndian.Uint32(p[off:]), seen, fn); err != nil {
+				return err
+			}
+		}
+		return db.walk(binary.BigEndian.Uint32(p[hdr+8:]), seen, fn)
+	default:
+		return fmt.Errorf("page %d: not a table b-tree page: %w", n, errSQLiteCorrupt)
+	}
+	return nil
+}
+
+func cellOffset(p []byte, array, i int) (int, error) {
+	at := array + 2*i
+	if at+2 > len(p) {
+		return 0, errSQLiteCorrupt
+	}
+	off := int(binary.BigEndian.Uint16(p[at:]))
+	if off >= len(p) {
+		return 0, errSQLiteCorrupt
+	}
+	return off, nil
+}
+
+// leafPayload returns the record held by a table leaf cell, following the
+// overflow chain for records too large to fit on the page.
+func (db *sqliteDB) leafPayload(cell []byte) ([]byte, error) {
+	size, n := sqliteVarint(cell)
+	if n == 0 || size > uint64(len(db.data)) {
+		return nil, errSQLiteCorrupt
+	}
+	_, m := sqliteVarint(cell[n:]) // rowid
+	if m == 0 {
+		return nil, errSQLiteCorrupt
+	}
+	cell = cell[n+m:]
+	total := int(size)
+
+	// Spill computation from the file format spec, section 1.6.
+	maxLocal := db.usable - 35
+	local := total
+	if total > maxLocal {
+		minLocal := (db.usable-12)*32/255 - 23
+		local = minLocal + (total-minLocal)%(db.usable-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+	if len(cell) < local {
+		return nil, errSQLiteCorrupt
+	}
+	payload := make([]byte, 0, total)
+	payload = append(payload, cell[:local]...)
+	if local == total {
+		return payload, nil
+	}
+	if len(cell) < local+4 {
+		return nil, errSQLiteCorrupt
+	}
+	next := binary.BigEndian.Uint32(cell[local:])
+	for len(payload) < total {
+		p, err := db.page(next)
+		if err != nil {
+			return nil, err
+		}
+		chunk := p[4:db.usable]
+		if rest := total - len(payload); len(chunk) > rest {
+			chunk = chunk[:rest]
+		}
+		payload = append(payload, chunk...)
+		next = binary.BigEndian.Uint32(p)
+	}
+	return payload, nil
+}
+
+// decodeRecord decodes a record into nil, int64, float64, string or []byte
+// values. An INTEGER PRIMARY KEY column reads as nil as its value is the rowid.
+func decodeRecord(rec []byte) ([]any, error) {
+	hdrLen, n := sqliteVarint(rec)
+	if n == 0 || hdrLen > uint64(len(rec)) {
+		return nil, errSQLiteCorrupt
+	}
+	var types []uint64
+	for off := n; off < int(hdrLen); {
+		t, m := sqliteVarint(rec[off:hdrLen])
+		if m == 0 {
+			return nil, errSQLiteCorrupt
+		}
+		types = append(types, t)
+		off += m
+	}
+	body := rec[hdrLen:]
+	row := make([]any, 0, len(types))
+	for _, t := range types {
+		var size int
+		switch {
+		case t == 0, t == 8, t == 9:
+		case t <= 4:
+			size = int(t)
+		case t == 5:
+			size = 6
+		case t == 6, t == 7:
+			size = 8
+		case t >= 12:
+			size = int((t - 12) / 2)
+		default:
+			return nil, errSQLiteCorrupt
+		}
+		if size > len(body) {
+			return nil, errSQLiteCorrupt
+		}
+		v := body[:size]
+		body = body[size:]
+		switch {
+		case t == 0:
+			row = append(row, nil)
+		case t == 8:
+			row = append(row, int64(0))
+		case t == 9:
+			row = append(row, int64(1))
+		case t == 7:
+			row = append(row, math.Float64frombits(binary.BigEndian.Uint64(v)))
+		case t <= 6:
+			// big-endian two's complement, sign extended from the top byte
+			i := int64(int8(v[0]))
+			for _, b := range v[1:] {
+				i = i<<8 | int64(b)
+			}
+			row = append(row, i)
+		case t%2 == 0:
+			row = append(row, v)
+		default:
+			row = append(row, string(v))
+		}
+	}
+	return row, nil
+}
+
+// sqliteVarint decodes a SQLite varint, returning the value and its length,
+// or a length of 0 when b is too short.
+func sqliteVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 8; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	if len(b) < 9 {
+		return 0, 0
+	}
+	return v<<8 | uint64(b[8]), 9
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package signing signs images the way `cosign sign --key` does, so that
+// `cosign verify --key` accepts them.
+//
+// A signature is a simple signing payload naming the repository and the
+// manifest digest, signed with the key and stored as a layer of an image
+// tagged sha256-<hex>.sig in the same repository. Each signer adds a layer
+// rather than replacing the image, so signatures from other keys survive.
+package signing
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// PasswordEnv holds the password of an encrypted cosign key, as for cosign.
+	PasswordEnv = "COSIGN_PASSWORD"
+
+	// SignatureAnnotation carries the base64 signature of a payload layer.
+	SignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	payloadMediaType types.MediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	payloadType                      = "cosign container image signature"
+)
+
+// Signer signs payloads with a private key.
+type Signer struct {
+	key crypto.Signer
+}
+
+// LoadSigner reads a PEM private key: a cosign key encrypted with password,
+// or an unencrypted PKCS#8, EC or PKCS#1 RSA key.
+func LoadSigner(path, password string) (*Signer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s holds no PEM encoded key", path)
+	}
+	var key any
+	switch block.Type {
+	case "ENCRYPTED SIGSTORE PRIVATE KEY", "ENCRYPTED COSIGN PRIVATE KEY":
+		der, err := decrypt(block.Bytes, password)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting %s: %w", path, err)
+		}
+		key, err = x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, err
+		}
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey, *rsa.PrivateKey, ed25519.PrivateKey:
+		return &Signer{key: k.(crypto.Signer)}, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported key type %T", path, key)
+}
+
+// encryptedKey is the JSON inside a cosign key's PEM block, see
+// github.com/secure-systems-lab/go-securesystemslib/encrypted.
+type encryptedKey struct {
+	KDF struct {
+		Name   string `json:"name"`
+		Params struct {
+			N int `json:"N"`
+			R int `json:"r"`
+			P int `json:"p"`
+		} `json:"params"`
+		Salt []byte `json:"salt"`
+	} `json:"kdf"`
+	Cipher struct {
+		Name  string `json:"name"`
+		Nonce []byte `json:"nonce"`
+	} `json:"cipher"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func decrypt(b []byte, password string) ([]byte, error) {
+	var k encryptedKey
+	if err := json.Unmarshal(b, &k); err != nil {
+		return nil, err
+	}
+	if k.KDF.Name != "scrypt" || k.Cipher.Name != "nacl/secretbox" {
+		return nil, fmt.Errorf("unsupported encryption %s with %s", k.Cipher.Name, k.KDF.Name)
+	}
+	if len(k.Cipher.Nonce) != 24 {
+		return nil, errors.New("malformed nonce")
+	}
+	secret, err := scrypt.Key([]byte(password), k.KDF.Salt, k.KDF.Params.N, k.KDF.Params.R, k.KDF.Params.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	var nonce [24]byte
+	var key [32]byte
+	copy(nonce[:], k.Cipher.Nonce)
+	copy(key[:], secret)
+	der, ok := secretbox.Open(nil, k.Ciphertext, &nonce, &key)
+	if !ok {
+		return nil, fmt.Errorf("wrong password, set it in %s", PasswordEnv)
+	}
+	return der, nil
+}
+
+// Sign signs payload, ECDSA and RSA keys over its SHA-256 as cosign does.
+func (s *Signer) Sign(payload []byte) ([]byte, error) {
+	if _, ok := s.key.(ed25519.PrivateKey); ok {
+		return s.key.Sign(rand.Reader, payload, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(payload)
+	return s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// Public returns the public key signatures verify against.
+func (s *Signer) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// simpleSigning is the payload of a signature, the "atomic container
+// signature" format of containers/image.
+type simpleSigning struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]any `json:"optional"`
+}
+
+// Payload returns the simple signing payload for the manifest digest in repo.
+func Payload(repo name.Repository, digest v1.Hash) ([]byte, error) {
+	var p simpleSigning
+	p.Critical.Identity.DockerReference = repo.Name()
+	p.Critical.Image.DockerManifestDigest = digest.String()
+	p.Critical.Type = payloadType
+	return json.Marshal(p)
+}
+
+// Tag is where the signatures of digest are kept in repo.
+func Tag(repo name.Repository, digest v1.Hash) name.Tag {
+	return repo.Tag(fmt.Sprintf("%s-%s.sig", digest.Algorithm, digest.Hex))
+}
+
+// Append returns sigs with a layer holding payload and its signature added.
+// sigs is the image already at the signature tag, nil if there is none. A
+// signature already present is not added twice.
+func Append(sigs v1.Image, payload, signature []byte) (v1.Image, error) {
+	encoded := base64.StdEncoding.EncodeToString(signature)
+	if sigs == nil {
+		sigs = mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), types.OCIConfigJSON)
+	} else {
+		m, err := sigs.Manifest()
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range m.Layers {
+			if l.Annotations[SignatureAnnotation] == encoded {
+				return sigs, nil
+			}
+		}
+	}
+	layer, err := newPayloadLayer(payload)
+	if err != nil {
+		return nil, err
+	}
+	return mutate.Append(sigs, mutate.Addendum{
+		Layer:       layer,
+		Annotations: map[string]string{SignatureAnnotation: encoded},
+	})
+}
+
+// payloadLayer is a layer stored uncompressed, the payload as is.
+type payloadLayer struct {
+	payload []byte
+	digest  v1.Hash
+}
+
+func newPayloadLayer(payload []byte) (*payloadLayer, error) {
+	h, _, err := v1.SHA256(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	return &payloadLayer{payload: payload, digest: h}, nil
+}
+
+func (l *payloadLayer) Digest() (v1.Hash, error)            { return l.digest, nil }
+func (l *payloadLayer) DiffID() (v1.Hash, error)            { return l.digest, nil }
+func (l *payloadLayer) Size() (int64, error)                { return int64(len(l.payload)), nil }
+func (l *payloadLayer) MediaType() (types.MediaType, error) { return payloadMediaType, nil }
+func (l *payloadLayer) Compressed() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(l.payload)), nil
+}
+func (l *payloadLayer) Uncompressed() (io.ReadCloser, error) {
+	return l.Compressed()
+}
+
+// Verify reports whether signature is a valid signature of payload by pub.
+func Verify(pub crypto.PublicKey, payload, signature []byte) bool {
+	digest := sha256.Sum256(payload)
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, digest[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, payload, signature)
+	}
+	return false
+}
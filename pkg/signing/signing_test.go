@@ -0,0 +1,146 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/osscontainertools/kaniko/testutil"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+func writePEM(t *testing.T, typ string, b []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	testutil.CheckNoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b}), 0o600))
+	return path
+}
+
+// encryptKey encrypts der the way `cosign generate-key-pair` does, with
+// scrypt parameters small enough for a test.
+func encryptKey(t *testing.T, der []byte, password string) []byte {
+	t.Helper()
+	var k encryptedKey
+	k.KDF.Name = "scrypt"
+	k.KDF.Params.N, k.KDF.Params.R, k.KDF.Params.P = 1024, 8, 1
+	k.KDF.Salt = make([]byte, 32)
+	_, _ = rand.Read(k.KDF.Salt)
+	k.Cipher.Name = "nacl/secretbox"
+	k.Cipher.Nonce = make([]byte, 24)
+	_, _ = rand.Read(k.Cipher.Nonce)
+	secret, err := scrypt.Key([]byte(password), k.KDF.Salt, 1024, 8, 1, 32)
+	testutil.CheckNoError(t, err)
+	var nonce [24]byte
+	var key [32]byte
+	copy(nonce[:], k.Cipher.Nonce)
+	copy(key[:], secret)
+	k.Ciphertext = secretbox.Seal(nil, der, &nonce, &key)
+	b, err := json.Marshal(k)
+	testutil.CheckNoError(t, err)
+	return b
+}
+
+func TestLoadSigner(t *testing.T) {
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.CheckNoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(ec)
+	testutil.CheckNoError(t, err)
+	sec1, err := x509.MarshalECPrivateKey(ec)
+	testutil.CheckNoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	testutil.CheckNoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	testutil.CheckNoError(t, err)
+	edPKCS8, err := x509.MarshalPKCS8PrivateKey(edKey)
+	testutil.CheckNoError(t, err)
+
+	tests := []struct {
+		name     string
+		path     string
+		password string
+		wantErr  bool
+	}{
+		{name: "pkcs8", path: writePEM(t, "PRIVATE KEY", pkcs8)},
+		{name: "ec", path: writePEM(t, "EC PRIVATE KEY", sec1)},
+		{name: "rsa", path: writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))},
+		{name: "ed25519", path: writePEM(t, "PRIVATE KEY", edPKCS8)},
+		{name: "cosign", path: writePEM(t, "ENCRYPTED SIGSTORE PRIVATE KEY", encryptKey(t, pkcs8, "s3cret")), password: "s3cret"},
+		{name: "cosign wrong password", path: writePEM(t, "ENCRYPTED COSIGN PRIVATE KEY", encryptKey(t, pkcs8, "s3cret")), password: "guess", wantErr: true},
+		{name: "public key", path: writePEM(t, "PUBLIC KEY", []byte{}), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := LoadSigner(tt.path, tt.password)
+			testutil.CheckError(t, tt.wantErr, err)
+			if err != nil {
+				return
+			}
+			payload := []byte("payload")
+			sig, err := s.Sign(payload)
+			testutil.CheckNoError(t, err)
+			if !Verify(s.Public(), payload, sig) {
+				t.Error("signature does not verify")
+			}
+			if Verify(s.Public(), []byte("other"), sig) {
+				t.Error("signature verifies for another payload")
+			}
+		})
+	}
+}
+
+func TestPayload(t *testing.T) {
+	repo, err := name.NewRepository("gcr.io/foo/bar")
+	testutil.CheckNoError(t, err)
+	digest := v1.Hash{Algorithm: "sha256", Hex: "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}
+	payload, err := Payload(repo, digest)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, `{"critical":{"identity":{"docker-reference":"gcr.io/foo/bar"},"image":{"docker-manifest-digest":"sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},"type":"cosign container image signature"},"optional":null}`, string(payload))
+	testutil.CheckDeepEqual(t, "gcr.io/foo/bar:sha256-0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef.sig", Tag(repo, digest).String())
+}
+
+func TestAppend(t *testing.T) {
+	sigs, err := Append(nil, []byte("payload"), []byte("sig1"))
+	testutil.CheckNoError(t, err)
+	sigs, err = Append(sigs, []byte("payload"), []byte("sig2"))
+	testutil.CheckNoError(t, err)
+	// the same signature again is not a new layer
+	sigs, err = Append(sigs, []byte("payload"), []byte("sig1"))
+	testutil.CheckNoError(t, err)
+
+	m, err := sigs.Manifest()
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, 2, len(m.Layers))
+	testutil.CheckDeepEqual(t, payloadMediaType, m.Layers[0].MediaType)
+	testutil.CheckDeepEqual(t, base64.StdEncoding.EncodeToString([]byte("sig2")), m.Layers[1].Annotations[SignatureAnnotation])
+	cf, err := sigs.ConfigFile()
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, m.Layers[0].Digest, cf.RootFS.DiffIDs[0])
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/osscontainertools/kaniko/pkg/util"
+)
+
+// ErrNotSigned is returned by VerifyImage when no signature checks out.
+var ErrNotSigned = errors.New("no valid signature")
+
+// LoadPublicKey reads a PEM public key, as written to cosign.pub by
+// `cosign generate-key-pair`.
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s holds no PEM encoded public key", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported key type %T", path, key)
+}
+
+// Policy assigns the keys images must be signed with by repository.
+type Policy struct {
+	// scopes maps a registry or repository prefix to its keys.
+	scopes   map[string][]crypto.PublicKey
+	fallback []crypto.PublicKey
+}
+
+// LoadPolicy reads the policy at path, a JSON object mapping registries and
+// repository prefixes to public key files:
+//
+//	{
+//	  "docker.io/library": ["docker-library.pub"],
+//	  "gcr.io/my-project": ["my-project.pub", "my-project-next.pub"]
+//	}
+//
+// Key paths are relative to the policy file. Repositories no entry matches
+// use the key at defaultKey. Either path may be empty.
+func LoadPolicy(path, defaultKey string) (*Policy, error) {
+	p := &Policy{scopes: map[string][]crypto.PublicKey{}}
+	if defaultKey != "" {
+		key, err := LoadPublicKey(defaultKey)
+		if err != nil {
+			return nil, err
+		}
+		p.fallback = []crypto.PublicKey{key}
+	}
+	if path == "" {
+		return p, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var scopes map[string][]string
+	if err := json.Unmarshal(b, &scopes); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for scope, paths := range scopes {
+		normalized, err := util.NormalizeRepositoryScope(scope)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("%s: no keys for %s", path, scope)
+		}
+		for _, keyPath := range paths {
+			if !filepath.IsAbs(keyPath) {
+				keyPath = filepath.Join(filepath.Dir(path), keyPath)
+			}
+			key, err := LoadPublicKey(keyPath)
+			if err != nil {
+				return nil, err
+			}
+			p.scopes[normalized] = append(p.scopes[normalized], key)
+		}
+	}
+	return p, nil
+}
+
+// Keys returns the keys for repo, those of the longest matching entry or
+// else the default key. It is empty if repo has no key.
+func (p *Policy) Keys(repo name.Repository) []crypto.PublicKey {
+	match := ""
+	for scope := range p.scopes {
+		if util.InRepositoryScope(repo, scope) && len(scope) > len(match) {
+			match = scope
+		}
+	}
+	if match == "" {
+		return p.fallback
+	}
+	return p.scopes[match]
+}
+
+// VerifyImage checks that sigs, the image at the signature tag of digest,
+// holds a signature of digest by one of keys.
+func VerifyImage(sigs v1.Image, digest v1.Hash, keys []crypto.PublicKey) error {
+	m, err := sigs.Manifest()
+	if err != nil {
+		return err
+	}
+	for _, desc := range m.Layers {
+		encoded, ok := desc.Annotations[SignatureAnnotation]
+		if !ok {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		layer, err := sigs.LayerByDigest(desc.Digest)
+		if err != nil {
+			return err
+		}
+		rc, err := layer.Compressed()
+		if err != nil {
+			return err
+		}
+		payload, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if !Verify(key, payload, signature) {
+				continue
+			}
+			var p simpleSigning
+			if err := json.Unmarshal(payload, &p); err != nil {
+				return fmt.Errorf("parsing signed payload: %w", err)
+			}
+			// a valid signature of another image, copied over to this tag
+			if p.Critical.Image.DockerManifestDigest != digest.String() {
+				continue
+			}
+			return nil
+		}
+	}
+	return ErrNotSigned
+}
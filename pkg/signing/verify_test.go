@@ -0,0 +1,116 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/osscontainertools/kaniko/testutil"
+)
+
+func newSigner(t *testing.T, dir, pub string) *Signer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.CheckNoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	testutil.CheckNoError(t, err)
+	testutil.CheckNoError(t, os.WriteFile(filepath.Join(dir, pub), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644))
+	return &Signer{key: key}
+}
+
+func TestPolicy(t *testing.T) {
+	dir := t.TempDir()
+	def := newSigner(t, dir, "default.pub")
+	library := newSigner(t, dir, "library.pub")
+	project := newSigner(t, dir, "project.pub")
+	policy := filepath.Join(dir, "policy.json")
+	testutil.CheckNoError(t, os.WriteFile(policy, []byte(`{
+		"docker.io/library": ["library.pub"],
+		"gcr.io": ["`+filepath.Join(dir, "default.pub")+`"],
+		"gcr.io/project": ["project.pub"]
+	}`), 0o644))
+
+	p, err := LoadPolicy(policy, filepath.Join(dir, "default.pub"))
+	testutil.CheckNoError(t, err)
+	tests := []struct {
+		repo string
+		want crypto.PublicKey
+	}{
+		{repo: "debian", want: library.Public()},
+		{repo: "index.docker.io/library/debian", want: library.Public()},
+		{repo: "bitnami/redis", want: def.Public()},
+		{repo: "gcr.io/project/app", want: project.Public()},
+		{repo: "gcr.io/project", want: project.Public()},
+		{repo: "gcr.io/projectx/app", want: def.Public()},
+		{repo: "quay.io/foo/bar", want: def.Public()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.repo, func(t *testing.T) {
+			repo, err := name.NewRepository(tt.repo)
+			testutil.CheckNoError(t, err)
+			keys := p.Keys(repo)
+			testutil.CheckDeepEqual(t, 1, len(keys))
+			testutil.CheckDeepEqual(t, true, keys[0].(*ecdsa.PublicKey).Equal(tt.want))
+		})
+	}
+
+	p, err = LoadPolicy(policy, "")
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, 0, len(p.Keys(name.MustParseReference("quay.io/foo/bar").Context())))
+
+	testutil.CheckNoError(t, os.WriteFile(policy, []byte(`{"gcr.io": ["missing.pub"]}`), 0o644))
+	_, err = LoadPolicy(policy, "")
+	testutil.CheckError(t, true, err)
+}
+
+func TestVerifyImage(t *testing.T) {
+	dir := t.TempDir()
+	signer := newSigner(t, dir, "cosign.pub")
+	other := newSigner(t, dir, "other.pub")
+	repo := name.MustParseReference("gcr.io/foo/bar").Context()
+	digest := v1.Hash{Algorithm: "sha256", Hex: "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}
+	otherDigest := v1.Hash{Algorithm: "sha256", Hex: "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"}
+
+	sign := func(sigs v1.Image, s *Signer, d v1.Hash) v1.Image {
+		payload, err := Payload(repo, d)
+		testutil.CheckNoError(t, err)
+		sig, err := s.Sign(payload)
+		testutil.CheckNoError(t, err)
+		sigs, err = Append(sigs, payload, sig)
+		testutil.CheckNoError(t, err)
+		return sigs
+	}
+
+	sigs := sign(nil, other, digest)
+	testutil.CheckDeepEqual(t, true, errors.Is(VerifyImage(sigs, digest, []crypto.PublicKey{signer.Public()}), ErrNotSigned))
+	// a signature of another image doesn't count either
+	sigs = sign(sigs, signer, otherDigest)
+	testutil.CheckDeepEqual(t, true, errors.Is(VerifyImage(sigs, digest, []crypto.PublicKey{signer.Public()}), ErrNotSigned))
+	sigs = sign(sigs, signer, digest)
+	testutil.CheckNoError(t, VerifyImage(sigs, digest, []crypto.PublicKey{other.Public(), signer.Public()}))
+}
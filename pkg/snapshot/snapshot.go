@@ -17,6 +17,7 @@ limitations under the License.
 package snapshot
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -49,17 +50,18 @@ func NewSnapshotter(l *LayeredMap, d string) *Snapshotter {
 }
 
 // Init initializes a new snapshotter
-func (s *Snapshotter) Init() error {
+func (s *Snapshotter) Init(ctx context.Context) error {
 	assert.Assert("snapshot.init.layeredmap-set", s.l != nil, "Snapshotter.Init: layered map must be set")
 	assert.Assert("snapshot.init.directory-set", s.directory != "", "Snapshotter.Init: directory must be non-empty")
 	logrus.Info("Initializing snapshotter ...")
-	_, _, err := s.scanFullFilesystem()
+	_, _, err := s.scanFullFilesystem(ctx)
 	return err
 }
 
 // TakeSnapshot takes a snapshot of the specified files, avoiding directories in the ignorelist, and creates
 // a tarball of the changed files. Returns the tarball path and the number of files snapshotted.
-func (s *Snapshotter) TakeSnapshot(files []string, shdCheckDelete bool) (string, int, error) {
+// Files are dated no later than the SOURCE_DATE_EPOCH of ctx.
+func (s *Snapshotter) TakeSnapshot(ctx context.Context, files []string, shdCheckDelete bool) (string, int, error) {
 	assert.Assert("snapshot.takesnapshot.layeredmap-set", s.l != nil, "Snapshotter.TakeSnapshot: layered map must be set")
 	assert.Assert("snapshot.takesnapshot.directory-set", s.directory != "", "Snapshotter.TakeSnapshot: directory must be non-empty")
 	err := os.MkdirAll(config.KanikoLayersDir, 0o755)
@@ -114,8 +116,9 @@ func (s *Snapshotter) TakeSnapshot(files []string, shdCheckDelete bool) (string,
 	}
 
 	t := util.NewTar(f)
+	t.SetSourceDateEpoch(config.SourceDateEpoch(ctx))
 	defer t.Close()
-	if err := writeToTar(t, filesToAdd, filesToWhiteout); err != nil {
+	if err := writeToTar(ctx, t, filesToAdd, filesToWhiteout); err != nil {
 		return "", 0, err
 	}
 	return f.Name(), len(filesToAdd) + len(filesToWhiteout), nil
@@ -123,7 +126,8 @@ func (s *Snapshotter) TakeSnapshot(files []string, shdCheckDelete bool) (string,
 
 // TakeSnapshotFS takes a snapshot of the filesystem, avoiding directories in the ignorelist, and creates
 // a tarball of the changed files. Returns the tarball path and the number of files snapshotted.
-func (s *Snapshotter) TakeSnapshotFS() (string, int, error) {
+// Files are dated no later than the SOURCE_DATE_EPOCH of ctx.
+func (s *Snapshotter) TakeSnapshotFS(ctx context.Context) (string, int, error) {
 	assert.Assert("snapshot.takesnapshotfs.layeredmap-set", s.l != nil, "Snapshotter.TakeSnapshotFS: layered map must be set")
 	assert.Assert("snapshot.takesnapshotfs.directory-set", s.directory != "", "Snapshotter.TakeSnapshotFS: directory must be non-empty")
 	err := os.MkdirAll(config.KanikoLayersDir, 0o755)
@@ -137,14 +141,15 @@ func (s *Snapshotter) TakeSnapshotFS() (string, int, error) {
 	}
 	defer f.Close()
 	t := util.NewTar(f)
+	t.SetSourceDateEpoch(config.SourceDateEpoch(ctx))
 	defer t.Close()
 
-	filesToAdd, filesToWhiteOut, err := s.scanFullFilesystem()
+	filesToAdd, filesToWhiteOut, err := s.scanFullFilesystem(ctx)
 	if err != nil {
 		return "", 0, err
 	}
 
-	if err := writeToTar(t, filesToAdd, filesToWhiteOut); err != nil {
+	if err := writeToTar(ctx, t, filesToAdd, filesToWhiteOut); err != nil {
 		return "", 0, err
 	}
 	return f.Name(), len(filesToAdd) + len(filesToWhiteOut), nil
@@ -157,7 +162,7 @@ func (s *Snapshotter) getSnashotPathPrefix() string {
 	return snapshotPathPrefix
 }
 
-func (s *Snapshotter) scanFullFilesystem() ([]string, []string, error) {
+func (s *Snapshotter) scanFullFilesystem(ctx context.Context) ([]string, []string, error) {
 	logrus.Info("Taking snapshot of full filesystem...")
 
 	// Some of the operations that follow (e.g. hashing) depend on the file system being synced,
@@ -193,6 +198,9 @@ func (s *Snapshotter) scanFullFilesystem() ([]string, []string, error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	timer := timing.Start("Resolving Paths")
 
 	filesToAdd := []string{}
@@ -250,7 +258,7 @@ func removeObsoleteWhiteouts(deletedFiles map[string]struct{}) (filesToWhiteout
 	return filesToWhiteout
 }
 
-func writeToTar(t util.Tar, files, whiteouts []string) error {
+func writeToTar(ctx context.Context, t util.Tar, files, whiteouts []string) error {
 	timer := timing.Start("Writing tar file")
 	defer timer.End()
 
@@ -276,6 +284,9 @@ func writeToTar(t util.Tar, files, whiteouts []string) error {
 	}
 
 	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := addParentDirectories(t, addedPaths, path); err != nil {
 			return err
 		}
@@ -18,6 +18,7 @@ package snapshot
 
 import (
 	"archive/tar"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -48,7 +49,7 @@ func TestSnapshotFSFileChange(t *testing.T) {
 		t.Fatalf("Error setting up fs: %s", err)
 	}
 	// Take another snapshot
-	tarPath, _, err := snapshotter.TakeSnapshotFS()
+	tarPath, _, err := snapshotter.TakeSnapshotFS(context.Background())
 	if err != nil {
 		t.Fatalf("Error taking snapshot of fs: %s", err)
 	}
@@ -112,7 +113,7 @@ func TestSnapshotFSIsReproducible(t *testing.T) {
 		t.Fatalf("Error setting up fs: %s", err)
 	}
 	// Take another snapshot
-	tarPath, _, err := snapshotter.TakeSnapshotFS()
+	tarPath, _, err := snapshotter.TakeSnapshotFS(context.Background())
 	if err != nil {
 		t.Fatalf("Error taking snapshot of fs: %s", err)
 	}
@@ -141,7 +142,7 @@ func TestSnapshotFSChangePermissions(t *testing.T) {
 		t.Fatalf("Error changing permissions on %s: %v", batPath, err)
 	}
 	// Take another snapshot
-	tarPath, _, err := snapshotter.TakeSnapshotFS()
+	tarPath, _, err := snapshotter.TakeSnapshotFS(context.Background())
 	if err != nil {
 		t.Fatalf("Error taking snapshot of fs: %s", err)
 	}
@@ -206,7 +207,7 @@ func TestSnapshotFSReplaceDirWithLink(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tarPath, _, err := snapshotter.TakeSnapshotFS()
+	tarPath, _, err := snapshotter.TakeSnapshotFS(context.Background())
 	if err != nil {
 		t.Fatalf("Error taking snapshot of fs: %s", err)
 	}
@@ -252,7 +253,7 @@ func TestSnapshotFiles(t *testing.T) {
 	filesToSnapshot := []string{
 		filepath.Join(testDir, "foo"),
 	}
-	tarPath, _, err := snapshotter.TakeSnapshot(filesToSnapshot, false)
+	tarPath, _, err := snapshotter.TakeSnapshot(context.Background(), filesToSnapshot, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -287,7 +288,7 @@ func TestEmptySnapshotFS(t *testing.T) {
 	defer cleanup()
 
 	// Take snapshot with no changes
-	tarPath, _, err := snapshotter.TakeSnapshotFS()
+	tarPath, _, err := snapshotter.TakeSnapshotFS(context.Background())
 	if err != nil {
 		t.Fatalf("Error taking snapshot of fs: %s", err)
 	}
@@ -342,7 +343,7 @@ func TestSnapshotPreservesFileOrder(t *testing.T) {
 		}
 
 		// Take a snapshot
-		tarPath, _, err := snapshotter.TakeSnapshot(filesToSnapshot, false)
+		tarPath, _, err := snapshotter.TakeSnapshot(context.Background(), filesToSnapshot, false)
 		if err != nil {
 			t.Fatalf("Error taking snapshot of fs: %s", err)
 		}
@@ -373,7 +374,7 @@ func TestSnapshotIncludesParentDirBeforeWhiteoutFile(t *testing.T) {
 
 	// Take a snapshot
 	filesToSnapshot := []string{filepath.Join(testDir, "kaniko/file", "bar/bat")}
-	_, _, err = snapshotter.TakeSnapshot(filesToSnapshot, false)
+	_, _, err = snapshotter.TakeSnapshot(context.Background(), filesToSnapshot, false)
 	if err != nil {
 		t.Fatalf("Error taking snapshot of fs: %s", err)
 	}
@@ -397,7 +398,7 @@ func TestSnapshotIncludesParentDirBeforeWhiteoutFile(t *testing.T) {
 	}
 
 	// Take a snapshot again
-	tarPath, _, err := snapshotter.TakeSnapshot(filesToSnapshot, true)
+	tarPath, _, err := snapshotter.TakeSnapshot(context.Background(), filesToSnapshot, true)
 	if err != nil {
 		t.Fatalf("Error taking snapshot of fs: %s", err)
 	}
@@ -463,7 +464,7 @@ func TestSnapshotPreservesWhiteoutOrder(t *testing.T) {
 		}
 
 		// Take a snapshot
-		_, _, err = snapshotter.TakeSnapshot(filesToSnapshot, false)
+		_, _, err = snapshotter.TakeSnapshot(context.Background(), filesToSnapshot, false)
 		if err != nil {
 			t.Fatalf("Error taking snapshot of fs: %s", err)
 		}
@@ -477,7 +478,7 @@ func TestSnapshotPreservesWhiteoutOrder(t *testing.T) {
 		}
 
 		// Take a snapshot again
-		tarPath, _, err := snapshotter.TakeSnapshot(filesToSnapshot, true)
+		tarPath, _, err := snapshotter.TakeSnapshot(context.Background(), filesToSnapshot, true)
 		if err != nil {
 			t.Fatalf("Error taking snapshot of fs: %s", err)
 		}
@@ -507,7 +508,7 @@ func TestSnapshotOmitsUnameGname(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tarPath, _, err := snapshotter.TakeSnapshotFS()
+	tarPath, _, err := snapshotter.TakeSnapshotFS(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -559,7 +560,7 @@ func setUpTest(t *testing.T) (string, *Snapshotter, func(), error) {
 	// Take the initial snapshot
 	l := NewLayeredMap(util.Hasher())
 	snapshotter := NewSnapshotter(l, testDir)
-	if err := snapshotter.Init(); err != nil {
+	if err := snapshotter.Init(context.Background()); err != nil {
 		return "", nil, nil, fmt.Errorf("initializing snapshotter: %w", err)
 	}
 
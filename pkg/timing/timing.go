@@ -18,7 +18,10 @@ package timing
 
 import (
 	"context"
+	"os"
 	"sync"
+	"syscall"
+	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -91,3 +94,23 @@ func start(parent trace.Span, category string) trace.Span {
 	span.SetAttributes(attribute.String("kaniko.phase", phaseFor(category)))
 	return span
 }
+
+// RusageAttributes describes the resources a finished process and the
+// descendants it waited for used, user and system CPU time, peak RSS and
+// block I/O.
+func RusageAttributes(state *os.ProcessState) []attribute.KeyValue {
+	if state == nil {
+		return nil
+	}
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return nil
+	}
+	return []attribute.KeyValue{
+		attribute.Int64("kaniko.run.cpu.user_ms", time.Duration(ru.Utime.Nano()).Milliseconds()),
+		attribute.Int64("kaniko.run.cpu.system_ms", time.Duration(ru.Stime.Nano()).Milliseconds()),
+		attribute.Int64("kaniko.run.max_rss_kb", int64(ru.Maxrss)),
+		attribute.Int64("kaniko.run.block.in", int64(ru.Inblock)),
+		attribute.Int64("kaniko.run.block.out", int64(ru.Oublock)),
+	}
+}
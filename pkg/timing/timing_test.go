@@ -18,6 +18,7 @@ package timing
 
 import (
 	"context"
+	"os/exec"
 	"testing"
 
 	"go.opentelemetry.io/otel/trace/noop"
@@ -40,3 +41,22 @@ func TestSetTracerConcurrentWithStart(t *testing.T) {
 	}
 	<-done
 }
+
+func TestRusageAttributes(t *testing.T) {
+	if attrs := RusageAttributes(nil); attrs != nil {
+		t.Errorf("expected no attributes without a process state, got %v", attrs)
+	}
+	cmd := exec.Command("/bin/sh", "-c", "true")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, kv := range RusageAttributes(cmd.ProcessState) {
+		got[string(kv.Key)] = true
+	}
+	for _, k := range []string{"kaniko.run.cpu.user_ms", "kaniko.run.cpu.system_ms", "kaniko.run.max_rss_kb", "kaniko.run.block.in", "kaniko.run.block.out"} {
+		if !got[k] {
+			t.Errorf("missing attribute %s", k)
+		}
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
@@ -209,6 +210,23 @@ func buildID(path, target string, content []byte) string {
 	return hex.EncodeToString(sum[:])[:16]
 }
 
+// TraceEnv returns the TRACEPARENT and TRACESTATE variables for span, so
+// instrumented processes started by RUN nest their spans under it. Empty for
+// the noop span of an untraced build.
+func TraceEnv(span trace.Span) []string {
+	sc := span.SpanContext()
+	if !sc.IsValid() {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(trace.ContextWithSpanContext(context.Background(), sc), carrier)
+	env := []string{"TRACEPARENT=" + carrier.Get("traceparent")}
+	if ts := carrier.Get("tracestate"); ts != "" {
+		env = append(env, "TRACESTATE="+ts)
+	}
+	return env
+}
+
 // Shutdown ends the root span with the outcome and flushes. Idempotent. A
 // killed process leaves the root span unended, which the backend marks crashed.
 func Shutdown(err error) {
@@ -19,6 +19,9 @@ package tracing
 import (
 	"testing"
 
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
 	"github.com/osscontainertools/kaniko/pkg/config"
 )
 
@@ -70,3 +73,36 @@ func TestBuildID(t *testing.T) {
 		t.Error("empty readable Dockerfile must be content-addressed")
 	}
 }
+
+type spanWithContext struct {
+	noop.Span
+	sc trace.SpanContext
+}
+
+func (s spanWithContext) SpanContext() trace.SpanContext { return s.sc }
+
+// RUN steps hand these to the child process, the format is fixed by W3C trace-context.
+func TestTraceEnv(t *testing.T) {
+	if env := TraceEnv(noop.Span{}); env != nil {
+		t.Errorf("untraced span must not inject env, got %v", env)
+	}
+
+	ts, err := trace.ParseTraceState("vendor=value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+		TraceState: ts,
+	})
+	got := TraceEnv(spanWithContext{sc: sc})
+	want := []string{
+		"TRACEPARENT=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"TRACESTATE=vendor=value",
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("TraceEnv() = %v, want %v", got, want)
+	}
+}
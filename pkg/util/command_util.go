@@ -84,7 +84,7 @@ func ResolveEnvironmentReplacement(value string, envs []string, isFilepath bool)
 	shlex := shell.NewLex(parser.DefaultEscapeToken)
 	fp, _, err := shlex.ProcessWord(value, shell.EnvsFromSlice(envs))
 	// Check after replacement if value is a remote URL
-	if !isFilepath || IsSrcRemoteFileURL(fp) {
+	if !isFilepath || IsSrcRemoteFileURL(fp) || IsSrcGitURL(fp) {
 		return fp, err
 	}
 	if err != nil {
@@ -108,9 +108,14 @@ func ResolveEnvironmentReplacementRaw(value string, envs []string) (string, erro
 	return fp, err
 }
 
-func ResolveEnvAndWildcards(sd instructions.SourcesAndDest, fileContext FileContext, envs []string) ([]string, string, error) {
+// ResolveEnvAndWildcards resolves the build args, env and wildcards in the
+// sources and dest of a COPY or ADD.
+//
+// With parents, as for COPY --parents, a source keeps the ./ pivot of its
+// pattern, ie. src/./app/*.go resolves to src/./app/main.go. See ParentsDestinationFilepath.
+func ResolveEnvAndWildcards(sd instructions.SourcesAndDest, fileContext FileContext, envs []string, parents bool) ([]string, string, error) {
 	// First, resolve any environment replacement
-	resolvedEnvs, err := ResolveEnvironmentReplacementList(sd.SourcePaths, envs, true)
+	resolvedEnvs, err := ResolveEnvironmentReplacementList(sd.SourcePaths, envs, !parents)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to resolve environment: %w", err)
 	}
@@ -122,7 +127,16 @@ func ResolveEnvAndWildcards(sd instructions.SourcesAndDest, fileContext FileCont
 	dest := dests[0]
 	sd.DestPath = dest
 	// Resolve wildcards and get a list of resolved sources
-	srcs, err := ResolveSources(resolvedEnvs, fileContext.Root)
+	var srcs []string
+	if parents {
+		srcs, err = resolveParentsSources(resolvedEnvs, fileContext.Root)
+		sd.SourcePaths = make([]string, len(resolvedEnvs))
+		for i, src := range resolvedEnvs {
+			sd.SourcePaths[i] = filepath.Clean(src)
+		}
+	} else {
+		srcs, err = ResolveSources(resolvedEnvs, fileContext.Root)
+	}
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to resolve sources: %w", err)
 	}
@@ -130,6 +144,40 @@ func ResolveEnvAndWildcards(sd instructions.SourcesAndDest, fileContext FileCont
 	return srcs, dest, err
 }
 
+// parentsPivot separates the directories COPY --parents leaves out from the
+// ones it reproduces under the destination.
+const parentsPivot = "/./"
+
+// resolveParentsSources resolves the wildcards of every source separately, so
+// each match can be given the pivot of its pattern.
+func resolveParentsSources(srcs []string, root string) ([]string, error) {
+	var resolved []string
+	for _, src := range srcs {
+		pivot, rest, ok := strings.Cut(src, parentsPivot)
+		if !ok {
+			matches, err := ResolveSources([]string{filepath.Clean(src)}, root)
+			if err != nil {
+				return nil, err
+			}
+			resolved = append(resolved, matches...)
+			continue
+		}
+		pivot = filepath.Clean(pivot)
+		matches, err := ResolveSources([]string{filepath.Join(pivot, rest)}, root)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			rel, err := filepath.Rel(pivot, m)
+			if err != nil {
+				return nil, err
+			}
+			resolved = append(resolved, pivot+parentsPivot+rel)
+		}
+	}
+	return resolved, nil
+}
+
 // ContainsWildcards returns true if any entry in paths contains wildcards
 func ContainsWildcards(paths []string) bool {
 	for _, path := range paths {
@@ -164,7 +212,7 @@ func ResolveSources(srcs []string, root string) ([]string, error) {
 func matchSources(srcs, files []string) ([]string, error) {
 	var matchedSources []string
 	for _, src := range srcs {
-		if IsSrcRemoteFileURL(src) {
+		if IsSrcRemoteFileURL(src) || IsSrcGitURL(src) {
 			matchedSources = append(matchedSources, src)
 			continue
 		}
@@ -229,6 +277,21 @@ func DestinationFilepath(src, dest, cwd string) (string, error) {
 	return newDest, nil
 }
 
+// ParentsDestinationFilepath gives the destination of src for COPY --parents,
+// which reproduces its path below dest. The path is relative to the ./ pivot
+// of src if it has one, or to the root of the context otherwise.
+//
+// If dest is not an absolute filepath, add /cwd to the beginning
+func ParentsDestinationFilepath(src, dest, cwd string) string {
+	if _, after, ok := strings.Cut(src, parentsPivot); ok {
+		src = after
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(cwd, dest)
+	}
+	return filepath.Join(dest, filepath.Clean(pathSeparator+src))
+}
+
 // URLDestinationFilepath gives the destination a file from a remote URL should be saved to
 func URLDestinationFilepath(rawurl, dest, cwd string, envs []string) (string, error) {
 	if !IsDestDir(dest) {
@@ -275,7 +338,7 @@ func IsSrcsValid(srcsAndDest instructions.SourcesAndDest, resolvedSources []stri
 
 	// If there is only one source and it's a directory, docker assumes the dest is a directory
 	if len(resolvedSources) == 1 {
-		if IsSrcRemoteFileURL(resolvedSources[0]) {
+		if IsSrcRemoteFileURL(resolvedSources[0]) || IsSrcGitURL(resolvedSources[0]) {
 			return nil
 		}
 		path := filepath.Join(fileContext.Root, resolvedSources[0])
@@ -290,7 +353,7 @@ func IsSrcsValid(srcsAndDest instructions.SourcesAndDest, resolvedSources []stri
 
 	totalFiles := 0
 	for _, src := range resolvedSources {
-		if IsSrcRemoteFileURL(src) {
+		if IsSrcRemoteFileURL(src) || IsSrcGitURL(src) {
 			totalFiles++
 			continue
 		}
@@ -319,6 +382,18 @@ func IsSrcRemoteFileURL(rawurl string) bool {
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
 
+// IsSrcGitURL returns true if rawurl refers to a git repository, ie. git@host:repo.git,
+// git:// and ssh:// URLs, or HTTP(S) URLs ending in .git, optionally followed by #ref:subdir
+func IsSrcGitURL(rawurl string) bool {
+	for _, prefix := range []string{"git@", "git://", "ssh://"} {
+		if strings.HasPrefix(rawurl, prefix) {
+			return true
+		}
+	}
+	u, err := url.Parse(rawurl)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && strings.HasSuffix(u.Path, ".git")
+}
+
 func UpdateConfigEnv(envVars []instructions.KeyValuePair, config *v1.Config, replacementEnvs []string) error {
 	newEnvs := make([]instructions.KeyValuePair, len(envVars))
 	for index, pair := range envVars {
@@ -933,3 +933,56 @@ func TestIsSrcRemoteFileURL(t *testing.T) {
 		)
 	}
 }
+
+func TestParentsDestinationFilepath(t *testing.T) {
+	tests := []struct {
+		src      string
+		dest     string
+		expected string
+	}{
+		{src: "a/package.json", dest: "/app/", expected: "/app/a/package.json"},
+		{src: "x/./c/package.json", dest: "/app", expected: "/app/c/package.json"},
+		{src: "x/c", dest: "app/", expected: "/cwd/app/x/c"},
+		{src: ".", dest: "/app/", expected: "/app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			testutil.CheckDeepEqual(t, tt.expected, ParentsDestinationFilepath(tt.src, tt.dest, "/cwd"))
+		})
+	}
+}
+
+func TestResolveEnvAndWildcards_Parents(t *testing.T) {
+	root := t.TempDir()
+	testutil.CheckNoError(t, testutil.SetupFiles(root, map[string]string{
+		"x/a/package.json": "a",
+		"x/b/package.json": "b",
+		"y/package.json":   "y",
+	}))
+	sd := instructions.SourcesAndDest{SourcePaths: []string{"$DIR/./*/package.json", "y/package.json"}, DestPath: "/app/"}
+	srcs, dest, err := ResolveEnvAndWildcards(sd, FileContext{Root: root}, []string{"DIR=x"}, true)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, "/app/", dest)
+	testutil.CheckDeepEqual(t, []string{"x/./a/package.json", "x/./b/package.json", "y/package.json"}, srcs)
+}
+
+func TestIsSrcGitURL(t *testing.T) {
+	tests := []struct {
+		rawurl string
+		want   bool
+	}{
+		{rawurl: "https://github.com/org/repo.git", want: true},
+		{rawurl: "https://github.com/org/repo.git#v1.0:sub/dir", want: true},
+		{rawurl: "git@github.com:org/repo.git#main", want: true},
+		{rawurl: "git://example.com/repo", want: true},
+		{rawurl: "ssh://git@example.com/repo.git", want: true},
+		{rawurl: "https://example.com/foobar.tar.gz", want: false},
+		{rawurl: "ftp://example.com/repo.git", want: false},
+		{rawurl: "repo.git", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rawurl, func(t *testing.T) {
+			testutil.CheckDeepEqual(t, tt.want, IsSrcGitURL(tt.rawurl))
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"archive/tar"
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -28,6 +29,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -37,6 +40,7 @@ import (
 	"github.com/moby/go-archive"
 	"github.com/moby/patternmatcher"
 	"github.com/moby/patternmatcher/ignorefile"
+	digest "github.com/opencontainers/go-digest"
 	"github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/pkg/timing"
 	otiai10Cpy "github.com/otiai10/copy"
@@ -91,7 +95,11 @@ var volumes = []string{}
 type FileContext struct {
 	Root          string
 	ExcludedFiles []string
-	matcher       *patternmatcher.PatternMatcher
+	// Contexts maps the names of --build-context directories to their path
+	Contexts map[string]string
+	// Platform is what the stage is built for, images are pulled for it
+	Platform string
+	matcher  *patternmatcher.PatternMatcher
 }
 
 type ExtractFunction func(string, *tar.Header, string, io.Reader) error
@@ -723,8 +731,15 @@ func AddVolumePathToIgnoreList(path string) {
 //  1. If <src> is a remote file URL:
 //     - destination will have permissions of 0600 by default if not specified with chmod
 //     - If remote file has HTTP Last-Modified header, we set the mtime of the file to that timestamp
-func DownloadFileToDest(rawurl, dest string, uid, gid int64, chmod fs.FileMode) error {
-	resp, err := http.Get(rawurl) //nolint:noctx
+//
+// If checksum is set, the content is verified against it while streaming and
+// dest is removed again on a mismatch.
+func DownloadFileToDest(ctx context.Context, rawurl, dest string, uid, gid int64, chmod fs.FileMode, checksum digest.Digest) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -733,12 +748,27 @@ func DownloadFileToDest(rawurl, dest string, uid, gid int64, chmod fs.FileMode)
 	if resp.StatusCode >= 400 {
 		return fmt.Errorf("invalid response status %d", resp.StatusCode)
 	}
+	return writeDownloadedFile(rawurl, resp.Body, resp.Header.Get("Last-Modified"), dest, uid, gid, chmod, checksum)
+}
 
-	if err := CreateFile(dest, resp.Body, chmod, 0o755, uint32(uid), uint32(gid)); err != nil {
+// writeDownloadedFile writes the content of rawurl to dest, with its mtime
+// set to lastMod if that is a valid HTTP date.
+func writeDownloadedFile(rawurl string, body io.Reader, lastMod string, dest string, uid, gid int64, chmod fs.FileMode, checksum digest.Digest) error {
+	var digester digest.Digester
+	if checksum != "" {
+		digester = checksum.Algorithm().Digester()
+		body = io.TeeReader(body, digester.Hash())
+	}
+	if err := CreateFile(dest, body, chmod, 0o755, uint32(uid), uint32(gid)); err != nil {
 		return err
 	}
+	if digester != nil && digester.Digest() != checksum {
+		if err := os.Remove(dest); err != nil {
+			logrus.Warnf("removing %s after checksum mismatch: %v", dest, err)
+		}
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", rawurl, checksum, digester.Digest())
+	}
 	mTime := time.Time{}
-	lastMod := resp.Header.Get("Last-Modified")
 	if lastMod != "" {
 		if parsedMTime, err := http.ParseTime(lastMod); err == nil {
 			mTime = parsedMTime
@@ -784,7 +814,7 @@ func CopyDir(src, dest string, context FileContext, uid, gid int64, chmod mode.S
 			return nil, fmt.Errorf("copying dir: %w", err)
 		}
 		destPath := filepath.Join(dest, file)
-		if CheckIgnoreList(destPath) {
+		if CheckIgnoreList(UnstagedPath(destPath)) {
 			logrus.Debugf("Skipping copy for ignored path: %s", destPath)
 			continue
 		}
@@ -895,13 +925,31 @@ func MoveDir(src, dest string) error {
 	return nil
 }
 
+// IsKanikoDest reports whether a copy to dest would write into the kaniko
+// directory. Destinations in a COPY --link staging root are checked by the
+// path they will have in the rootfs.
+func IsKanikoDest(dest string) bool {
+	return HasFilepathPrefix(UnstagedPath(dest), config.KanikoDir, false)
+}
+
+// UnstagedPath returns the path dest will have in the rootfs once the COPY
+// --link staging root it is in is extracted, other paths are returned as is.
+func UnstagedPath(dest string) string {
+	rel, err := filepath.Rel(config.KanikoLinkDir, dest)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return dest
+	}
+	_, p, _ := strings.Cut(rel, "/")
+	return filepath.Join(config.RootDir, p)
+}
+
 // CopySymlink copies the symlink at src to dest.
 func CopySymlink(src, dest string, context FileContext) (bool, error) {
 	if context.ExcludesFile(src) {
 		logrus.Debugf("%s found in .dockerignore, ignoring", src)
 		return true, nil
 	}
-	if HasFilepathPrefix(dest, config.KanikoDir, false) {
+	if IsKanikoDest(dest) {
 		logrus.Warnf("Skipping copy targeting kaniko directory: %s", dest)
 		logrus.Info("Writes to the kaniko directory are blocked to prevent overwriting the executor.")
 		logrus.Info("To copy files there, relocate kaniko with KANIKO_DIR: https://github.com/osscontainertools/kaniko#bootstrapping-kaniko")
@@ -928,7 +976,7 @@ func CopyFile(src, dest string, context FileContext, uid, gid int64, chmod mode.
 		logrus.Debugf("%s found in .dockerignore, ignoring", src)
 		return true, nil
 	}
-	if HasFilepathPrefix(dest, config.KanikoDir, false) {
+	if IsKanikoDest(dest) {
 		logrus.Warnf("Skipping copy targeting kaniko directory: %s", dest)
 		logrus.Info("Writes to the kaniko directory are blocked to prevent overwriting the executor.")
 		logrus.Info("To copy files there, relocate kaniko with KANIKO_DIR: https://github.com/osscontainertools/kaniko#bootstrapping-kaniko")
@@ -1009,6 +1057,53 @@ func NewFileContextFromDockerfile(dockerfilePath, buildcontext string) (FileCont
 	return fileContext, nil
 }
 
+// From returns the context a COPY --from=from reads its sources from. That is
+// a named build context, or else the files of the stage by that index or of
+// the image by that name pulled for c.Platform.
+func (c FileContext) From(from string) FileContext {
+	if dir, ok := c.Contexts[from]; ok {
+		return FileContext{Root: dir, Contexts: c.Contexts}
+	}
+	if _, err := strconv.Atoi(from); err != nil {
+		from = DependencyKey(from, c.Platform)
+	}
+	return FileContext{Root: filepath.Join(config.KanikoInterStageDepsDir, from), Contexts: c.Contexts}
+}
+
+// DependencyKey is what the image a COPY --from=image reads is known by once
+// pulled for platform, the name of its directory below the dependency dir.
+func DependencyKey(image, platform string) string {
+	if platform == "" {
+		return image
+	}
+	return image + "#" + platform
+}
+
+// SplitDependencyKey returns the image and platform of a DependencyKey.
+func SplitDependencyKey(key string) (image, platform string) {
+	image, platform, _ = strings.Cut(key, "#")
+	return image, platform
+}
+
+// WithExcludes returns a copy of the context that additionally excludes
+// patterns, as given by COPY --exclude. Like the .dockerignore they are
+// matched relative to the root of the context.
+func (c FileContext) WithExcludes(patterns []string) (FileContext, error) {
+	if len(patterns) == 0 {
+		return c, nil
+	}
+	excluded := append(slices.Clone(c.ExcludedFiles), patterns...)
+	matcher, err := patternmatcher.New(excluded)
+	if err != nil {
+		return c, fmt.Errorf("parsing exclude patterns: %w", err)
+	}
+	c.ExcludedFiles = excluded
+	if c.matcher != nil {
+		c.matcher = matcher
+	}
+	return c, nil
+}
+
 // getExcludedFiles returns a list of files to exclude from the .dockerignore
 func getExcludedFiles(dockerfilePath, buildcontext string) ([]string, error) {
 	path := dockerfilePath + ".dockerignore"
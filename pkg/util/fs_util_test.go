@@ -19,8 +19,12 @@ package util
 import (
 	"archive/tar"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -32,6 +36,7 @@ import (
 	"github.com/golang/mock/gomock"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/types"
+	digest "github.com/opencontainers/go-digest"
 	"github.com/osscontainertools/kaniko/pkg/config"
 	"github.com/osscontainertools/kaniko/pkg/constants"
 	"github.com/osscontainertools/kaniko/testutil"
@@ -1998,3 +2003,71 @@ func Test_setFileTimes(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadFileToDest_Checksum(t *testing.T) {
+	content := "remote content"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		io.WriteString(w, content)
+	}))
+	defer server.Close()
+	uid, gid := int64(os.Getuid()), int64(os.Getgid())
+
+	t.Run("match", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "file")
+		err := DownloadFileToDest(t.Context(), server.URL, dest, uid, gid, 0o600, digest.FromString(content))
+		testutil.CheckNoError(t, err)
+		b, err := os.ReadFile(dest)
+		testutil.CheckNoError(t, err)
+		testutil.CheckDeepEqual(t, content, string(b))
+		fi, err := os.Stat(dest)
+		testutil.CheckNoError(t, err)
+		testutil.CheckDeepEqual(t, time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC), fi.ModTime().UTC())
+	})
+	t.Run("mismatch", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "file")
+		err := DownloadFileToDest(t.Context(), server.URL, dest, uid, gid, 0o600, digest.FromString("something else"))
+		testutil.CheckError(t, true, err)
+		if !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if _, err := os.Stat(dest); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed after a mismatch, got %v", dest, err)
+		}
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		err := DownloadFileToDest(ctx, server.URL, filepath.Join(t.TempDir(), "file"), uid, gid, 0o600, "")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the download to be cancelled, got %v", err)
+		}
+	})
+}
+
+func Test_FileContext_WithExcludes(t *testing.T) {
+	for _, precompile := range []bool{false, true} {
+		t.Run(fmt.Sprintf("precompile=%t", precompile), func(t *testing.T) {
+			previous := config.FF.PrecompileDockerignore
+			config.FF.PrecompileDockerignore = precompile
+			t.Cleanup(func() { config.FF.PrecompileDockerignore = previous })
+			tempDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tempDir, ".dockerignore"), []byte("*.log\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			fileContext, err := NewFileContextFromDockerfile("", tempDir)
+			testutil.CheckNoError(t, err)
+
+			excluding, err := fileContext.WithExcludes([]string{"docs"})
+			testutil.CheckNoError(t, err)
+			testutil.CheckDeepEqual(t, true, excluding.ExcludesFile(filepath.Join(tempDir, "app.log")))
+			testutil.CheckDeepEqual(t, true, excluding.ExcludesFile(filepath.Join(tempDir, "docs/guide.md")))
+			testutil.CheckDeepEqual(t, false, excluding.ExcludesFile(filepath.Join(tempDir, "main.go")))
+			// the original context is left untouched
+			testutil.CheckDeepEqual(t, false, fileContext.ExcludesFile(filepath.Join(tempDir, "docs/guide.md")))
+
+			_, err = fileContext.WithExcludes([]string{"[invalid"})
+			testutil.CheckError(t, true, err)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// NormalizeRepositoryScope spells scope, a registry or a repository prefix
+// on one, the way name.Repository spells names, so that docker.io/library
+// matches index.docker.io/library/debian.
+func NormalizeRepositoryScope(scope string) (string, error) {
+	registry, path, _ := strings.Cut(strings.TrimSuffix(scope, "/"), "/")
+	reg, err := name.NewRegistry(registry, name.WeakValidation)
+	if err != nil {
+		return "", fmt.Errorf("invalid registry in %q: %w", scope, err)
+	}
+	if path == "" {
+		return reg.Name(), nil
+	}
+	return reg.Name() + "/" + path, nil
+}
+
+// InRepositoryScope reports whether repo is the normalized scope or lies
+// under it.
+func InRepositoryScope(repo name.Repository, scope string) bool {
+	return repo.Name() == scope || strings.HasPrefix(repo.Name(), scope+"/")
+}
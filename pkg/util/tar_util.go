@@ -27,29 +27,51 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/moby/go-archive"
 	"github.com/moby/go-archive/compression"
 	"github.com/osscontainertools/kaniko/pkg/assert"
 	"github.com/osscontainertools/kaniko/pkg/config"
+	"github.com/pierrec/lz4/v4"
 	"github.com/sirupsen/logrus"
+	"github.com/ulikunitz/xz"
 )
 
+// lz4MaxFrameHeader is the size of the longest lz4 frame header
+const lz4MaxFrameHeader = 19
+
 // Tar knows how to write files to a tar file.
 type Tar struct {
 	hardlinks map[uint64]string
 	w         *tar.Writer
+	root      string
+	epoch     *time.Time
 }
 
 // NewTar will create an instance of Tar that can write files to the writer at f.
 func NewTar(f io.Writer) Tar {
+	return NewTarWithRoot(f, config.RootDir)
+}
+
+// NewTarWithRoot is like NewTar, but names the files in the tar relative to
+// root instead of the rootfs.
+func NewTarWithRoot(f io.Writer, root string) Tar {
 	w := tar.NewWriter(f)
 	return Tar{
 		w:         w,
 		hardlinks: map[uint64]string{},
+		root:      root,
 	}
 }
 
+// SetSourceDateEpoch makes the tar date the files added from now on no later
+// than epoch, nil leaves their timestamps as they are.
+func (t *Tar) SetSourceDateEpoch(epoch *time.Time) {
+	t.epoch = epoch
+}
+
 // Close will close any open streams used by Tar.
 func (t *Tar) Close() {
 	t.w.Close()
@@ -82,10 +104,10 @@ func (t *Tar) AddFileToTar(p string) error {
 		return err
 	}
 
-	assert.Assert("tar.root-path-excluded", p != config.RootDir, "snapshot must not include root path '/'")
+	assert.Assert("tar.root-path-excluded", p != t.root, "snapshot must not include root path '/'")
 
 	// Docker uses no leading / in the tarball
-	hdr.Name = strings.TrimPrefix(p, config.RootDir)
+	hdr.Name = strings.TrimPrefix(p, t.root)
 	hdr.Name = strings.TrimLeft(hdr.Name, "/")
 
 	if hdr.Typeflag == tar.TypeDir && !strings.HasSuffix(hdr.Name, "/") {
@@ -97,13 +119,18 @@ func (t *Tar) AddFileToTar(p string) error {
 	hdr.Gname = ""
 	// use PAX format to preserve accurate mtime (match Docker behavior)
 	hdr.Format = tar.FormatPAX
+	if epoch := t.epoch; epoch != nil {
+		hdr.ModTime = clampTime(hdr.ModTime, *epoch)
+		hdr.AccessTime = clampTime(hdr.AccessTime, *epoch)
+		hdr.ChangeTime = clampTime(hdr.ChangeTime, *epoch)
+	}
 
 	hardlink, linkDst := t.checkHardlink(p, i)
 	if hardlink {
 		if config.FF.RelativeLinkTargets {
-			hdr.Linkname = strings.TrimLeft(strings.TrimPrefix(linkDst, config.RootDir), "/")
+			hdr.Linkname = strings.TrimLeft(strings.TrimPrefix(linkDst, t.root), "/")
 		} else {
-			hdr.Linkname = linkDst
+			hdr.Linkname = "/" + strings.TrimLeft(strings.TrimPrefix(linkDst, t.root), "/")
 		}
 		hdr.Typeflag = tar.TypeLink
 		hdr.Size = 0
@@ -125,6 +152,15 @@ func (t *Tar) AddFileToTar(p string) error {
 	return nil
 }
 
+// clampTime returns t, or epoch if t is later, the way buildkit dates files
+// for SOURCE_DATE_EPOCH.
+func clampTime(t, epoch time.Time) time.Time {
+	if t.After(epoch) {
+		return epoch
+	}
+	return t
+}
+
 // writeSecurityXattrToTarFile writes security.capability
 // xattrs from a tar header to filesystem
 func writeSecurityXattrToTarFile(path string, hdr *tar.Header) error {
@@ -223,10 +259,31 @@ func UnpackLocalTarArchive(path, dest string) ([]string, error) {
 		case compression.Bzip2:
 			bzr := bzip2.NewReader(file)
 			return UnTar(bzr, dest)
+		case compression.Zstd:
+			zr, err := zstd.NewReader(file)
+			if err != nil {
+				return nil, err
+			}
+			defer zr.Close()
+			return UnTar(zr, dest)
+		case compression.Xz:
+			xzr, err := xz.NewReader(file)
+			if err != nil {
+				return nil, err
+			}
+			return UnTar(xzr, dest)
 		default:
 			logrus.Fatalf("unsupported compression algorithm: %d", compressionLevel)
 		}
 	}
+	if fileIsLz4Tar(path) {
+		file, err := FSys.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return UnTar(lz4.NewReader(file), dest)
+	}
 	if fileIsUncompressedTar(path) {
 		file, err := FSys.Open(path)
 		if err != nil {
@@ -242,7 +299,7 @@ func UnpackLocalTarArchive(path, dest string) ([]string, error) {
 func IsFileLocalTarArchive(src string) bool {
 	compressed, _ := fileIsCompressedTar(src)
 	uncompressed := fileIsUncompressedTar(src)
-	return compressed || uncompressed
+	return compressed || fileIsLz4Tar(src) || uncompressed
 }
 
 func fileIsCompressedTar(src string) (bool, compression.Compression) {
@@ -259,6 +316,23 @@ func fileIsCompressedTar(src string) (bool, compression.Compression) {
 	return (compressionLevel > 0), compressionLevel
 }
 
+// fileIsLz4Tar returns true if src starts with an lz4 frame header, go-archive
+// does not detect lz4 so it is not one of the compression.Compression levels
+func fileIsLz4Tar(src string) bool {
+	r, err := FSys.Open(src)
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+	buf := make([]byte, lz4MaxFrameHeader)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false
+	}
+	ok, _ := lz4.ValidFrameHeader(buf[:n])
+	return ok
+}
+
 func fileIsUncompressedTar(src string) bool {
 	r, err := FSys.Open(src)
 	if err != nil {
@@ -57,6 +57,21 @@ func Test_IsLocalTarArchive(t *testing.T) {
 	}
 }
 
+func Test_UnpackLocalTarArchive_Compressions(t *testing.T) {
+	// testdata holds a tar with hello.txt, compressed by the xz, lz4 and zstd tools
+	for _, name := range []string{"archive.tar.xz", "archive.tar.lz4", "archive.tar.zst"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join("testdata", name)
+			testutil.CheckDeepEqual(t, true, IsFileLocalTarArchive(path))
+			dest := t.TempDir()
+			files, err := UnpackLocalTarArchive(path, dest)
+			testutil.CheckErrorAndDeepEqual(t, false, err, []string{filepath.Join(dest, "hello.txt")}, files)
+			b, err := os.ReadFile(filepath.Join(dest, "hello.txt"))
+			testutil.CheckErrorAndDeepEqual(t, false, err, "hello\n", string(b))
+		})
+	}
+}
+
 func Test_AddFileToTar(t *testing.T) {
 	testDir := t.TempDir()
 
@@ -86,6 +101,45 @@ func Test_AddFileToTar(t *testing.T) {
 	testutil.CheckDeepEqual(t, mtime, hdr.ModTime)
 }
 
+func Test_AddFileToTar_SourceDateEpoch(t *testing.T) {
+	testDir := t.TempDir()
+	epoch := time.Unix(1700000000, 0).UTC()
+
+	mtimes := map[string]time.Time{
+		"old": time.Unix(1600000000, 0),
+		"new": time.Unix(1800000000, 0),
+	}
+	buf := new(bytes.Buffer)
+	tarw := NewTarWithRoot(buf, testDir)
+	tarw.SetSourceDateEpoch(&epoch)
+	for _, name := range []string{"old", "new"} {
+		path := filepath.Join(testDir, name)
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtimes[name], mtimes[name]); err != nil {
+			t.Fatal(err)
+		}
+		if err := tarw.AddFileToTar(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tarw.Close()
+
+	// files older than the epoch keep their mtime, newer ones are clamped
+	tarReader := tar.NewReader(buf)
+	for _, want := range []time.Time{mtimes["old"], epoch} {
+		hdr, err := tarReader.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		testutil.CheckDeepEqual(t, want.Unix(), hdr.ModTime.Unix())
+		if hdr.ChangeTime.After(epoch) {
+			t.Errorf("%s: ctime %s is after the epoch", hdr.Name, hdr.ChangeTime)
+		}
+	}
+}
+
 func setUpFilesAndTars(testDir string) error {
 	regularFilesAndContents := map[string]string{
 		regularFiles[0]: "",
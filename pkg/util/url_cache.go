@@ -0,0 +1,221 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// URLCache keeps the content of remote files in a local directory, so they
+// are only downloaded again once the server reports a change through their
+// ETag or Last-Modified headers.
+type URLCache struct {
+	Dir string
+}
+
+// CachedURL is the content of a remote file as stored in a URLCache.
+type CachedURL struct {
+	URL          string        `json:"url"`
+	ETag         string        `json:"etag,omitempty"`
+	LastModified string        `json:"lastModified,omitempty"`
+	Digest       digest.Digest `json:"digest"`
+	// Path is the file holding the content
+	Path string `json:"-"`
+}
+
+const (
+	urlCacheContent = "content"
+	urlCacheEntry   = "entry.json"
+	urlCacheLock    = "lock"
+)
+
+// Fetch revalidates the cached content of rawurl with a conditional request,
+// and downloads it again if the server reports a change.
+func (c *URLCache) Fetch(ctx context.Context, rawurl string) (CachedURL, error) {
+	dir := c.dir(rawurl)
+	unlock, err := lockURLDir(dir)
+	if err != nil {
+		return CachedURL{}, err
+	}
+	defer unlock()
+	return fetchCachedURL(ctx, dir, rawurl)
+}
+
+func (c *URLCache) dir(rawurl string) string {
+	sum := sha256.Sum256([]byte(rawurl))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+}
+
+// lockURLDir takes an exclusive lock on the cache dir of a URL, builds sharing
+// the cache must not interleave replacing its content and entry.
+func lockURLDir(dir string) (func(), error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating url cache dir: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, urlCacheLock), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening url cache lock: %w", err)
+	}
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking url cache dir %s: %w", dir, err)
+	}
+	return func() {
+		_ = unix.Flock(int(f.Fd()), unix.LOCK_UN)
+		_ = f.Close()
+	}, nil
+}
+
+func fetchCachedURL(ctx context.Context, dir, rawurl string) (CachedURL, error) {
+	cached, err := readCachedURL(dir)
+	if err != nil {
+		logrus.Debugf("Ignoring cached %s: %v", rawurl, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return CachedURL{}, err
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return CachedURL{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		logrus.Debugf("Using cached %s, not modified", rawurl)
+		return *cached, nil
+	}
+	if resp.StatusCode >= 400 {
+		return CachedURL{}, fmt.Errorf("invalid response status %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return CachedURL{}, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return writeCachedURL(dir, rawurl, resp)
+}
+
+func readCachedURL(dir string) (*CachedURL, error) {
+	b, err := os.ReadFile(filepath.Join(dir, urlCacheEntry))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cached CachedURL
+	if err := json.Unmarshal(b, &cached); err != nil {
+		return nil, err
+	}
+	cached.Path = filepath.Join(dir, urlCacheContent)
+	if _, err := os.Stat(cached.Path); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+// writeCachedURL stores the response body and its entry, both are renamed
+// into place so an interrupted download never leaves a partial entry. The
+// caller holds the lock of dir.
+func writeCachedURL(dir, rawurl string, resp *http.Response) (CachedURL, error) {
+	tmp, err := os.CreateTemp(dir, urlCacheContent+"-")
+	if err != nil {
+		return CachedURL{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	digester := digest.Canonical.Digester()
+	_, err = io.Copy(io.MultiWriter(tmp, digester.Hash()), resp.Body)
+	if err := errors.Join(err, tmp.Close()); err != nil {
+		return CachedURL{}, fmt.Errorf("downloading %s: %w", rawurl, err)
+	}
+
+	cached := CachedURL{
+		URL:          rawurl,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Digest:       digester.Digest(),
+		Path:         filepath.Join(dir, urlCacheContent),
+	}
+	b, err := json.Marshal(cached)
+	if err != nil {
+		return CachedURL{}, err
+	}
+	// drop the old entry first, so content and entry never mismatch
+	if err := os.Remove(filepath.Join(dir, urlCacheEntry)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return CachedURL{}, err
+	}
+	if err := os.Rename(tmp.Name(), cached.Path); err != nil {
+		return CachedURL{}, err
+	}
+	entry, err := os.CreateTemp(dir, urlCacheEntry+"-")
+	if err != nil {
+		return CachedURL{}, err
+	}
+	defer os.Remove(entry.Name())
+	_, err = entry.Write(b)
+	if err := errors.Join(err, entry.Chmod(0o644), entry.Close()); err != nil {
+		return CachedURL{}, err
+	}
+	if err := os.Rename(entry.Name(), filepath.Join(dir, urlCacheEntry)); err != nil {
+		return CachedURL{}, err
+	}
+	return cached, nil
+}
+
+// CopyToDest fetches rawurl through the cache and writes it to dest the same
+// way DownloadFileToDest does.
+func (c *URLCache) CopyToDest(ctx context.Context, rawurl, dest string, uid, gid int64, chmod fs.FileMode, checksum digest.Digest) error {
+	dir := c.dir(rawurl)
+	unlock, err := lockURLDir(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	cached, err := fetchCachedURL(ctx, dir, rawurl)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(cached.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeDownloadedFile(rawurl, f, cached.LastModified, dest, uid, gid, chmod, checksum)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2026 OSS Container Tools
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/osscontainertools/kaniko/testutil"
+)
+
+func TestURLCache_Fetch(t *testing.T) {
+	content := "version 1"
+	etag := `"v1"`
+	lastModified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		} else if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !lastModified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	cache := &URLCache{Dir: t.TempDir()}
+	fetch := func() CachedURL {
+		t.Helper()
+		cached, err := cache.Fetch(t.Context(), server.URL+"/file")
+		testutil.CheckNoError(t, err)
+		b, err := os.ReadFile(cached.Path)
+		testutil.CheckNoError(t, err)
+		testutil.CheckDeepEqual(t, content, string(b))
+		testutil.CheckDeepEqual(t, digest.FromString(content), cached.Digest)
+		return cached
+	}
+
+	fetch()
+	fetch()
+	testutil.CheckDeepEqual(t, 1, downloads)
+
+	// a changed ETag is downloaded again
+	content, etag = "version 2", `"v2"`
+	fetch()
+	testutil.CheckDeepEqual(t, 2, downloads)
+
+	// without an ETag the cache is revalidated with Last-Modified
+	etag = ""
+	fetch()
+	testutil.CheckDeepEqual(t, 2, downloads)
+	lastModified = lastModified.Add(time.Hour)
+	content = "version 3"
+	fetch()
+	fetch()
+	testutil.CheckDeepEqual(t, 3, downloads)
+}
+
+func TestURLCache_FetchConcurrent(t *testing.T) {
+	var downloads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "version %d", downloads.Add(1))
+	}))
+	defer server.Close()
+
+	// builds sharing the cache never leave an entry that mismatches its content
+	cache := &URLCache{Dir: t.TempDir()}
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			_, err := cache.Fetch(t.Context(), server.URL+"/file")
+			testutil.CheckNoError(t, err)
+		})
+	}
+	wg.Wait()
+	dir := cache.dir(server.URL + "/file")
+	cached, err := readCachedURL(dir)
+	testutil.CheckNoError(t, err)
+	b, err := os.ReadFile(cached.Path)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, digest.FromBytes(b), cached.Digest)
+	entries, err := os.ReadDir(dir)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, 3, len(entries))
+}
+
+func TestURLCache_CopyToDest(t *testing.T) {
+	lastModified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	cache := &URLCache{Dir: t.TempDir()}
+	dest := filepath.Join(t.TempDir(), "file")
+	err := cache.CopyToDest(t.Context(), server.URL, dest, int64(os.Getuid()), int64(os.Getgid()), 0o600, digest.FromString("content"))
+	testutil.CheckNoError(t, err)
+	fi, err := os.Stat(dest)
+	testutil.CheckNoError(t, err)
+	testutil.CheckDeepEqual(t, lastModified, fi.ModTime().UTC())
+	testutil.CheckDeepEqual(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	err = cache.CopyToDest(t.Context(), server.URL, dest, int64(os.Getuid()), int64(os.Getgid()), 0o600, digest.FromString("other"))
+	testutil.CheckError(t, true, err)
+}
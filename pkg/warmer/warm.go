@@ -17,6 +17,7 @@ limitations under the License.
 package warmer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -24,6 +25,7 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"slices"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -40,14 +42,17 @@ import (
 
 // WarmCache populates the cache
 func WarmCache(opts *config.WarmerOptions) error {
-	var dockerfileImages []string
+	var dockerfileImages []dockerfileImage
 	cacheDir := opts.CacheDir
-	images := opts.Images
+	var images []dockerfileImage
+	for _, img := range opts.Images {
+		images = append(images, dockerfileImage{name: img})
+	}
 
 	// if opts.image is empty,we need to parse dockerfilepath to get images list
 	if opts.DockerfilePath != "" {
 		var err error
-		if dockerfileImages, err = ParseDockerfile(opts); err != nil {
+		if dockerfileImages, err = parseDockerfileImages(opts); err != nil {
 			return fmt.Errorf("failed to parse Dockerfile: %w", err)
 		}
 	}
@@ -61,17 +66,17 @@ func WarmCache(opts *config.WarmerOptions) error {
 	errs := 0
 	if config.FF.OCIWarmer {
 		for _, img := range images {
-			err := ociWarmToFile(cacheDir, img, opts)
+			err := ociWarmToFile(cacheDir, img.name, platformOptions(opts, img.platform))
 			if err != nil {
-				logrus.Warnf("Error while trying to warm image: %v %v", img, err)
+				logrus.Warnf("Error while trying to warm image: %v %v", img.name, err)
 				errs++
 			}
 		}
 	} else {
 		for _, img := range images {
-			err := warmToFile(cacheDir, img, opts)
+			err := warmToFile(cacheDir, img.name, platformOptions(opts, img.platform))
 			if err != nil {
-				logrus.Warnf("Error while trying to warm image: %v %v", img, err)
+				logrus.Warnf("Error while trying to warm image: %v %v", img.name, err)
 				errs++
 			}
 		}
@@ -102,7 +107,7 @@ func warmToFile(cacheDir, img string, opts *config.WarmerOptions) error {
 	defer mtfsFile.Close()
 
 	cw := &Warmer{
-		Remote:         remote.RetrieveRemoteImage,
+		Remote:         retrieveRemoteImage,
 		Local:          cache.LocalSource,
 		TarWriter:      f,
 		ManifestWriter: mtfsFile,
@@ -165,7 +170,7 @@ func ociWarmToFile(cacheDir, img string, opts *config.WarmerOptions) error {
 	defer os.RemoveAll(tmp)
 
 	cw := &OciWarmer{
-		Remote: remote.RetrieveRemoteImage,
+		Remote: retrieveRemoteImage,
 		Local:  cache.LocalSource,
 		TmpDir: tmp,
 	}
@@ -215,10 +220,15 @@ func ociWarmToFile(cacheDir, img string, opts *config.WarmerOptions) error {
 }
 
 // FetchRemoteImage retrieves a Docker image manifest from a remote source.
-// github.com/GoogleContainerTools/kaniko/image/remote.RetrieveRemoteImage can be used as
-// this type.
+// github.com/GoogleContainerTools/kaniko/image/remote.RetrieveRemoteImage bound to a
+// context can be used as this type.
 type FetchRemoteImage func(image string, opts config.RegistryOptions, customPlatform string) (v1.Image, error)
 
+// retrieveRemoteImage is remote.RetrieveRemoteImage as a FetchRemoteImage.
+func retrieveRemoteImage(image string, opts config.RegistryOptions, customPlatform string) (v1.Image, error) {
+	return remote.RetrieveRemoteImage(context.Background(), image, opts, customPlatform)
+}
+
 // FetchLocalSource retrieves a Docker image manifest from a local source.
 // github.com/GoogleContainerTools/kaniko/cache.LocalSource can be used as
 // this type.
@@ -390,10 +400,30 @@ func (w *OciWarmer) Write(cacheRef name.Reference, img v1.Image) error {
 	return nil
 }
 
+// dockerfileImage is a base image of a Dockerfile, with the FROM --platform of
+// its stage if it has one.
+type dockerfileImage struct {
+	name     string
+	platform string
+}
+
+// ParseDockerfile returns the base images of the Dockerfile at opts.DockerfilePath.
 func ParseDockerfile(opts *config.WarmerOptions) ([]string, error) {
+	images, err := parseDockerfileImages(opts)
+	if err != nil {
+		return nil, err
+	}
+	baseNames := make([]string, 0, len(images))
+	for _, img := range images {
+		baseNames = append(baseNames, img.name)
+	}
+	return baseNames, nil
+}
+
+func parseDockerfileImages(opts *config.WarmerOptions) ([]dockerfileImage, error) {
 	var err error
 	var d []uint8
-	var baseNames []string
+	var baseImages []dockerfileImage
 	match, _ := regexp.MatchString("^https?://", opts.DockerfilePath)
 	if match {
 		resp, e := http.Get(opts.DockerfilePath) //nolint:noctx
@@ -418,7 +448,11 @@ func ParseDockerfile(opts *config.WarmerOptions) ([]string, error) {
 		return nil, fmt.Errorf("parsing dockerfile: %w", err)
 	}
 
-	args := opts.BuildArgs
+	args, err := dockerfile.PlatformArgs(opts.CustomPlatform)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, opts.BuildArgs...)
 	for _, marg := range metaArgs {
 		for _, arg := range marg.Args {
 			args = append(args, fmt.Sprintf("%s=%s", arg.Key, arg.ValueString()))
@@ -437,13 +471,29 @@ outer:
 				continue outer
 			}
 		}
-		// deduplicate
-		for _, x := range baseNames {
-			if x == resolvedBaseName {
-				continue outer
+		var platform string
+		if s.Platform != "" {
+			if platform, err = util.ResolveEnvironmentReplacement(s.Platform, args, false); err != nil {
+				return nil, fmt.Errorf("resolving platform %s: %w", s.Platform, err)
 			}
 		}
-		baseNames = append(baseNames, resolvedBaseName)
+		img := dockerfileImage{name: resolvedBaseName, platform: platform}
+		// deduplicate
+		if slices.Contains(baseImages, img) {
+			continue
+		}
+		baseImages = append(baseImages, img)
 	}
-	return baseNames, nil
+	return baseImages, nil
+}
+
+// platformOptions returns the options to warm an image for platform, which
+// replaces the --customPlatform if set.
+func platformOptions(opts *config.WarmerOptions, platform string) *config.WarmerOptions {
+	if platform == "" {
+		return opts
+	}
+	o := *opts
+	o.CustomPlatform = platform
+	return &o
 }
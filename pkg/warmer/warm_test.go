@@ -18,8 +18,10 @@ package warmer
 
 import (
 	"os"
+	"reflect"
 	"testing"
 
+	"github.com/containerd/platforms"
 	"github.com/osscontainertools/kaniko/pkg/config"
 )
 
@@ -120,6 +122,43 @@ FROM nginx:$NGINX_VERSION-alpine-slim
 	}
 }
 
+func TestParseDockerfile_PlatformDockerfile(t *testing.T) {
+	dockerfile := `FROM --platform=$BUILDPLATFORM golang:1.20 AS build
+FROM --platform=linux/arm64 golang:1.20 AS cross
+FROM alpine:latest
+`
+	tmpfile, err := os.CreateTemp("", "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(dockerfile)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tmpfile.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &config.WarmerOptions{DockerfilePath: tmpfile.Name(), CustomPlatform: "linux/s390x"}
+	images, err := parseDockerfileImages(opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	build := platforms.Format(platforms.Normalize(platforms.DefaultSpec()))
+	expected := []dockerfileImage{
+		{name: "golang:1.20", platform: build},
+		{name: "golang:1.20", platform: "linux/arm64"},
+		{name: "alpine:latest"},
+	}
+	if !reflect.DeepEqual(images, expected) {
+		t.Fatalf("expected %v, got %v", expected, images)
+	}
+	if o := platformOptions(opts, images[1].platform); o.CustomPlatform != "linux/arm64" || opts.CustomPlatform != "linux/s390x" {
+		t.Fatalf("expected a copy of the options for linux/arm64, got %s", o.CustomPlatform)
+	}
+}
+
 func TestParseDockerfile_MissingsDockerfile(t *testing.T) {
 	opts := &config.WarmerOptions{DockerfilePath: "dummy-nowhere"}
 	baseNames, err := ParseDockerfile(opts)